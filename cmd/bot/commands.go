@@ -0,0 +1,299 @@
+package main
+
+import (
+	"fitness-bot/internal/bot"
+	"fitness-bot/internal/handlers"
+	"fitness-bot/internal/models"
+	"log/slog"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// role - роли пользователя, для которых доступна команда
+type role int
+
+const (
+	roleAdmin role = 1 << iota
+	roleManager
+	roleTrainer
+	roleClient
+)
+
+// command - slash-команда и хендлер, в который она ведёт
+type command struct {
+	name        string
+	description string
+	roles       role // 0 - доступна всем, даже без ролей
+	handle      bot.StateHandler
+}
+
+// commands - все команды в порядке показа в меню Telegram
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: "start", description: "Главное меню", handle: commandStart},
+		{name: "workout", description: "Добавить тренировку", roles: roleClient, handle: commandWorkout},
+		{name: "history", description: "Мои тренировки", roles: roleClient, handle: commandHistory},
+		{name: "programs", description: "Программы тренера", roles: roleClient, handle: commandPrograms},
+		{name: "stats", description: "Прогресс по упражнению", roles: roleClient | roleTrainer, handle: commandStats},
+		{name: "groups", description: "Групповые тренировки", roles: roleClient | roleTrainer, handle: commandGroups},
+		{name: "settings", description: "Единицы веса и свой вес", roles: roleClient | roleTrainer, handle: commandSettings},
+		{name: "clients", description: "Мои клиенты", roles: roleTrainer, handle: commandClients},
+		{name: "templates", description: "Шаблоны тренировок", roles: roleTrainer, handle: commandTemplates},
+		{name: "org", description: "Управление организацией", roles: roleManager, handle: commandOrg},
+		{name: "admin", description: "Админ-панель", roles: roleAdmin, handle: commandAdmin},
+		{name: "cancel", description: "Отменить текущее действие", handle: commandCancel},
+		{name: "help", description: "Список команд", handle: commandHelp},
+	}
+}
+
+// defaultCommands - меню для пользователей, которым ещё не назначено меню чата
+func defaultCommands() []tgbotapi.BotCommand {
+	return []tgbotapi.BotCommand{
+		{Command: "start", Description: "Главное меню"},
+		{Command: "help", Description: "Список команд"},
+	}
+}
+
+// userRoles возвращает роли пользователя по его доступам
+func userRoles(accessInfo *models.AccessInfo) role {
+	var r role
+	if accessInfo.IsAdmin {
+		r |= roleAdmin
+	}
+	for _, org := range accessInfo.ManagerOrgs {
+		if org.IsActive {
+			r |= roleManager
+		}
+	}
+	for _, org := range accessInfo.TrainerOrgs {
+		if org.IsActive {
+			r |= roleTrainer
+		}
+	}
+	if len(accessInfo.ClientAccess) > 0 {
+		r |= roleClient
+	}
+	return r
+}
+
+// availableCommands возвращает команды, доступные пользователю
+func availableCommands(accessInfo *models.AccessInfo) []command {
+	roles := userRoles(accessInfo)
+	var available []command
+	for _, c := range commands {
+		if c.roles == 0 || c.roles&roles != 0 {
+			available = append(available, c)
+		}
+	}
+	return available
+}
+
+// updateCommandMenu показывает в личном чате только команды ролей пользователя
+func updateCommandMenu(b *bot.Bot, message *tgbotapi.Message, accessInfo *models.AccessInfo) {
+	if !message.Chat.IsPrivate() {
+		return
+	}
+	var menu []tgbotapi.BotCommand
+	for _, c := range availableCommands(accessInfo) {
+		menu = append(menu, tgbotapi.BotCommand{Command: c.name, Description: c.description})
+	}
+	b.SetChatCommands(message.Chat.ID, menu)
+}
+
+// handleCommand выполняет slash-команду.
+// Команда начинает новый сценарий, поэтому текущий диалог (кроме /cancel и /help) сбрасывается.
+func handleCommand(b *bot.Bot, message *tgbotapi.Message, state *models.UserState, accessInfo *models.AccessInfo) {
+	name := message.Command()
+	for _, c := range availableCommands(accessInfo) {
+		if c.name != name {
+			continue
+		}
+		if name != "cancel" && name != "help" && state != nil {
+			b.CleanupMessages(message.Chat.ID, message.From.ID)
+			b.ClearState(message.From.ID)
+		}
+		c.handle(b, message, state, accessInfo)
+		return
+	}
+
+	for _, c := range commands {
+		if c.name == name {
+			b.SendMessage(message.Chat.ID, "❌ Эта команда вам недоступна. Список команд: /help")
+			return
+		}
+	}
+	b.SendMessage(message.Chat.ID, "Неизвестная команда. Список команд: /help")
+}
+
+func commandStart(b *bot.Bot, message *tgbotapi.Message, _ *models.UserState, accessInfo *models.AccessInfo) {
+	handleStartCommand(b, message, accessInfo)
+}
+
+func commandHelp(b *bot.Bot, message *tgbotapi.Message, _ *models.UserState, accessInfo *models.AccessInfo) {
+	text := bot.NewText(bot.Bold("Доступные команды:"), bot.Plain("\n\n"))
+	for _, c := range availableCommands(accessInfo) {
+		text = text.Line(bot.Plain("/" + c.name + " — " + c.description))
+	}
+	text = text.Add(bot.Plain("\nТакже можно пользоваться кнопками меню."))
+	b.SendText(message.Chat.ID, text, nil)
+}
+
+func commandCancel(b *bot.Bot, message *tgbotapi.Message, state *models.UserState, accessInfo *models.AccessInfo) {
+	if state == nil {
+		b.SendMessageWithKeyboard(message.Chat.ID, "Нечего отменять.", bot.GetStartMenuKeyboard(accessInfo))
+		return
+	}
+	// Ведём себя как кнопка «❌ Отмена» - часть сценариев обрабатывает её сама
+	cancel := *message
+	cancel.Text = bot.CancelText
+	cancel.Entities = nil
+	b.Router.Handle(b, &cancel, state, accessInfo)
+}
+
+// commandWorkout начинает тренировку с тренером; если тренеров несколько - сначала выбор
+func commandWorkout(b *bot.Bot, message *tgbotapi.Message, state *models.UserState, accessInfo *models.AccessInfo) {
+	access := currentClientAccess(state, accessInfo)
+	if access == nil {
+		handlers.HandleClientMenu(b, message, accessInfo.ClientAccess)
+		return
+	}
+	b.SetState(message.From.ID, "client_with_trainer", clientStateData(access))
+	handlers.HandleAddWorkout(b, message)
+}
+
+func commandHistory(b *bot.Bot, message *tgbotapi.Message, _ *models.UserState, _ *models.AccessInfo) {
+	handlers.HandleMyWorkouts(b, message)
+}
+
+// commandPrograms показывает программы текущего тренера; если тренеров несколько - сначала выбор
+func commandPrograms(b *bot.Bot, message *tgbotapi.Message, state *models.UserState, accessInfo *models.AccessInfo) {
+	access := currentClientAccess(state, accessInfo)
+	if access == nil {
+		handlers.HandleClientMenu(b, message, accessInfo.ClientAccess)
+		return
+	}
+	b.SetState(message.From.ID, "client_with_trainer", clientStateData(access))
+	handlers.HandleAssignedTemplates(b, message)
+}
+
+func commandStats(b *bot.Bot, message *tgbotapi.Message, _ *models.UserState, _ *models.AccessInfo) {
+	handlers.HandleStats(b, message)
+}
+
+// commandGroups показывает групповые тренировки организации, в которой пользователь сейчас работает
+func commandGroups(b *bot.Bot, message *tgbotapi.Message, state *models.UserState, accessInfo *models.AccessInfo) {
+	// HandleGroupTrainings берёт организацию из состояния, поэтому восстанавливаем панель
+	if data := currentTrainerOrg(state, accessInfo); data != nil {
+		b.SetState(message.From.ID, "trainer_managing_org", data)
+	} else if access := currentClientAccess(state, accessInfo); access != nil {
+		b.SetState(message.From.ID, "client_with_trainer", clientStateData(access))
+	}
+	handlers.HandleGroupTrainings(b, message)
+}
+
+// commandClients показывает клиентов тренера; если организаций несколько - сначала выбор
+func commandClients(b *bot.Bot, message *tgbotapi.Message, state *models.UserState, accessInfo *models.AccessInfo) {
+	data := currentTrainerOrg(state, accessInfo)
+	if data == nil {
+		handlers.HandleTrainerMenu(b, message, accessInfo.TrainerOrgs)
+		return
+	}
+	b.SetState(message.From.ID, "trainer_managing_org", data)
+	handlers.HandleListClients(b, message)
+}
+
+// commandTemplates показывает шаблоны организации тренера; если организаций несколько - сначала выбор
+func commandTemplates(b *bot.Bot, message *tgbotapi.Message, state *models.UserState, accessInfo *models.AccessInfo) {
+	data := currentTrainerOrg(state, accessInfo)
+	if data == nil {
+		handlers.HandleTrainerMenu(b, message, accessInfo.TrainerOrgs)
+		return
+	}
+	b.SetState(message.From.ID, "trainer_managing_org", data)
+	handlers.HandleTemplates(b, message)
+}
+
+func commandSettings(b *bot.Bot, message *tgbotapi.Message, _ *models.UserState, _ *models.AccessInfo) {
+	handlers.HandleSettings(b, message)
+}
+
+func commandOrg(b *bot.Bot, message *tgbotapi.Message, _ *models.UserState, accessInfo *models.AccessInfo) {
+	handlers.HandleManagerMenu(b, message, accessInfo.ManagerOrgs)
+}
+
+func commandAdmin(b *bot.Bot, message *tgbotapi.Message, _ *models.UserState, _ *models.AccessInfo) {
+	handlers.HandleAdminMenu(b, message)
+}
+
+// currentClientAccess возвращает тренера, с которым клиент работает сейчас
+// (из состояния) или единственного тренера клиента
+func currentClientAccess(state *models.UserState, accessInfo *models.AccessInfo) *models.ClientAccessInfo {
+	if state != nil {
+		if tcID, ok := bot.GetStateInt64(state.Data, "trainer_client_id"); ok {
+			for _, access := range accessInfo.ClientAccess {
+				if access.TrainerClientID == tcID {
+					return access
+				}
+			}
+		}
+	}
+	if len(accessInfo.ClientAccess) == 1 {
+		return accessInfo.ClientAccess[0]
+	}
+	return nil
+}
+
+// clientStateData - данные состояния client_with_trainer
+func clientStateData(access *models.ClientAccessInfo) map[string]interface{} {
+	return map[string]interface{}{
+		"trainer_client_id": access.TrainerClientID,
+		"trainer_id":        access.TrainerID,
+		"trainer_username":  access.TrainerUsername,
+		"org_id":            access.OrganizationID,
+		"org_name":          access.OrganizationName,
+	}
+}
+
+// currentTrainerOrg возвращает данные панели тренера: организацию из состояния
+// или единственную активную организацию тренера
+func currentTrainerOrg(state *models.UserState, accessInfo *models.AccessInfo) map[string]interface{} {
+	var active []*models.TrainerOrgInfo
+	for _, org := range accessInfo.TrainerOrgs {
+		if org.IsActive {
+			active = append(active, org)
+		}
+	}
+
+	if state != nil {
+		trainerID, okT := bot.GetStateInt64(state.Data, "trainer_id")
+		orgID, okID := bot.GetStateInt64(state.Data, "org_id")
+		if okT && okID {
+			for _, org := range active {
+				if org.TrainerID == trainerID && org.Organization.ID == orgID {
+					return trainerOrgData(org)
+				}
+			}
+		}
+	}
+	if len(active) == 1 {
+		return trainerOrgData(active[0])
+	}
+	return nil
+}
+
+func trainerOrgData(org *models.TrainerOrgInfo) map[string]interface{} {
+	return map[string]interface{}{
+		"trainer_id": org.TrainerID,
+		"org_id":     org.Organization.ID,
+		"org_name":   org.Organization.Name,
+	}
+}
+
+// registerDefaultCommands задаёт меню команд по умолчанию при старте
+func registerDefaultCommands(b *bot.Bot) {
+	if err := b.SetDefaultCommands(defaultCommands()); err != nil {
+		slog.Warn("Failed to register default commands", "error", err)
+	}
+}
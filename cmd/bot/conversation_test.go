@@ -0,0 +1,552 @@
+package main
+
+import (
+	"fitness-bot/internal/bot"
+	"fitness-bot/internal/database"
+	"fitness-bot/internal/models"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
+)
+
+// fakeStorage - bot.Storage в памяти для сценарных тестов.
+// Реализует только то, что проходят сценарии: остальные методы вызывают встроенный
+// nil-интерфейс и падают, так что новый запрос к данным сразу виден в тесте.
+type fakeStorage struct {
+	bot.Storage
+
+	mu           sync.Mutex
+	nextID       int64
+	users        map[int64]*models.User
+	access       map[int64]*models.AccessInfo // по telegram_id
+	orgs         map[int64]*models.Organization
+	muscleGroups []*models.MuscleGroupRecord
+	workouts     map[int64]*models.Workout
+	exercises    []*models.Exercise
+	catalog      map[string]*models.CatalogExercise // по названию в нижнем регистре
+}
+
+func newFakeStorage() *fakeStorage {
+	return &fakeStorage{
+		nextID:   100,
+		users:    make(map[int64]*models.User),
+		access:   make(map[int64]*models.AccessInfo),
+		orgs:     make(map[int64]*models.Organization),
+		workouts: make(map[int64]*models.Workout),
+		catalog:  make(map[string]*models.CatalogExercise),
+		muscleGroups: []*models.MuscleGroupRecord{
+			{ID: 1, Name: "Грудь", Emoji: "💪", SortOrder: 1},
+			{ID: 2, Name: "Ноги", Emoji: "🦵", SortOrder: 2},
+		},
+	}
+}
+
+func (s *fakeStorage) id() int64 {
+	s.nextID++
+	return s.nextID
+}
+
+func (s *fakeStorage) LinkTelegramID(int64, string) error {
+	return nil
+}
+
+func (s *fakeStorage) EnsureUser(telegramID int64, username, fullName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[telegramID]; !ok {
+		s.users[telegramID] = &models.User{ID: s.id(), TelegramID: telegramID, Username: username, FullName: fullName, WeightUnit: models.UnitKg}
+	}
+	return nil
+}
+
+func (s *fakeStorage) GetUserByTelegramID(telegramID int64) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.users[telegramID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (s *fakeStorage) GetUserAccessInfo(telegramID int64, _ string) (*models.AccessInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Копия: обработчики дописывают в неё IsAdmin
+	info := &models.AccessInfo{}
+	if access, ok := s.access[telegramID]; ok {
+		*info = *access
+	}
+	return info, nil
+}
+
+func (s *fakeStorage) GetOrganizationByID(id int64) (*models.Organization, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	org, ok := s.orgs[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return org, nil
+}
+
+func (s *fakeStorage) GetMuscleGroups(*int64) ([]*models.MuscleGroupRecord, error) {
+	return s.muscleGroups, nil
+}
+
+func (s *fakeStorage) GetMuscleGroupsByIDs(ids []int64, _ *int64) ([]models.MuscleGroupRecord, error) {
+	var groups []models.MuscleGroupRecord
+	for _, g := range s.muscleGroups {
+		for _, id := range ids {
+			if g.ID == id {
+				groups = append(groups, *g)
+			}
+		}
+	}
+	return groups, nil
+}
+
+func (s *fakeStorage) CreateWorkout(workout *models.Workout) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	workout.ID = s.id()
+	workout.Status = models.WorkoutDraft
+	s.workouts[workout.ID] = workout
+	return nil
+}
+
+func (s *fakeStorage) GetWorkoutByID(id int64) (*models.Workout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	workout, ok := s.workouts[id]
+	if !ok || workout.Status == models.WorkoutCancelled {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *workout
+	return &copied, nil
+}
+
+func (s *fakeStorage) GetWorkoutOrganizationID(workout *models.Workout) (*int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if workout.TrainerClientID == nil {
+		return nil, nil
+	}
+	for _, info := range s.access {
+		for _, access := range info.ClientAccess {
+			if access.TrainerClientID == *workout.TrainerClientID {
+				orgID := access.OrganizationID
+				return &orgID, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (s *fakeStorage) ResolveCatalogExercise(name string, orgID *int64, muscleGroup models.MuscleGroup) (*database.CatalogMatch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := strings.ToLower(name)
+	if entry, ok := s.catalog[key]; ok {
+		return &database.CatalogMatch{Entry: entry}, nil
+	}
+	if orgID == nil {
+		return nil, nil
+	}
+	entry := &models.CatalogExercise{ID: s.id(), OrganizationID: orgID, Name: name, NormalizedName: key, MuscleGroup: muscleGroup}
+	s.catalog[key] = entry
+	return &database.CatalogMatch{Entry: entry}, nil
+}
+
+func (s *fakeStorage) CreateExercise(exercise *models.Exercise) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	exercise.ID = s.id()
+	s.exercises = append(s.exercises, exercise)
+	return nil
+}
+
+func (s *fakeStorage) GetExerciseByID(id int64) (*models.Exercise, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ex := range s.exercises {
+		if ex.ID == id {
+			copied := *ex
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (s *fakeStorage) UpdateExercise(exercise *models.Exercise) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ex := range s.exercises {
+		if ex.ID == exercise.ID {
+			ex.Name, ex.CatalogID = exercise.Name, exercise.CatalogID
+			ex.Bodyweight, ex.BodyweightKg = exercise.Bodyweight, exercise.BodyweightKg
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (s *fakeStorage) CanEditWorkout(workout *models.Workout, telegramID int64, _ string) (bool, error) {
+	return workout.ClientTelegramID == telegramID, nil
+}
+
+func (s *fakeStorage) FinishWorkout(workoutID int64, finishedAt *time.Time) (models.WorkoutStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	workout, ok := s.workouts[workoutID]
+	if !ok {
+		return models.WorkoutDraft, gorm.ErrRecordNotFound
+	}
+	workout.Status = models.WorkoutCancelled
+	for _, ex := range s.exercises {
+		if ex.WorkoutID == workoutID {
+			workout.Status = models.WorkoutCompleted
+			workout.FinishedAt = finishedAt
+			break
+		}
+	}
+	return workout.Status, nil
+}
+
+func (s *fakeStorage) CancelWorkout(workoutID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if workout, ok := s.workouts[workoutID]; ok {
+		workout.Status = models.WorkoutCancelled
+	}
+	return nil
+}
+
+// workoutExercises возвращает упражнения тренировки в порядке записи
+func (s *fakeStorage) workoutExercises(workoutID int64) []*models.Exercise {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result []*models.Exercise
+	for _, ex := range s.exercises {
+		if ex.WorkoutID == workoutID {
+			result = append(result, ex)
+		}
+	}
+	return result
+}
+
+// conversation - диалог одного пользователя с ботом в личном чате
+type conversation struct {
+	t    *testing.T
+	b    *bot.Bot
+	api  *bot.RecordingMessenger
+	db   *fakeStorage
+	user *tgbotapi.User
+	chat *tgbotapi.Chat
+
+	nextMessageID int
+}
+
+func newConversation(t *testing.T) *conversation {
+	api := bot.NewRecordingMessenger()
+	db := newFakeStorage()
+	b := bot.New(api, db, "admin", bot.NewMemoryStateStore())
+	b.Router = newRouter()
+	t.Cleanup(b.StopAllRest)
+
+	return &conversation{
+		t:    t,
+		b:    b,
+		api:  api,
+		db:   db,
+		user: &tgbotapi.User{ID: 42, UserName: "client", FirstName: "Иван"},
+		chat: &tgbotapi.Chat{ID: 42, Type: "private"},
+	}
+}
+
+// send присылает боту сообщение; «/команда» отмечается как команда
+func (c *conversation) send(text string) {
+	c.t.Helper()
+	c.nextMessageID++
+	message := &tgbotapi.Message{MessageID: c.nextMessageID, From: c.user, Chat: c.chat, Text: text}
+	if strings.HasPrefix(text, "/") {
+		command, _, _ := strings.Cut(text, " ")
+		message.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(command)}}
+	}
+	handleUpdate(c.b, message)
+}
+
+// press нажимает inline-кнопку с data под сообщением messageID
+func (c *conversation) press(data string, messageID int) {
+	c.t.Helper()
+	handleCallback(c.b, &tgbotapi.CallbackQuery{
+		ID:      "cb",
+		From:    c.user,
+		Message: &tgbotapi.Message{MessageID: messageID, Chat: c.chat},
+		Data:    data,
+	})
+}
+
+// lastReply возвращает текст последнего отправленного сообщения
+func (c *conversation) lastReply() string {
+	c.t.Helper()
+	msg, ok := c.api.LastMessage()
+	if !ok {
+		c.t.Fatal("бот ничего не отправил")
+	}
+	return msg.Text
+}
+
+// expectReply проверяет, что последнее сообщение содержит want
+func (c *conversation) expectReply(want string) {
+	c.t.Helper()
+	if got := c.lastReply(); !strings.Contains(got, want) {
+		c.t.Fatalf("ответ бота:\n%s\nожидалось, что он содержит %q", got, want)
+	}
+}
+
+// expectState проверяет состояние диалога ("" - состояния нет)
+func (c *conversation) expectState(want string) {
+	c.t.Helper()
+	got := ""
+	if state := c.b.GetState(c.user.ID); state != nil {
+		got = state.State
+	}
+	if got != want {
+		c.t.Fatalf("состояние %q, ожидалось %q", got, want)
+	}
+}
+
+// withTrainer даёт пользователю доступ клиента к тренеру организации
+func (c *conversation) withTrainer() *models.ClientAccessInfo {
+	access := &models.ClientAccessInfo{
+		TrainerClientID:  7,
+		OrganizationID:   3,
+		OrganizationName: "Фитнес Плюс",
+		TrainerID:        5,
+		TrainerUsername:  "coach",
+		IsActive:         true,
+	}
+	c.db.access[c.user.ID] = &models.AccessInfo{ClientAccess: []*models.ClientAccessInfo{access}}
+	c.db.orgs[access.OrganizationID] = &models.Organization{ID: access.OrganizationID, Name: access.OrganizationName, IsActive: true}
+	return access
+}
+
+// startWorkout проходит от меню клиента до добавления упражнений и возвращает ID тренировки
+func (c *conversation) startWorkout() int64 {
+	c.t.Helper()
+	c.send("📝 Мои тренировки")
+	c.expectState("client_with_trainer")
+	c.expectReply("Тренировки с @coach")
+
+	c.send("➕ Добавить тренировку")
+	c.expectState("awaiting_muscle_group")
+	c.send(bot.MuscleGroupButton(c.db.muscleGroups[0], false))
+	c.send(bot.MuscleGroupsDoneText)
+	c.expectState("awaiting_workout_date")
+	c.expectReply("Когда тренировка?")
+
+	c.send(bot.WorkoutNowText)
+	c.expectState("adding_exercises")
+	workoutID, _ := bot.GetStateInt64(c.b.GetState(c.user.ID).Data, "workout_id")
+	return workoutID
+}
+
+func TestStartGreetsNewUser(t *testing.T) {
+	c := newConversation(t)
+
+	c.send("/start")
+
+	c.expectReply("Добро пожаловать")
+	c.expectState("")
+	if _, err := c.db.GetUserByTelegramID(c.user.ID); err != nil {
+		t.Errorf("пользователь не сохранён: %v", err)
+	}
+	commands := 0
+	for _, req := range c.api.Other {
+		if _, ok := req.(tgbotapi.SetMyCommandsConfig); ok {
+			commands++
+		}
+	}
+	if commands != 1 {
+		t.Errorf("меню команд обновлено %d раз, ожидался 1", commands)
+	}
+}
+
+func TestClientRecordsWorkout(t *testing.T) {
+	c := newConversation(t)
+	access := c.withTrainer()
+
+	workoutID := c.startWorkout()
+	workout, err := c.db.GetWorkoutByID(workoutID)
+	if err != nil {
+		t.Fatalf("тренировка не создана: %v", err)
+	}
+	if workout.TrainerClientID == nil || *workout.TrainerClientID != access.TrainerClientID {
+		t.Errorf("тренировка записана с trainer_client_id %v, ожидался %d", workout.TrainerClientID, access.TrainerClientID)
+	}
+	if len(workout.MuscleGroups) != 1 || workout.MuscleGroups[0].ID != c.db.muscleGroups[0].ID {
+		t.Errorf("группы мышц тренировки %+v", workout.MuscleGroups)
+	}
+
+	c.send("Жим лежа 4x10x80\nПрисед\n100x5\n110x3 @9")
+	c.expectReply("✅ *Жим лежа*: 80кг×10")
+	c.expectReply("✅ *Присед*: 100кг×5, 110кг×3 @9")
+
+	exercises := c.db.workoutExercises(workoutID)
+	if len(exercises) != 2 {
+		t.Fatalf("записано упражнений: %d, ожидалось 2", len(exercises))
+	}
+	if ex := exercises[0]; ex.Name != "Жим лежа" || len(ex.Sets) != 4 || ex.Sets[0].Weight != 80 || ex.CatalogID == nil {
+		t.Errorf("первое упражнение записано как %+v", ex)
+	}
+	if ex := exercises[1]; ex.Name != "Присед" || len(ex.Sets) != 2 || ex.Order != 2 || ex.Sets[1].RPE == nil {
+		t.Errorf("второе упражнение записано как %+v", ex)
+	}
+
+	c.send(bot.FinishWorkoutText)
+	c.expectReply("Тренировка сохранена")
+	c.expectState("")
+	if status := c.db.workouts[workoutID].Status; status != models.WorkoutCompleted {
+		t.Errorf("статус тренировки %q, ожидался %q", status, models.WorkoutCompleted)
+	}
+}
+
+func TestClientFixesUnparsedExercise(t *testing.T) {
+	c := newConversation(t)
+	c.withTrainer()
+	workoutID := c.startWorkout()
+
+	c.send("Жим лежа\nПрисед 5x5x100")
+	c.expectReply("Не удалось разобрать упражнение")
+	c.expectReply("строка 1 «Жим лежа»")
+	c.expectState("adding_exercises")
+	if n := len(c.db.workoutExercises(workoutID)); n != 0 {
+		t.Fatalf("после ошибки записано упражнений: %d", n)
+	}
+
+	c.send("Жим лежа 3x10x80")
+	c.expectReply("✅ *Жим лежа*")
+	if n := len(c.db.workoutExercises(workoutID)); n != 1 {
+		t.Errorf("записано упражнений: %d, ожидалось 1", n)
+	}
+}
+
+func TestCancelledWorkoutIsDiscarded(t *testing.T) {
+	c := newConversation(t)
+	c.withTrainer()
+	workoutID := c.startWorkout()
+
+	c.send("Жим лежа 3x10x80")
+	c.send(bot.CancelText)
+
+	c.expectReply("Тренировка отменена")
+	c.expectState("")
+	if status := c.db.workouts[workoutID].Status; status != models.WorkoutCancelled {
+		t.Errorf("статус тренировки %q, ожидался %q", status, models.WorkoutCancelled)
+	}
+}
+
+func TestEditDuringWorkoutKeepsDraft(t *testing.T) {
+	c := newConversation(t)
+	c.withTrainer()
+	workoutID := c.startWorkout()
+	c.send("Жим лежа 3x10x80")
+	exercise := c.db.workoutExercises(workoutID)[0]
+
+	c.press("exedit:"+strconv.FormatInt(exercise.ID, 10)+":name", 1)
+	c.expectState("editing_exercise")
+	c.send("Жим штанги лежа")
+
+	c.expectState("adding_exercises")
+	if id, _ := bot.GetStateInt64(c.b.GetState(c.user.ID).Data, "workout_id"); id != workoutID {
+		t.Errorf("после правки workout_id %d, ожидался %d", id, workoutID)
+	}
+	if name := c.db.workoutExercises(workoutID)[0].Name; name != "Жим штанги лежа" {
+		t.Errorf("название упражнения %q", name)
+	}
+
+	c.send("Присед 5x5x100")
+	c.expectReply("✅ *Присед*")
+	if n := len(c.db.workoutExercises(workoutID)); n != 2 {
+		t.Errorf("в черновике упражнений: %d, ожидалось 2", n)
+	}
+}
+
+func TestStaleFinishButton(t *testing.T) {
+	c := newConversation(t)
+	c.withTrainer()
+	workoutID := c.startWorkout()
+	c.send("Жим лежа 3x10x80")
+	c.send(bot.FinishWorkoutText)
+	c.api.Reset()
+
+	// Кнопка под сообщением уже завершённой тренировки
+	c.press("exercise:0:finish", 1)
+
+	if len(c.api.CallbackAnswers) != 1 {
+		t.Errorf("ответов на нажатие: %d, ожидался 1", len(c.api.CallbackAnswers))
+	}
+	c.expectReply("Нет активной тренировки")
+	if status := c.db.workouts[workoutID].Status; status != models.WorkoutCompleted {
+		t.Errorf("статус тренировки изменился на %q", status)
+	}
+}
+
+func TestStaleCancelButtonKeepsOtherFlow(t *testing.T) {
+	c := newConversation(t)
+	c.withTrainer()
+	c.send("📝 Мои тренировки")
+	c.send("➕ Добавить тренировку")
+	c.send(bot.MuscleGroupButton(c.db.muscleGroups[0], false))
+	c.expectState("awaiting_muscle_group")
+	c.api.Reset()
+
+	// Кнопка «Отмена» под сообщением давно закрытой тренировки
+	c.press("exercise:0:cancel", 1)
+
+	c.expectReply("Нет активной тренировки")
+	if strings.Contains(c.lastReply(), "отменена") {
+		t.Errorf("на устаревшую кнопку ответ %q", c.lastReply())
+	}
+	c.expectState("awaiting_muscle_group")
+	if ids := bot.GetStateInt64s(c.b.GetState(c.user.ID).Data, "muscle_group_ids"); len(ids) != 1 {
+		t.Errorf("выбор групп мышц потерян: %v", ids)
+	}
+	if len(c.api.Deletes) != 0 {
+		t.Errorf("удалено сообщений сценария: %d", len(c.api.Deletes))
+	}
+}
+
+func TestMuscleGroupsDoneWithoutSelection(t *testing.T) {
+	c := newConversation(t)
+	c.withTrainer()
+	c.send("📝 Мои тренировки")
+	c.send("➕ Добавить тренировку")
+	c.api.Reset()
+
+	c.press("muscle:done", 1)
+
+	if len(c.api.CallbackAnswers) != 1 {
+		t.Fatalf("ответов на нажатие: %d, ожидался 1", len(c.api.CallbackAnswers))
+	}
+	if text := c.api.CallbackAnswers[0].Text; !strings.Contains(text, "хотя бы одну группу") {
+		t.Errorf("ответ на нажатие %q", text)
+	}
+	c.expectState("awaiting_muscle_group")
+}
+
+func TestNoAccessUserGetsMenu(t *testing.T) {
+	c := newConversation(t)
+
+	c.send("📝 Мои тренировки")
+
+	c.expectReply("нет активных доступов")
+	c.expectState("")
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fitness-bot/internal/database"
+	"fitness-bot/internal/models"
+	"log/slog"
+	"time"
+)
+
+// startDraftSweep раз в interval закрывает брошенные черновики тренировок:
+// с упражнениями - завершает, пустые - отменяет. Канал закрывается, когда
+// после отмены ctx закончился последний проход.
+func startDraftSweep(ctx context.Context, db *database.DB, interval time.Duration) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			sweepDraftWorkouts(db)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return done
+}
+
+// sweepDraftWorkouts закрывает черновики, в которые не добавляли упражнений дольше MaxWorkoutDuration
+func sweepDraftWorkouts(db *database.DB) {
+	completed, cancelled, err := db.SweepDraftWorkouts(time.Now().Add(-models.MaxWorkoutDuration))
+	if err != nil {
+		slog.Error("Draft sweep failed", "error", err)
+		return
+	}
+	if completed > 0 || cancelled > 0 {
+		slog.Info("Draft workouts swept", "completed", completed, "cancelled", cancelled)
+	}
+}
@@ -6,10 +6,14 @@ import (
 	"fitness-bot/internal/database"
 	"fitness-bot/internal/handlers"
 	"fitness-bot/internal/models"
-	"log"
+	"fitness-bot/internal/parser"
+	"log/slog"
 	"os"
+	"os/signal"
+	"runtime/debug"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -17,8 +21,12 @@ import (
 )
 
 func main() {
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using environment variables")
+	envErr := godotenv.Load()
+
+	// Все логи (включая GORM и стандартный log) идут через slog
+	slog.SetDefault(bot.NewLogger(os.Stderr, os.Getenv("LOG_FORMAT"), os.Getenv("LOG_LEVEL")))
+	if envErr != nil {
+		slog.Info("No .env file found, using environment variables")
 	}
 
 	// Применяем миграции
@@ -30,84 +38,246 @@ func main() {
 		os.Getenv("DB_NAME"),
 	)
 	if err := database.RunMigrations(dbURL); err != nil {
-		log.Printf("Warning: migrations failed: %v", err)
+		slog.Warn("Migrations failed", "error", err)
 	}
 
-	ctx := context.Background()
+	// Корневой контекст отменяется по SIGINT/SIGTERM (docker compose down/stop)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	db, err := database.NewDB(ctx)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal("Failed to connect to database", "error", err)
 	}
+	// Закрывается последним - после того, как завершились обработчики
 	defer db.Close()
 
-	log.Println("Database connected successfully")
+	slog.Info("Database connected successfully")
+
+	// Рабочий контекст обработчиков (их запросов к БД и Telegram): не отменяется по сигналу,
+	// чтобы начатое успело завершиться, но отменяется, если они не уложились в SHUTDOWN_TIMEOUT
+	workCtx, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+	// Выполняется раньше db.Close: зависшие обработчики бросают ожидание отправки
+	defer cancelWork()
 
 	botToken := os.Getenv("TELEGRAM_BOT_TOKEN")
 	if botToken == "" {
-		log.Fatal("TELEGRAM_BOT_TOKEN is required")
+		fatal("TELEGRAM_BOT_TOKEN is required")
 	}
 
 	adminUsername := os.Getenv("ADMIN_USERNAME")
 	if adminUsername == "" {
-		log.Fatal("ADMIN_USERNAME is required")
+		fatal("ADMIN_USERNAME is required")
 	}
 
-	b, err := bot.NewBot(botToken, db, adminUsername)
+	b, err := bot.NewBot(workCtx, botToken, db.WithContext(workCtx), adminUsername)
 	if err != nil {
-		log.Fatalf("Failed to create bot: %v", err)
+		fatal("Failed to create bot", "error", err)
 	}
+	b.Router = newRouter()
+	registerDefaultCommands(b)
+
+	updates, stopUpdates, err := startUpdates(b)
+	if err != nil {
+		slog.Error("Failed to start receiving updates", "error", err)
+		return
+	}
+
+	dispatcher := bot.NewDispatcher(
+		envInt("UPDATE_WORKERS", 16),
+		envInt("UPDATE_QUEUE_SIZE", 1000),
+		func(update tgbotapi.Update) { handleIncoming(b, update) },
+	)
 
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
+	sweepDone := startDraftSweep(ctx, db.WithContext(ctx), envDuration("DRAFT_SWEEP_INTERVAL", 15*time.Minute))
 
-	updates := b.API.GetUpdatesChan(u)
+	slog.Info("Bot started successfully")
 
-	log.Println("Bot started successfully!")
+	receiveUpdates(ctx, updates, dispatcher)
+
+	slog.Info("Shutting down: stopped receiving updates, waiting for handlers")
+	stopUpdates()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), envDuration("SHUTDOWN_TIMEOUT", 20*time.Second))
+	defer cancel()
+	drainUpdates(shutdownCtx, updates, dispatcher)
+
+	// Очистка черновиков пишет в БД - дожидаемся её до закрытия соединения
+	stop()
+	<-sweepDone
+
+	// Состояния диалогов пишутся в хранилище синхронно из обработчиков,
+	// поэтому после их завершения сбрасывать на диск больше нечего
+	if err := dispatcher.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Shutdown: handlers did not finish in time", "error", err)
+		return
+	}
+	// Таймеры отдыха живут только в памяти - после перезапуска их не продолжить
+	b.StopAllRest()
+	slog.Info("Bot stopped")
+}
+
+// fatal логирует ошибку запуска и завершает процесс
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
 
-	for update := range updates {
-		if update.CallbackQuery != nil {
-			go safeHandleCallback(b, update.CallbackQuery)
-			continue
+// receiveUpdates передаёт обновления диспетчеру до отмены ctx или закрытия канала
+func receiveUpdates(ctx context.Context, updates tgbotapi.UpdatesChannel, dispatcher *bot.Dispatcher) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case update, ok := <-updates:
+			if !ok {
+				return
+			}
+			dispatchUpdate(ctx, update, dispatcher)
 		}
+	}
+}
 
-		if update.Message == nil {
-			continue
+// drainUpdates передаёт диспетчеру обновления, оставшиеся в канале после остановки приёма.
+// Telegram уже считает их доставленными (вебхуку ответили 200 OK, polling сдвинул offset),
+// поэтому бросить их - значит потерять.
+func drainUpdates(ctx context.Context, updates tgbotapi.UpdatesChannel, dispatcher *bot.Dispatcher) {
+	for {
+		select {
+		case update, ok := <-updates:
+			if !ok {
+				return
+			}
+			dispatchUpdate(ctx, update, dispatcher)
+		default:
+			// Вебхук к этому моменту закрыл канал, а polling мог начать новый getUpdates -
+			// его ответ ещё не подтверждён, и Telegram пришлёт эти обновления после перезапуска
+			return
 		}
+	}
+}
+
+// dispatchUpdate ставит обновление в очередь диспетчера, пропуская неподдерживаемые типы
+func dispatchUpdate(ctx context.Context, update tgbotapi.Update, dispatcher *bot.Dispatcher) {
+	if update.CallbackQuery == nil && update.Message == nil {
+		return
+	}
+	if err := dispatcher.Dispatch(ctx, update); err != nil {
+		slog.Warn("Error dispatching update", "update_id", update.UpdateID, "error", err)
+	}
+}
+
+// handleIncoming передаёт обновление обработчикам (общий путь для polling и webhook)
+// и в конце логирует длительность и исход обработки
+func handleIncoming(b *bot.Bot, update tgbotapi.Update) {
+	start := time.Now()
+	user := update.SentFrom()
+	if user == nil {
+		return
+	}
+
+	ulog := bot.NewUpdateLog(updateLogger(b, update, user))
+	unbind := b.BindLog(user.ID, ulog)
+	defer unbind()
+
+	var panicked bool
+	if update.CallbackQuery != nil {
+		panicked = safeHandleCallback(b, update.CallbackQuery)
+	} else {
+		panicked = safeHandleUpdate(b, update.Message)
+	}
+
+	outcome := "ok"
+	switch {
+	case panicked:
+		outcome = "panic"
+	case ulog.Failed():
+		outcome = "error"
+	}
+	ulog.Info("Update handled", "outcome", outcome, "duration_ms", time.Since(start).Milliseconds())
+}
+
+// updateLogger - логгер с контекстом обновления: кто, откуда, в каком состоянии
+func updateLogger(b *bot.Bot, update tgbotapi.Update, user *tgbotapi.User) *slog.Logger {
+	attrs := []any{
+		"update_id", update.UpdateID,
+		"user_id", user.ID,
+		"username", user.UserName,
+	}
+	if chat := update.FromChat(); chat != nil {
+		attrs = append(attrs, "chat_id", chat.ID)
+	}
+	if state := b.GetState(user.ID); state != nil {
+		attrs = append(attrs, "state", state.State)
+	}
+	if update.CallbackQuery != nil {
+		prefix, _, _ := bot.ParseCallbackData(update.CallbackQuery.Data)
+		attrs = append(attrs, "callback_prefix", prefix)
+	} else if update.Message != nil && update.Message.IsCommand() {
+		attrs = append(attrs, "command", update.Message.Command())
+	}
+	return slog.Default().With(attrs...)
+}
+
+// envDuration читает длительность (например, "20s") из переменной окружения
+func envDuration(name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		slog.Warn("Invalid environment variable, using default", "name", name, "value", value, "default", def.String())
+		return def
+	}
+	return d
+}
 
-		go safeHandleUpdate(b, update.Message)
+// envInt читает целое число из переменной окружения
+func envInt(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
 	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Warn("Invalid environment variable, using default", "name", name, "value", value, "default", def)
+		return def
+	}
+	return n
 }
 
 // safeHandleUpdate оборачивает handleUpdate с recover для защиты от panic
-func safeHandleUpdate(b *bot.Bot, message *tgbotapi.Message) {
+func safeHandleUpdate(b *bot.Bot, message *tgbotapi.Message) (panicked bool) {
 	defer func() {
 		if r := recover(); r != nil {
-			log.Printf("PANIC recovered: %v (user: %d, text: %s)", r, message.From.ID, message.Text)
+			panicked = true
+			b.Log(message.From.ID).Error("PANIC recovered", "panic", r, "text", message.Text, "stack", string(debug.Stack()))
 			b.SendMessage(message.Chat.ID, "❌ Произошла ошибка. Попробуйте /start")
 			b.ClearState(message.From.ID)
 		}
 	}()
 	handleUpdate(b, message)
+	return false
 }
 
 // safeHandleCallback оборачивает handleCallback с recover
-func safeHandleCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery) {
+func safeHandleCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery) (panicked bool) {
 	defer func() {
 		if r := recover(); r != nil {
-			log.Printf("PANIC in callback: %v (user: %d, data: %s)", r, callback.From.ID, callback.Data)
+			panicked = true
+			b.Log(callback.From.ID).Error("PANIC in callback", "panic", r, "data", callback.Data, "stack", string(debug.Stack()))
 			b.AnswerCallback(callback.ID, "Произошла ошибка")
 			b.ClearState(callback.From.ID)
 		}
 	}()
 	handleCallback(b, callback)
+	return false
 }
 
 // handleCallback обрабатывает нажатия на inline-кнопки
 func handleCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery) {
 
-	// Отвечаем на callback чтобы убрать "часики"
-	b.AnswerCallback(callback.ID, "")
-
 	// Парсим callback data
 	prefix, id, action := bot.ParseCallbackData(callback.Data)
 
@@ -115,7 +285,8 @@ func handleCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery) {
 	username := callback.From.UserName
 	accessInfo, err := b.DB.GetUserAccessInfo( callback.From.ID, username)
 	if err != nil {
-		log.Printf("Error getting access info in callback: %v", err)
+		b.Log(callback.From.ID).Error("Error getting access info in callback", "error", err)
+		b.AnswerCallback(callback.ID, "Ошибка. Попробуйте позже.")
 		return
 	}
 	accessInfo.IsAdmin = b.IsAdmin(username)
@@ -123,35 +294,115 @@ func handleCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery) {
 	chatID := callback.Message.Chat.ID
 	messageID := callback.Message.MessageID
 
+	// Ответ убирает "часики" с кнопки. Telegram принимает на нажатие только один ответ,
+	// поэтому отвечаем после обработки: так в нём может быть предупреждение обработчика
+	answer := ""
 	switch prefix {
 	case "org":
 		handleOrgCallback(b, callback, id, action, accessInfo, chatID, messageID)
+	case "rest":
+		if !b.StopRest(callback.From.ID) {
+			b.EditText(chatID, messageID, bot.NewText(bot.Plain("⏹ Отдых остановлен")), nil)
+		}
+	case "wdate":
+		handleWorkoutDateCallback(b, callback, action, accessInfo)
+	case "mgroup":
+		handleMuscleGroupCallback(b, callback, id, action, chatID, messageID)
 	case "muscle":
-		handleMuscleCallback(b, callback, action, chatID, messageID)
+		answer = handleMuscleCallback(b, callback, id, action, accessInfo, chatID, messageID)
 	case "client":
-		handleClientListCallback(b, callback, id, action, chatID, messageID)
+		handleClientListCallback(b, callback, id, action, accessInfo, chatID, messageID)
 	case "client_action":
-		handleClientActionCallback(b, callback, id, action, chatID, messageID)
+		answer = handleClientActionCallback(b, callback, id, action, accessInfo, chatID, messageID)
 	case "manager":
-		handleManagerListCallback(b, callback, id, action, chatID, messageID)
+		answer = handleManagerListCallback(b, callback, id, action, accessInfo, chatID, messageID)
 	case "trainer":
-		handleTrainerListCallback(b, callback, id, action, chatID, messageID)
+		answer = handleTrainerListCallback(b, callback, id, action, accessInfo, chatID, messageID)
 	case "exercise":
 		handleExerciseCallback(b, callback, action, accessInfo, chatID, messageID)
+	case "workout":
+		handleWorkoutCallback(b, callback, id, action, chatID, messageID)
+	case "exedit":
+		handleExerciseEditCallback(b, callback, id, action, chatID, messageID)
+	case "template":
+		handleTemplateCallback(b, callback, id, action, chatID, messageID)
+	case "tassign":
+		handleTemplateAssignCallback(b, callback, id, action, chatID, messageID)
+	case "tstart":
+		handlers.StartTemplateWorkout(b, callbackMessage(callback), id)
+	case "settings":
+		handleSettingsCallback(b, callback, action, chatID, messageID)
+	case "hist":
+		handleHistoryCallback(b, callback, chatID, messageID)
+	case "cstat":
+		handleClientStatsCallback(b, callback, id, action, chatID, messageID)
+	case "hview":
+		pos, _ := bot.ParseHistoryPosition(strings.TrimPrefix(callback.Data, "hview:"+strconv.FormatInt(id, 10)+":"))
+		handlers.ShowHistoryWorkout(b, chatID, messageID, callback.From, id, pos)
+	default:
+		b.Log(callback.From.ID).Warn("Unknown callback prefix", "prefix", prefix)
+	}
+	b.AnswerCallback(callback.ID, answer)
+}
+
+// handleSettingsCallback обрабатывает кнопки настроек
+func handleSettingsCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery, action string, chatID int64, messageID int) {
+	switch action {
+	case "kg":
+		handlers.SetWeightUnit(b, chatID, messageID, callback.From.ID, models.UnitKg)
+	case "lb":
+		handlers.SetWeightUnit(b, chatID, messageID, callback.From.ID, models.UnitLb)
+	case "bodyweight":
+		handlers.StartBodyweightInput(b, callbackMessage(callback))
+	case "share":
+		handlers.ToggleShareWorkouts(b, chatID, messageID, callback.From.ID)
+	}
+}
+
+// handleHistoryCallback обрабатывает листание, фильтры и выгрузку истории тренировок
+func handleHistoryCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery, chatID int64, messageID int) {
+	pos, view := bot.ParseHistoryPosition(strings.TrimPrefix(callback.Data, "hist:"))
+	switch view {
+	case "":
+		handlers.ShowHistoryPage(b, chatID, messageID, callback.From, pos, "")
+	case bot.HistoryRangeView:
+		handlers.StartHistoryRangeInput(b, callbackMessage(callback), pos)
+	case bot.HistoryExportView:
+		handlers.SendHistoryExport(b, chatID, callback.From, pos)
 	default:
-		log.Printf("Unknown callback prefix: %s", prefix)
+		handlers.ShowHistoryFilter(b, chatID, messageID, callback.From, pos, view)
+	}
+}
+
+// handleClientStatsCallback обрабатывает статистику клиента у тренера:
+// cstat:<trainer_client_id> - сводка, cstat:<trainer_client_id>:<catalog_id> - графики упражнения
+func handleClientStatsCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery, trainerClientID int64, action string, chatID int64, messageID int) {
+	if action == "" {
+		handlers.ShowClientStats(b, chatID, messageID, callback.From, trainerClientID)
+		return
+	}
+	catalogID, err := strconv.ParseInt(action, 10, 64)
+	if err != nil {
+		return
+	}
+	handlers.ShowClientExerciseStats(b, chatID, callback.From, trainerClientID, catalogID)
+}
+
+// callbackMessage создаёт сообщение-заглушку для вызова хендлеров из callback
+func callbackMessage(callback *tgbotapi.CallbackQuery) *tgbotapi.Message {
+	return &tgbotapi.Message{
+		Chat: callback.Message.Chat,
+		From: callback.From,
 	}
 }
 
 // handleOrgCallback обрабатывает выбор организации
 func handleOrgCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery, id int64, action string, accessInfo *models.AccessInfo, chatID int64, messageID int) {
+	state := b.GetState(callback.From.ID)
 	if action == "cancel" {
-		b.CleanupMessages(chatID, callback.From.ID)
-		b.ClearState(callback.From.ID)
+		b.Router.Cancel(b, callbackMessage(callback), state, accessInfo)
 		return
 	}
-
-	state := b.GetState(callback.From.ID)
 	if state == nil {
 		return
 	}
@@ -165,119 +416,79 @@ func handleOrgCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery, id int64, a
 		}
 		for _, org := range orgs {
 			if org.ID == id {
-				// Очищаем предыдущие сообщения
-				b.CleanupMessages(chatID, callback.From.ID)
-				b.SetState(callback.From.ID, "admin_managing_org", map[string]interface{}{
-					"org_id":   org.ID,
-					"org_name": org.Name,
-				})
-				b.SendMessageWithKeyboard(
-					chatID,
-					"Управление организацией *"+bot.EscapeMarkdown(org.Name)+"*\n\nВыберите действие:",
-					bot.GetOrgManageKeyboard(),
-				)
+				handlers.ShowAdminOrgMenu(b, callbackMessage(callback), org.ID, org.Name)
 				return
 			}
 		}
 	}
 }
 
-// handleMuscleCallback обрабатывает выбор группы мышц
-func handleMuscleCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery, action string, chatID int64, messageID int) {
-
+// handleMuscleCallback отмечает группы мышц новой тренировки, по «Готово» создаёт её.
+// Возвращает текст ответа на нажатие (пустой - без всплывающего сообщения).
+func handleMuscleCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery, id int64, action string, accessInfo *models.AccessInfo, chatID int64, messageID int) string {
+	state := b.GetState(callback.From.ID)
 	if action == "cancel" {
-		b.CleanupMessages(chatID, callback.From.ID)
-		b.ClearState(callback.From.ID)
-		accessInfo, _ := b.DB.GetUserAccessInfo( callback.From.ID, callback.From.UserName)
-		accessInfo.IsAdmin = b.IsAdmin(callback.From.UserName)
-		b.SendMessageWithKeyboard(chatID, "Отменено.", bot.GetStartMenuKeyboard(accessInfo))
-		return
+		b.Router.Cancel(b, callbackMessage(callback), state, accessInfo)
+		return ""
 	}
-
-	state := b.GetState(callback.From.ID)
-	if state == nil {
-		return
+	if state == nil || state.State != "awaiting_muscle_group" {
+		return ""
 	}
 
-	muscleMap := map[string]models.MuscleGroup{
-		"chest":     models.MuscleChest,
-		"back":      models.MuscleBack,
-		"legs":      models.MuscleLegs,
-		"shoulders": models.MuscleShoulders,
-		"biceps":    models.MuscleBiceps,
-		"triceps":   models.MuscleTriceps,
-		"abs":       models.MuscleAbs,
-		"cardio":    models.MuscleCardio,
+	if id > 0 {
+		groups, err := b.DB.GetMuscleGroups(handlers.StateOrgID(state.Data))
+		if err != nil {
+			b.Log(callback.From.ID).Error("Error getting muscle groups", "error", err)
+			return ""
+		}
+		selected := handlers.ToggleID(bot.GetStateInt64s(state.Data, "muscle_group_ids"), id)
+		data := bot.CopyStateData(state.Data)
+		data["muscle_group_ids"] = selected
+		b.SetState(callback.From.ID, state.State, data)
+		b.EditKeyboard(chatID, messageID, bot.GetInlineMuscleGroupKeyboard(groups, selected))
+		return ""
 	}
-
-	muscleGroup, ok := muscleMap[action]
-	if !ok {
-		return
+	if action != "done" {
+		return ""
 	}
 
-	// Извлекаем trainer_client_id если есть
-	var trainerClientID *int64
-	if state.Data != nil {
-		if tcID, ok := bot.GetStateInt64(state.Data, "trainer_client_id"); ok && tcID > 0 {
-			trainerClientID = &tcID
-		}
+	if len(bot.GetStateInt64s(state.Data, "muscle_group_ids")) == 0 {
+		return "Выберите хотя бы одну группу мышц"
 	}
+	data := bot.CopyStateData(state.Data)
+	data["inline"] = true
+	b.SetState(callback.From.ID, "awaiting_workout_date", data)
+	keyboard := bot.GetInlineWorkoutDateKeyboard()
+	b.EditText(chatID, messageID, bot.NewText(bot.Plain(handlers.WorkoutDatePrompt)), &keyboard)
+	return ""
+}
 
-	workout := &models.Workout{
-		TrainerClientID:  trainerClientID,
-		ClientTelegramID: callback.From.ID,
-		Date:             time.Now(),
-		MuscleGroup:      muscleGroup,
+// handleWorkoutDateCallback создаёт тренировку сейчас или вчерашним числом
+func handleWorkoutDateCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery, action string, accessInfo *models.AccessInfo) {
+	state := b.GetState(callback.From.ID)
+	if action == "cancel" {
+		b.Router.Cancel(b, callbackMessage(callback), state, accessInfo)
+		return
 	}
-
-	if err := b.DB.CreateWorkout(workout); err != nil {
-		log.Printf("Error creating workout: %v", err)
-		b.EditMessageText(chatID, messageID, "❌ Ошибка при создании тренировки.", nil)
+	if state == nil || state.State != "awaiting_workout_date" {
 		return
 	}
 
-	b.SetState(callback.From.ID, "adding_exercises", map[string]interface{}{
-		"workout_id":  workout.ID,
-		"telegram_id": callback.From.ID,
-		"order":       1,
-	})
-
-	// Очищаем предыдущие сообщения
-	b.CleanupMessages(chatID, callback.From.ID)
-
-	msgID := b.SendInlineKeyboard(
-		chatID,
-		"✅ Тренировка создана!\n\n*Добавьте упражнение:*\nОтправьте данные в формате:\n```\nНазвание\nПодходы\nПовторения\nВес (кг)\n```\n\nПример:\n```\nЖим лежа\n4\n10\n80\n```",
-		bot.GetInlineFinishKeyboard(),
-	)
-	// Сохраняем ID нового сообщения
-	b.StoreMessageID(callback.From.ID, msgID)
+	now := time.Now()
+	when := parser.Now(now)
+	if action == "yesterday" {
+		when, _ = parser.ParseWorkoutTime("вчера", now)
+	}
+	handlers.BeginWorkout(b, callbackMessage(callback), state, when)
 }
 
 // handleClientListCallback обрабатывает выбор клиента из списка
-func handleClientListCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery, id int64, action string, chatID int64, messageID int) {
+func handleClientListCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery, id int64, action string, accessInfo *models.AccessInfo, chatID int64, messageID int) {
+	state := b.GetState(callback.From.ID)
 	if action == "cancel" {
-		b.CleanupMessages(chatID, callback.From.ID)
-		state := b.GetState(callback.From.ID)
-		if state != nil {
-			trainerID, okT := bot.GetStateInt64(state.Data, "trainer_id")
-			orgID, okID := bot.GetStateInt64(state.Data, "org_id")
-			orgName, okName := bot.GetStateString(state.Data, "org_name")
-			if okT && okID && okName {
-				b.SetState(callback.From.ID, "trainer_managing_org", map[string]interface{}{
-					"trainer_id": trainerID,
-					"org_id":     orgID,
-					"org_name":   orgName,
-				})
-				b.SendMessageWithKeyboard(chatID, "🏋️ *Панель тренера - "+bot.EscapeMarkdown(orgName)+"*", bot.GetTrainerMenuKeyboard())
-				return
-			}
-		}
-		b.ClearState(callback.From.ID)
+		b.Router.Cancel(b, callbackMessage(callback), state, accessInfo)
 		return
 	}
-
-	state := b.GetState(callback.From.ID)
 	if state == nil {
 		return
 	}
@@ -291,24 +502,23 @@ func handleClientListCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery, id i
 	for _, client := range clients {
 		if client.Client.ID == id {
 			// Показываем информацию о клиенте
-			var sb strings.Builder
 			name := client.Client.Username
 			if client.FullName != "" {
 				name = client.FullName
 			}
 
-			sb.WriteString("👤 *Клиент: " + bot.EscapeMarkdown(name) + "*\n")
-			sb.WriteString("Username: @" + client.Client.Username + "\n")
-			sb.WriteString("Тренировок: " + strconv.Itoa(client.WorkoutCount) + "\n")
+			text := bot.NewText().Line(bot.Plain("👤 "), bot.Bold("Клиент: "+name))
+			text = text.Line(bot.Plain("Username: "), bot.Mention(client.Client.Username))
+			text = text.Line(bot.Plain("Тренировок: " + strconv.Itoa(client.WorkoutCount)))
 			if client.LastWorkout != nil {
-				sb.WriteString("Последняя: " + client.LastWorkout.Format("02.01.2006") + "\n")
+				text = text.Line(bot.Plain("Последняя: " + client.LastWorkout.Format("02.01.2006")))
 			}
 
 			status := "Активен ✅"
 			if !client.Client.IsActive {
 				status = "Деактивирован ❌"
 			}
-			sb.WriteString("Статус: " + status)
+			text = text.Add(bot.Plain("Статус: " + status))
 
 			b.SetState(callback.From.ID, "trainer_client_action", map[string]interface{}{
 				"trainer_id": state.Data["trainer_id"],
@@ -318,46 +528,47 @@ func handleClientListCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery, id i
 			})
 
 			keyboard := bot.GetInlineClientActionsKeyboard(client.Client.ID, client.Client.IsActive)
-			b.EditMessageText(chatID, messageID, sb.String(), &keyboard)
+			b.EditText(chatID, messageID, text, &keyboard)
 			return
 		}
 	}
 }
 
-// handleClientActionCallback обрабатывает действия с клиентом
-func handleClientActionCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery, id int64, action string, chatID int64, messageID int) {
+// handleClientActionCallback обрабатывает действия с клиентом и возвращает текст ответа на нажатие
+func handleClientActionCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery, id int64, action string, accessInfo *models.AccessInfo, chatID int64, messageID int) string {
 	state := b.GetState(callback.From.ID)
 	if state == nil {
-		return
+		return ""
 	}
 
 	if action == "back" {
 		// Возвращаемся к списку клиентов
-		b.CleanupMessages(chatID, callback.From.ID)
-		handlers.HandleListClients(b, &tgbotapi.Message{
-			Chat: &tgbotapi.Chat{ID: chatID},
-			From: callback.From,
-		})
-		return
+		b.Router.Cancel(b, callbackMessage(callback), state, accessInfo)
+		return ""
 	}
 
 	client, ok := state.Data["client"].(*models.ClientWithInfo)
 	if !ok || client == nil {
-		return
+		return ""
 	}
 
 	trainerID, okT := bot.GetStateInt64(state.Data, "trainer_id")
 	orgID, okID := bot.GetStateInt64(state.Data, "org_id")
 	orgName, okName := bot.GetStateString(state.Data, "org_name")
 	if !okT || !okID || !okName {
-		return
+		return ""
 	}
 
 	switch action {
 	case "stats":
-		b.EditMessageText(chatID, messageID, "📊 Статистика клиента @"+client.Client.Username+" будет добавлена позже.", nil)
+		handlers.ShowClientStats(b, chatID, messageID, callback.From, client.Client.ID)
 
 	case "workout":
+		groups, err := b.DB.GetMuscleGroups(&orgID)
+		if err != nil {
+			b.Log(callback.From.ID).Error("Error getting muscle groups", "error", err)
+			return "Ошибка. Попробуйте позже."
+		}
 		b.CleanupMessages(chatID, callback.From.ID)
 		b.SetState(callback.From.ID, "awaiting_muscle_group", map[string]interface{}{
 			"trainer_id":        trainerID,
@@ -366,23 +577,24 @@ func handleClientActionCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery, id
 			"client":            client,
 			"trainer_client_id": client.Client.ID,
 			"telegram_id":       callback.From.ID,
+			"muscle_group_ids":  []int64{},
 		})
-		keyboard := bot.GetInlineMuscleGroupKeyboard()
-		msgID := b.SendInlineKeyboard(chatID, "➕ *Создание тренировки для @"+client.Client.Username+"*\n\nВыберите группу мышц:", keyboard)
+		keyboard := bot.GetInlineMuscleGroupKeyboard(groups, nil)
+		msgID := b.SendInlineText(chatID,
+			bot.NewText(bot.Plain("➕ "), bot.Bold("Создание тренировки для @"+client.Client.Username), bot.Plain("\n\nВыберите группы мышц:")),
+			keyboard)
 		b.StoreMessageID(callback.From.ID, msgID)
 
 	case "history":
-		b.EditMessageText(chatID, messageID, "📋 История тренировок @"+client.Client.Username+" будет добавлена позже.", nil)
+		handlers.ShowHistoryPage(b, chatID, messageID, callback.From, bot.HistoryPosition{Client: client.Client.ID}, "")
 
 	case "delete":
 		if !client.Client.IsActive {
-			b.AnswerCallback(callback.ID, "Клиент уже деактивирован")
-			return
+			return "Клиент уже деактивирован"
 		}
 		if err := b.DB.RemoveClient( trainerID, client.Client.Username); err != nil {
-			log.Printf("Error removing client: %v", err)
-			b.AnswerCallback(callback.ID, "Ошибка удаления")
-			return
+			b.Log(callback.From.ID).Error("Error removing client", "error", err)
+			return "Ошибка удаления"
 		}
 		b.CleanupMessages(chatID, callback.From.ID)
 		b.SetState(callback.From.ID, "trainer_managing_org", map[string]interface{}{
@@ -390,148 +602,258 @@ func handleClientActionCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery, id
 			"org_id":     orgID,
 			"org_name":   orgName,
 		})
-		b.SendMessageWithKeyboard(
+		b.SendText(
 			chatID,
-			"✅ Клиент @"+client.Client.Username+" удалён.",
+			bot.NewText(bot.Plain("✅ Клиент "), bot.Mention(client.Client.Username), bot.Plain(" удалён.")),
 			bot.GetTrainerMenuKeyboard(),
 		)
 	}
+	return ""
 }
 
-// handleManagerListCallback обрабатывает выбор менеджера из списка
-func handleManagerListCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery, id int64, action string, chatID int64, messageID int) {
-
+// handleManagerListCallback обрабатывает выбор менеджера из списка и возвращает текст ответа на нажатие
+func handleManagerListCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery, id int64, action string, accessInfo *models.AccessInfo, chatID int64, messageID int) string {
+	state := b.GetState(callback.From.ID)
 	if action == "cancel" {
-		b.CleanupMessages(chatID, callback.From.ID)
-		state := b.GetState(callback.From.ID)
-		if state != nil {
-			orgID, okID := bot.GetStateInt64(state.Data, "org_id")
-			orgName, okName := bot.GetStateString(state.Data, "org_name")
-			if okID && okName {
-				b.SetState(callback.From.ID, "admin_managing_org", map[string]interface{}{
-					"org_id":   orgID,
-					"org_name": orgName,
-				})
-				b.SendMessageWithKeyboard(chatID, "Управление организацией *"+bot.EscapeMarkdown(orgName)+"*", bot.GetOrgManageKeyboard())
-				return
-			}
-		}
-		b.ClearState(callback.From.ID)
-		return
+		b.Router.Cancel(b, callbackMessage(callback), state, accessInfo)
+		return ""
 	}
-
-	state := b.GetState(callback.From.ID)
 	if state == nil {
-		return
+		return ""
 	}
 
 	managers, ok := state.Data["managers"].([]*models.OrganizationManager)
 	if !ok {
-		return
+		return ""
 	}
 
 	orgID, okID := bot.GetStateInt64(state.Data, "org_id")
 	orgName, okName := bot.GetStateString(state.Data, "org_name")
 	if !okID || !okName {
-		return
+		return ""
 	}
 
 	// Находим менеджера по ID
 	for _, manager := range managers {
 		if manager.ID == id {
 			if err := b.DB.RemoveManager( orgID, manager.Username); err != nil {
-				log.Printf("Error removing manager: %v", err)
-				b.AnswerCallback(callback.ID, "Ошибка удаления")
-				return
+				b.Log(callback.From.ID).Error("Error removing manager", "error", err)
+				return "Ошибка удаления"
 			}
 
-			b.CleanupMessages(chatID, callback.From.ID)
-			b.SetState(callback.From.ID, "admin_managing_org", map[string]interface{}{
-				"org_id":   orgID,
-				"org_name": orgName,
-			})
-			b.SendMessageWithKeyboard(
+			handlers.ShowAdminOrgMenu(b, callbackMessage(callback), orgID, orgName)
+			b.SendText(
 				chatID,
-				"✅ Менеджер @"+manager.Username+" удалён из организации *"+bot.EscapeMarkdown(orgName)+"*",
+				bot.NewText(bot.Plain("✅ Менеджер "), bot.Mention(manager.Username), bot.Plain(" удалён из организации "), bot.Bold(orgName)),
 				bot.GetOrgManageKeyboard(),
 			)
+			return ""
+		}
+	}
+	return ""
+}
+
+// handleWorkoutCallback обрабатывает просмотр, удаление тренировки и возврат к истории
+func handleWorkoutCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery, id int64, action string, chatID int64, messageID int) {
+	workout, ok := handlers.EditableWorkout(b, chatID, callback.From, id)
+	if !ok {
+		return
+	}
+
+	switch action {
+	case "":
+		handlers.ShowWorkoutEditor(b, chatID, messageID, callback.From, workout, "")
+	case "back":
+		handlers.ShowWorkoutHistory(b, chatID, messageID, callback.From, workout, "")
+	case "delete":
+		keyboard := bot.GetInlineDeleteConfirmKeyboard("workout", workout.ID)
+		b.EditText(chatID, messageID,
+			bot.NewText(bot.Plain("🗑 Удалить тренировку "), bot.Bold(workout.Date.Format("02.01.2006")+" - "+workout.MuscleGroupNames()), bot.Plain(" со всеми упражнениями?")),
+			&keyboard)
+	case "delete_confirm":
+		if err := b.DB.DeleteWorkout(workout.ID); err != nil {
+			b.Log(callback.From.ID).Error("Error deleting workout", "workout_id", workout.ID, "error", err)
+			b.SendMessage(chatID, "❌ Ошибка при удалении тренировки.")
 			return
 		}
+		handlers.ShowWorkoutHistory(b, chatID, messageID, callback.From, workout, "✅ Тренировка удалена.")
 	}
 }
 
-// handleTrainerListCallback обрабатывает выбор тренера из списка
-func handleTrainerListCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery, id int64, action string, chatID int64, messageID int) {
+// handleExerciseEditCallback обрабатывает правку и удаление упражнения
+func handleExerciseEditCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery, id int64, action string, chatID int64, messageID int) {
+	exercise, workout, ok := handlers.EditableExercise(b, chatID, callback.From, id)
+	if !ok {
+		return
+	}
 
-	if action == "cancel" {
-		b.CleanupMessages(chatID, callback.From.ID)
-		state := b.GetState(callback.From.ID)
-		if state != nil {
-			orgID, okID := bot.GetStateInt64(state.Data, "org_id")
-			orgName, okName := bot.GetStateString(state.Data, "org_name")
-			if okID && okName {
-				b.SetState(callback.From.ID, "manager_managing_org", map[string]interface{}{
-					"org_id":   orgID,
-					"org_name": orgName,
-				})
-				b.SendMessageWithKeyboard(chatID, "Управление организацией *"+bot.EscapeMarkdown(orgName)+"*", bot.GetManagerMenuKeyboard())
-				return
-			}
+	switch action {
+	case "":
+		handlers.ShowExerciseEditor(b, chatID, messageID, callback.From, exercise)
+	case "delete":
+		keyboard := bot.GetInlineDeleteConfirmKeyboard("exedit", exercise.ID)
+		b.EditText(chatID, messageID,
+			bot.NewText(bot.Plain("🗑 Удалить упражнение "), bot.Bold(exercise.Name), bot.Plain("?")),
+			&keyboard)
+	case "delete_confirm":
+		if err := b.DB.DeleteExercise(exercise.ID); err != nil {
+			b.Log(callback.From.ID).Error("Error deleting exercise", "exercise_id", exercise.ID, "error", err)
+			b.SendMessage(chatID, "❌ Ошибка при удалении упражнения.")
+			return
+		}
+		handlers.ShowWorkoutEditor(b, chatID, messageID, callback.From, workout, "✅ Упражнение удалено.")
+	default:
+		handlers.StartExerciseEdit(b, callbackMessage(callback), exercise, action)
+	}
+}
+
+// handleTemplateCallback обрабатывает список, карточку, назначение и удаление шаблона
+func handleTemplateCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery, id int64, action string, chatID int64, messageID int) {
+	if action == "new" {
+		handlers.StartTemplateCreation(b, callbackMessage(callback))
+		return
+	}
+
+	template, trainerID, ok := handlers.EditableTemplate(b, chatID, callback.From, id)
+	if !ok {
+		return
+	}
+
+	switch action {
+	case "":
+		handlers.ShowTemplateCard(b, chatID, messageID, callback.From, template, "")
+	case "back":
+		handlers.ShowTemplateList(b, chatID, messageID, callback.From, template.OrganizationID, "")
+	case "assign":
+		handlers.ShowTemplateAssign(b, chatID, messageID, callback.From, template, trainerID)
+	case "delete":
+		keyboard := bot.GetInlineDeleteConfirmKeyboard("template", template.ID)
+		b.EditText(chatID, messageID,
+			bot.NewText(bot.Plain("🗑 Удалить шаблон "), bot.Bold(template.Name), bot.Plain("? Назначения клиентам тоже будут сняты, записанные тренировки останутся.")),
+			&keyboard)
+	case "delete_confirm":
+		if err := b.DB.DeleteTemplate(template.ID); err != nil {
+			b.Log(callback.From.ID).Error("Error deleting template", "template_id", template.ID, "error", err)
+			b.SendMessage(chatID, "❌ Ошибка при удалении шаблона.")
+			return
 		}
-		b.ClearState(callback.From.ID)
+		handlers.ShowTemplateList(b, chatID, messageID, callback.From, template.OrganizationID, "✅ Шаблон удалён.")
+	}
+}
+
+// handleMuscleGroupCallback управляет группами мышц организации (mgroup:new, mgroup:<id>:delete)
+func handleMuscleGroupCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery, id int64, action string, chatID int64, messageID int) {
+	if action == "new" {
+		handlers.StartMuscleGroupCreation(b, callbackMessage(callback))
 		return
 	}
 
+	group, ok := handlers.ManagedMuscleGroup(b, chatID, callback.From, id)
+	if !ok {
+		return
+	}
 	state := b.GetState(callback.From.ID)
 	if state == nil {
 		return
 	}
+	orgName, _ := bot.GetStateString(state.Data, "org_name")
 
-	trainers, ok := state.Data["trainers"].([]*models.OrganizationTrainer)
+	switch action {
+	case "":
+		handlers.ShowMuscleGroups(b, chatID, messageID, callback.From, *group.OrganizationID, orgName, "")
+	case "delete":
+		keyboard := bot.GetInlineDeleteConfirmKeyboard("mgroup", group.ID)
+		b.EditText(chatID, messageID,
+			bot.NewText(bot.Plain("🗑 Удалить группу мышц "), bot.Bold(string(group.Name)), bot.Plain("? В записанных тренировках она останется.")),
+			&keyboard)
+	case "delete_confirm":
+		if err := b.DB.DeleteMuscleGroup(group.ID); err != nil {
+			b.Log(callback.From.ID).Error("Error deleting muscle group", "group_id", group.ID, "error", err)
+			b.SendMessage(chatID, "❌ Ошибка при удалении группы мышц.")
+			return
+		}
+		handlers.ShowMuscleGroups(b, chatID, messageID, callback.From, *group.OrganizationID, orgName, "✅ Группа мышц удалена.")
+	}
+}
+
+// handleTemplateAssignCallback назначает шаблон клиенту или снимает назначение (tassign:<шаблон>:<клиент>)
+func handleTemplateAssignCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery, id int64, action string, chatID int64, messageID int) {
+	trainerClientID, err := strconv.ParseInt(action, 10, 64)
+	if err != nil {
+		return
+	}
+	template, trainerID, ok := handlers.EditableTemplate(b, chatID, callback.From, id)
 	if !ok {
 		return
 	}
+	handlers.ToggleTemplateAssignment(b, chatID, messageID, callback.From, template, trainerID, trainerClientID)
+}
+
+// handleTrainerListCallback обрабатывает выбор тренера из списка и возвращает текст ответа на нажатие
+func handleTrainerListCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery, id int64, action string, accessInfo *models.AccessInfo, chatID int64, messageID int) string {
+	state := b.GetState(callback.From.ID)
+	if action == "cancel" {
+		b.Router.Cancel(b, callbackMessage(callback), state, accessInfo)
+		return ""
+	}
+	if state == nil {
+		return ""
+	}
+
+	trainers, ok := state.Data["trainers"].([]*models.OrganizationTrainer)
+	if !ok {
+		return ""
+	}
 
 	orgID, okID := bot.GetStateInt64(state.Data, "org_id")
 	orgName, okName := bot.GetStateString(state.Data, "org_name")
 	if !okID || !okName {
-		return
+		return ""
 	}
 
 	// Находим тренера по ID
 	for _, trainer := range trainers {
 		if trainer.ID == id {
 			if err := b.DB.RemoveTrainer( orgID, trainer.Username); err != nil {
-				log.Printf("Error removing trainer: %v", err)
-				b.AnswerCallback(callback.ID, "Ошибка удаления")
-				return
+				b.Log(callback.From.ID).Error("Error removing trainer", "error", err)
+				return "Ошибка удаления"
 			}
 
-			b.CleanupMessages(chatID, callback.From.ID)
-			b.SetState(callback.From.ID, "manager_managing_org", map[string]interface{}{
-				"org_id":   orgID,
-				"org_name": orgName,
-			})
-			b.SendMessageWithKeyboard(
+			handlers.ShowManagerOrgMenu(b, callbackMessage(callback), orgID, orgName)
+			b.SendText(
 				chatID,
-				"✅ Тренер @"+trainer.Username+" удалён из организации *"+bot.EscapeMarkdown(orgName)+"*",
+				bot.NewText(bot.Plain("✅ Тренер "), bot.Mention(trainer.Username), bot.Plain(" удалён из организации "), bot.Bold(orgName)),
 				bot.GetManagerMenuKeyboard(),
 			)
-			return
+			return ""
 		}
 	}
+	return ""
 }
 
-// handleExerciseCallback обрабатывает завершение/отмену добавления упражнений
+// handleExerciseCallback обрабатывает завершение/отмену добавления упражнений.
+// Отмена удаляет черновик тренировки вместе с добавленными упражнениями.
 func handleExerciseCallback(b *bot.Bot, callback *tgbotapi.CallbackQuery, action string, accessInfo *models.AccessInfo, chatID int64, messageID int) {
-	b.CleanupMessages(chatID, callback.From.ID)
-	b.ClearState(callback.From.ID)
+	workoutID, active := int64(0), false
+	if state := b.GetState(callback.From.ID); state != nil && state.State == "adding_exercises" {
+		workoutID, active = bot.GetStateInt64(state.Data, "workout_id")
+	}
+	if !active {
+		// Кнопка от уже закрытой тренировки: текущий сценарий пользователя не трогаем
+		b.SendMessage(chatID, "⚠️ Нет активной тренировки.")
+		return
+	}
 
+	text := "❌ Тренировка отменена."
 	if action == "finish" {
-		b.SendMessageWithKeyboard(chatID, "✅ Тренировка сохранена! 💪", bot.GetStartMenuKeyboard(accessInfo))
+		text = handlers.FinishedWorkoutText(handlers.FinishWorkout(b, callback.From.ID, workoutID))
 	} else {
-		b.SendMessageWithKeyboard(chatID, "❌ Тренировка отменена.", bot.GetStartMenuKeyboard(accessInfo))
+		handlers.CancelWorkout(b, callback.From.ID, workoutID)
 	}
+	b.CleanupMessages(chatID, callback.From.ID)
+	b.ClearState(callback.From.ID)
+
+	b.SendMessageWithKeyboard(chatID, text, bot.GetStartMenuKeyboard(accessInfo))
 }
 
 func handleUpdate(b *bot.Bot, message *tgbotapi.Message) {
@@ -539,7 +861,7 @@ func handleUpdate(b *bot.Bot, message *tgbotapi.Message) {
 	// Связываем telegram_id с username при каждом сообщении
 	if message.From.UserName != "" {
 		if err := b.DB.LinkTelegramID( message.From.ID, message.From.UserName); err != nil {
-			log.Printf("Error linking telegram ID: %v", err)
+			b.Log(message.From.ID).Error("Error linking telegram ID", "error", err)
 		}
 	}
 
@@ -547,7 +869,7 @@ func handleUpdate(b *bot.Bot, message *tgbotapi.Message) {
 	username := message.From.UserName
 	accessInfo, err := b.DB.GetUserAccessInfo( message.From.ID, username)
 	if err != nil {
-		log.Printf("Error getting access info: %v", err)
+		b.Log(message.From.ID).Error("Error getting access info", "error", err)
 		b.SendMessage(message.Chat.ID, "❌ Ошибка при проверке доступов.")
 		return
 	}
@@ -560,20 +882,18 @@ func handleUpdate(b *bot.Bot, message *tgbotapi.Message) {
 
 	state := b.GetState(message.From.ID)
 
+	// Меню команд в Telegram зависит от ролей пользователя
+	updateCommandMenu(b, message, accessInfo)
+
 	// Обработка команд
 	if message.IsCommand() {
-		switch message.Command() {
-		case "start":
-			handleStartCommand(b, message, accessInfo)
-		default:
-			b.SendMessage(message.Chat.ID, "Неизвестная команда. Используйте /start")
-		}
+		handleCommand(b, message, state, accessInfo)
 		return
 	}
 
 	// Обработка состояний
 	if state != nil {
-		handleState(b, message, state, accessInfo)
+		b.Router.Handle(b, message, state, accessInfo)
 		return
 	}
 
@@ -640,212 +960,6 @@ func handleStartCommand(b *bot.Bot, message *tgbotapi.Message, accessInfo *model
 	b.SendMessageWithKeyboard(message.Chat.ID, sb.String(), bot.GetStartMenuKeyboard(accessInfo))
 }
 
-func handleState(b *bot.Bot, message *tgbotapi.Message, state *models.UserState, accessInfo *models.AccessInfo) {
-	// Проверка на "Главное меню" или "Отмена"
-	if message.Text == "🔙 Главное меню" {
-		b.ClearState(message.From.ID)
-		handleStartCommand(b, message, accessInfo)
-		return
-	}
-
-	switch state.State {
-	// ===== АДМИН =====
-	case "admin_creating_org_name":
-		handlers.HandleCreateOrganizationName(b, message)
-	case "admin_creating_org_code":
-		handlers.HandleCreateOrganizationCode(b, message)
-	case "admin_selecting_org":
-		if message.Text == "❌ Отмена" {
-			b.ClearState(message.From.ID)
-			handlers.HandleAdminMenu(b, message)
-			return
-		}
-		if idx, err := strconv.Atoi(message.Text); err == nil {
-			handlers.HandleSelectOrganization(b, message, idx)
-		} else {
-			b.SendMessage(message.Chat.ID, "⚠️ Введите номер организации или нажмите «❌ Отмена»")
-		}
-	case "admin_managing_org":
-		handleAdminOrgActions(b, message, accessInfo)
-	case "admin_adding_manager":
-		handlers.HandleAddManagerUsername(b, message)
-	case "admin_removing_manager":
-		if message.Text == "❌ Отмена" {
-			orgID, okID := bot.GetStateInt64(state.Data, "org_id")
-			orgName, okName := bot.GetStateString(state.Data, "org_name")
-			if !okID || !okName {
-				b.ClearState(message.From.ID)
-				handlers.HandleAdminMenu(b, message)
-				return
-			}
-			b.SetState(message.From.ID, "admin_managing_org", map[string]interface{}{
-				"org_id":   orgID,
-				"org_name": orgName,
-			})
-			b.SendMessageWithKeyboard(message.Chat.ID,
-				"Управление организацией *"+bot.EscapeMarkdown(orgName)+"*",
-				bot.GetOrgManageKeyboard())
-			return
-		}
-		if idx, err := strconv.Atoi(message.Text); err == nil {
-			handlers.HandleRemoveManager(b, message, idx)
-		} else {
-			b.SendMessage(message.Chat.ID, "⚠️ Введите номер менеджера для удаления или нажмите «❌ Отмена»")
-		}
-
-	// ===== МЕНЕДЖЕР =====
-	case "manager_selecting_org":
-		if message.Text == "❌ Отмена" {
-			b.ClearState(message.From.ID)
-			handleStartCommand(b, message, accessInfo)
-			return
-		}
-		if idx, err := strconv.Atoi(message.Text); err == nil {
-			handlers.HandleManagerSelectOrg(b, message, idx)
-		} else {
-			b.SendMessage(message.Chat.ID, "⚠️ Введите номер организации или нажмите «❌ Отмена»")
-		}
-	case "manager_managing_org":
-		handleManagerOrgActions(b, message, accessInfo)
-	case "manager_adding_trainer":
-		handlers.HandleAddTrainerUsername(b, message)
-	case "manager_removing_trainer":
-		if message.Text == "❌ Отмена" {
-			orgID, okID := bot.GetStateInt64(state.Data, "org_id")
-			orgName, okName := bot.GetStateString(state.Data, "org_name")
-			if !okID || !okName {
-				b.ClearState(message.From.ID)
-				handleStartCommand(b, message, accessInfo)
-				return
-			}
-			b.SetState(message.From.ID, "manager_managing_org", map[string]interface{}{
-				"org_id":   orgID,
-				"org_name": orgName,
-			})
-			b.SendMessageWithKeyboard(message.Chat.ID,
-				"Управление организацией *"+bot.EscapeMarkdown(orgName)+"*",
-				bot.GetManagerMenuKeyboard())
-			return
-		}
-		if idx, err := strconv.Atoi(message.Text); err == nil {
-			handlers.HandleRemoveTrainer(b, message, idx)
-		} else {
-			b.SendMessage(message.Chat.ID, "⚠️ Введите номер тренера для удаления или нажмите «❌ Отмена»")
-		}
-
-	// ===== ТРЕНЕР =====
-	case "trainer_selecting_org":
-		if message.Text == "❌ Отмена" {
-			b.ClearState(message.From.ID)
-			handleStartCommand(b, message, accessInfo)
-			return
-		}
-		if idx, err := strconv.Atoi(message.Text); err == nil {
-			handlers.HandleTrainerSelectOrg(b, message, idx)
-		} else {
-			b.SendMessage(message.Chat.ID, "⚠️ Введите номер организации или нажмите «❌ Отмена»")
-		}
-	case "trainer_managing_org":
-		handleTrainerOrgActions(b, message, accessInfo)
-	case "trainer_adding_client":
-		handlers.HandleAddClientUsername(b, message)
-	case "trainer_viewing_clients":
-		text := message.Text
-		if text == "❌ Отмена" {
-			trainerID, okT := bot.GetStateInt64(state.Data, "trainer_id")
-			orgID, okID := bot.GetStateInt64(state.Data, "org_id")
-			orgName, okName := bot.GetStateString(state.Data, "org_name")
-			if !okT || !okID || !okName {
-				b.ClearState(message.From.ID)
-				handleStartCommand(b, message, accessInfo)
-				return
-			}
-			b.SetState(message.From.ID, "trainer_managing_org", map[string]interface{}{
-				"trainer_id": trainerID,
-				"org_id":     orgID,
-				"org_name":   orgName,
-			})
-			b.SendMessageWithKeyboard(message.Chat.ID,
-				"🏋️ *Панель тренера - "+bot.EscapeMarkdown(orgName)+"*",
-				bot.GetTrainerMenuKeyboard())
-			return
-		}
-		if strings.HasPrefix(strings.ToLower(text), "удалить ") {
-			parts := strings.Fields(text)
-			if len(parts) >= 2 {
-				if idx, err := strconv.Atoi(parts[1]); err == nil {
-					handlers.HandleRemoveClientByIndex(b, message, idx)
-					return
-				}
-			}
-		}
-		if idx, err := strconv.Atoi(text); err == nil {
-			handlers.HandleSelectClient(b, message, idx)
-		} else {
-			b.SendMessage(message.Chat.ID, "⚠️ Введите номер клиента, «удалить [номер]» или «❌ Отмена»")
-		}
-	case "trainer_client_action":
-		if idx, err := strconv.Atoi(message.Text); err == nil {
-			handlers.HandleClientAction(b, message, idx)
-		} else {
-			b.SendMessage(message.Chat.ID, "⚠️ Введите номер действия (1-4)")
-		}
-
-	// ===== КЛИЕНТ =====
-	case "client_selecting_trainer":
-		if message.Text == "❌ Отмена" {
-			b.ClearState(message.From.ID)
-			handleStartCommand(b, message, accessInfo)
-			return
-		}
-		if idx, err := strconv.Atoi(message.Text); err == nil {
-			handlers.HandleClientSelectTrainer(b, message, idx)
-		} else {
-			b.SendMessage(message.Chat.ID, "⚠️ Введите номер тренера или нажмите «❌ Отмена»")
-		}
-	case "client_with_trainer":
-		handleClientActions(b, message, accessInfo)
-	case "client_viewing_archive":
-		if message.Text == "❌ Отмена" {
-			b.ClearState(message.From.ID)
-			handleStartCommand(b, message, accessInfo)
-			return
-		}
-		if idx, err := strconv.Atoi(message.Text); err == nil {
-			handlers.HandleSelectArchivedTrainer(b, message, idx)
-		} else {
-			b.SendMessage(message.Chat.ID, "⚠️ Введите номер записи или нажмите «❌ Отмена»")
-		}
-
-	// ===== ТРЕНИРОВКИ =====
-	case "awaiting_muscle_group":
-		handlers.HandleMuscleGroupSelection(b, message)
-	case "adding_exercises":
-		handlers.HandleAddExercise(b, message)
-	case "awaiting_exercise_name":
-		handlers.HandleExerciseNameForStats(b, message)
-
-	// ===== ГРУППОВЫЕ ТРЕНИРОВКИ =====
-	case "joining_group_training":
-		if message.Text == "❌ Отмена" {
-			b.ClearState(message.From.ID)
-			handleStartCommand(b, message, accessInfo)
-			return
-		}
-		if idx, err := strconv.Atoi(message.Text); err == nil {
-			handlers.HandleJoinGroupTraining(b, message, idx)
-		} else {
-			b.SendMessage(message.Chat.ID, "⚠️ Введите номер тренировки или нажмите «❌ Отмена»")
-		}
-	case "creating_group_training":
-		handlers.HandleCreateGroupTrainingData(b, message)
-
-	default:
-		b.ClearState(message.From.ID)
-		handleStartCommand(b, message, accessInfo)
-	}
-}
-
 func handleMenuButtons(b *bot.Bot, message *tgbotapi.Message, accessInfo *models.AccessInfo) {
 	switch message.Text {
 	// ===== АДМИН =====
@@ -883,6 +997,9 @@ func handleMenuButtons(b *bot.Bot, message *tgbotapi.Message, accessInfo *models
 	case "📚 Архив тренировок":
 		handlers.HandleArchivedAccess(b, message, accessInfo.ArchivedAccess)
 
+	case bot.SettingsText:
+		handlers.HandleSettings(b, message)
+
 	case "ℹ️ О боте":
 		handlers.HandleNoAccess(b, message)
 
@@ -896,7 +1013,7 @@ func handleMenuButtons(b *bot.Bot, message *tgbotapi.Message, accessInfo *models
 }
 
 // handleAdminOrgActions обрабатывает действия в управлении организацией (админ)
-func handleAdminOrgActions(b *bot.Bot, message *tgbotapi.Message, accessInfo *models.AccessInfo) {
+func handleAdminOrgActions(b *bot.Bot, message *tgbotapi.Message, _ *models.UserState, _ *models.AccessInfo) {
 	switch message.Text {
 	case "➕ Добавить менеджера":
 		handlers.HandleAddManager(b, message)
@@ -910,22 +1027,23 @@ func handleAdminOrgActions(b *bot.Bot, message *tgbotapi.Message, accessInfo *mo
 }
 
 // handleManagerOrgActions обрабатывает действия в панели менеджера
-func handleManagerOrgActions(b *bot.Bot, message *tgbotapi.Message, accessInfo *models.AccessInfo) {
+func handleManagerOrgActions(b *bot.Bot, message *tgbotapi.Message, _ *models.UserState, _ *models.AccessInfo) {
 	switch message.Text {
 	case "➕ Добавить тренера":
 		handlers.HandleAddTrainer(b, message)
 	case "📋 Список тренеров":
 		handlers.HandleListTrainers(b, message)
-	case "🔙 Главное меню":
-		b.ClearState(message.From.ID)
-		handleStartCommand(b, message, accessInfo)
+	case "💪 Группы мышц":
+		handlers.HandleMuscleGroups(b, message)
+	case "⏱ Отдых по умолчанию":
+		handlers.HandleRestSettings(b, message)
 	default:
 		b.SendMessage(message.Chat.ID, "Выберите действие из меню.")
 	}
 }
 
 // handleTrainerOrgActions обрабатывает действия в панели тренера
-func handleTrainerOrgActions(b *bot.Bot, message *tgbotapi.Message, accessInfo *models.AccessInfo) {
+func handleTrainerOrgActions(b *bot.Bot, message *tgbotapi.Message, _ *models.UserState, _ *models.AccessInfo) {
 	switch message.Text {
 	case "➕ Добавить клиента":
 		handlers.HandleAddClient(b, message)
@@ -935,16 +1053,15 @@ func handleTrainerOrgActions(b *bot.Bot, message *tgbotapi.Message, accessInfo *
 		handlers.HandleGroupTrainings(b, message)
 	case "📊 Статистика":
 		handlers.HandleStats(b, message)
-	case "🔙 Главное меню":
-		b.ClearState(message.From.ID)
-		handleStartCommand(b, message, accessInfo)
+	case "📋 Шаблоны тренировок":
+		handlers.HandleTemplates(b, message)
 	default:
 		b.SendMessage(message.Chat.ID, "Выберите действие из меню.")
 	}
 }
 
 // handleClientActions обрабатывает действия в панели клиента
-func handleClientActions(b *bot.Bot, message *tgbotapi.Message, accessInfo *models.AccessInfo) {
+func handleClientActions(b *bot.Bot, message *tgbotapi.Message, _ *models.UserState, _ *models.AccessInfo) {
 	switch message.Text {
 	case "➕ Добавить тренировку":
 		handlers.HandleAddWorkout(b, message)
@@ -954,9 +1071,8 @@ func handleClientActions(b *bot.Bot, message *tgbotapi.Message, accessInfo *mode
 		handlers.HandleStats(b, message)
 	case "📅 Групповые тренировки":
 		handlers.HandleGroupTrainings(b, message)
-	case "🔙 Главное меню":
-		b.ClearState(message.From.ID)
-		handleStartCommand(b, message, accessInfo)
+	case "📋 Программы тренера":
+		handlers.HandleAssignedTemplates(b, message)
 	default:
 		b.SendMessage(message.Chat.ID, "Выберите действие из меню.")
 	}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"fitness-bot/internal/bot"
+	"fitness-bot/internal/handlers"
+	"fitness-bot/internal/models"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// newRouter регистрирует все состояния диалогов.
+// Новому сценарию достаточно добавить сюда свои состояния.
+func newRouter() *bot.Router {
+	r := bot.NewRouter()
+
+	// ===== ГЛАВНОЕ МЕНЮ =====
+	r.Register(bot.Route{
+		State: bot.StateRoot,
+		Enter: func(b *bot.Bot, message *tgbotapi.Message, _ map[string]interface{}, accessInfo *models.AccessInfo) {
+			handleStartCommand(b, message, accessInfo)
+		},
+	})
+
+	// ===== АДМИН =====
+	r.Register(bot.Route{
+		State: "admin_menu",
+		Enter: func(b *bot.Bot, message *tgbotapi.Message, _ map[string]interface{}, _ *models.AccessInfo) {
+			b.ClearState(message.From.ID)
+			handlers.HandleAdminMenu(b, message)
+		},
+	})
+	r.Register(bot.Route{
+		State:   "admin_creating_org_name",
+		Handler: plain(handlers.HandleCreateOrganizationName),
+		Parent:  "admin_menu",
+		Next:    []string{"admin_creating_org_code"},
+	})
+	r.Register(bot.Route{
+		State:   "admin_creating_org_code",
+		Handler: plain(handlers.HandleCreateOrganizationCode),
+		Parent:  "admin_menu",
+	})
+	r.Register(bot.Route{
+		State:   "admin_selecting_org",
+		Handler: byIndex(handlers.HandleSelectOrganization, "⚠️ Введите номер организации или нажмите «❌ Отмена»"),
+		Parent:  "admin_menu",
+		Next:    []string{"admin_managing_org"},
+	})
+	r.Register(bot.Route{
+		State:   "admin_managing_org",
+		Handler: handleAdminOrgActions,
+		Enter:   orgEnter(handlers.ShowAdminOrgMenu, "admin_menu"),
+		Next:    []string{"admin_adding_manager", "admin_removing_manager", "admin_selecting_org"},
+	})
+	r.Register(bot.Route{
+		State:   "admin_adding_manager",
+		Handler: plain(handlers.HandleAddManagerUsername),
+		Parent:  "admin_managing_org",
+	})
+	r.Register(bot.Route{
+		State:   "admin_removing_manager",
+		Handler: byIndex(handlers.HandleRemoveManager, "⚠️ Введите номер менеджера для удаления или нажмите «❌ Отмена»"),
+		Parent:  "admin_managing_org",
+	})
+
+	// ===== МЕНЕДЖЕР =====
+	r.Register(bot.Route{
+		State:   "manager_selecting_org",
+		Handler: byIndex(handlers.HandleManagerSelectOrg, "⚠️ Введите номер организации или нажмите «❌ Отмена»"),
+		Parent:  bot.StateRoot,
+		Next:    []string{"manager_managing_org"},
+	})
+	r.Register(bot.Route{
+		State:   "manager_managing_org",
+		Handler: handleManagerOrgActions,
+		Enter:   orgEnter(handlers.ShowManagerOrgMenu, bot.StateRoot),
+		Next:    []string{"manager_adding_trainer", "manager_removing_trainer", "manager_adding_muscle_group", "manager_setting_rest"},
+	})
+	r.Register(bot.Route{
+		State:   "manager_adding_trainer",
+		Handler: plain(handlers.HandleAddTrainerUsername),
+		Parent:  "manager_managing_org",
+	})
+	r.Register(bot.Route{
+		State:   "manager_removing_trainer",
+		Handler: byIndex(handlers.HandleRemoveTrainer, "⚠️ Введите номер тренера для удаления или нажмите «❌ Отмена»"),
+		Parent:  "manager_managing_org",
+	})
+	r.Register(bot.Route{
+		State:   "manager_adding_muscle_group",
+		Handler: plain(handlers.HandleAddMuscleGroupName),
+		Parent:  "manager_managing_org",
+	})
+	r.Register(bot.Route{
+		State:   "manager_setting_rest",
+		Handler: plain(handlers.HandleSetDefaultRest),
+		Parent:  "manager_managing_org",
+	})
+
+	// ===== ТРЕНЕР =====
+	r.Register(bot.Route{
+		State:   "trainer_selecting_org",
+		Handler: byIndex(handlers.HandleTrainerSelectOrg, "⚠️ Введите номер организации или нажмите «❌ Отмена»"),
+		Parent:  bot.StateRoot,
+		Next:    []string{"trainer_managing_org"},
+	})
+	r.Register(bot.Route{
+		State:   "trainer_managing_org",
+		Handler: handleTrainerOrgActions,
+		Enter:   trainerOrgEnter,
+		Next:    []string{"trainer_adding_client", "trainer_viewing_clients", "awaiting_exercise_name", "joining_group_training", "editing_exercise", "template_creating_name", "awaiting_history_range"},
+	})
+	r.Register(bot.Route{
+		State:   "trainer_adding_client",
+		Handler: plain(handlers.HandleAddClientUsername),
+		Parent:  "trainer_managing_org",
+	})
+	r.Register(bot.Route{
+		State:   "trainer_viewing_clients",
+		Handler: handleTrainerViewingClients,
+		Enter: func(b *bot.Bot, message *tgbotapi.Message, _ map[string]interface{}, _ *models.AccessInfo) {
+			handlers.HandleListClients(b, message)
+		},
+		Parent: "trainer_managing_org",
+		Next:   []string{"trainer_client_action"},
+	})
+	r.Register(bot.Route{
+		State:   "trainer_client_action",
+		Handler: byIndex(handlers.HandleClientAction, "⚠️ Введите номер действия (1-4)"),
+		Parent:  "trainer_viewing_clients",
+		Next:    []string{"awaiting_muscle_group", "trainer_managing_org", "awaiting_history_range"},
+	})
+	r.Register(bot.Route{
+		State:   "template_creating_name",
+		Handler: plain(handlers.HandleTemplateName),
+		Parent:  "trainer_managing_org",
+		Next:    []string{"template_selecting_muscle"},
+	})
+	r.Register(bot.Route{
+		State:   "template_selecting_muscle",
+		Handler: plain(handlers.HandleTemplateMuscleGroup),
+		Parent:  "trainer_managing_org",
+		Next:    []string{"template_adding_exercises"},
+	})
+	r.Register(bot.Route{
+		// «❌ Отмена» удаляет уже созданный шаблон - обрабатывается в хендлере
+		State:   "template_adding_exercises",
+		Handler: plain(handlers.HandleTemplateExercise),
+		Next:    []string{"trainer_managing_org"},
+	})
+
+	// ===== КЛИЕНТ =====
+	r.Register(bot.Route{
+		State:   "client_selecting_trainer",
+		Handler: byIndex(handlers.HandleClientSelectTrainer, "⚠️ Введите номер тренера или нажмите «❌ Отмена»"),
+		Parent:  bot.StateRoot,
+		Next:    []string{"client_with_trainer"},
+	})
+	r.Register(bot.Route{
+		State:   "client_with_trainer",
+		Handler: handleClientActions,
+		Next:    []string{"awaiting_muscle_group", "awaiting_exercise_name", "joining_group_training", "editing_exercise", "template_workout", "awaiting_history_range"},
+	})
+	r.Register(bot.Route{
+		// «❌ Отмена» удаляет черновик тренировки вместе с упражнениями - обрабатывается в хендлере
+		State:   "template_workout",
+		Handler: plain(handlers.HandleTemplateWorkout),
+		Next:    []string{"client_with_trainer"},
+	})
+	r.Register(bot.Route{
+		State:   "client_viewing_archive",
+		Handler: byIndex(handlers.HandleSelectArchivedTrainer, "⚠️ Введите номер записи или нажмите «❌ Отмена»"),
+		Parent:  bot.StateRoot,
+		Next:    []string{"awaiting_history_range"},
+	})
+
+	// ===== ТРЕНИРОВКИ =====
+	r.Register(bot.Route{
+		State:   "awaiting_muscle_group",
+		Handler: plain(handlers.HandleMuscleGroupSelection),
+		Parent:  bot.StateRoot,
+		Next:    []string{"awaiting_workout_date"},
+	})
+	r.Register(bot.Route{
+		State:   "awaiting_workout_date",
+		Handler: plain(handlers.HandleWorkoutDate),
+		Parent:  bot.StateRoot,
+		Next:    []string{"adding_exercises"},
+	})
+	r.Register(bot.Route{
+		// «❌ Отмена» удаляет черновик тренировки вместе с упражнениями - обрабатывается в хендлере
+		State:   "adding_exercises",
+		Handler: plain(handlers.HandleAddExercise),
+	})
+	r.Register(bot.Route{
+		// «❌ Отмена» возвращает в состояние, из которого начали правку - обрабатывается в хендлере
+		State:   "editing_exercise",
+		Handler: handlers.HandleEditExerciseValue,
+		Next:    []string{"client_with_trainer", "trainer_managing_org"},
+	})
+	r.Register(bot.Route{
+		// «❌ Отмена» возвращает в состояние, из которого открыли ввод - обрабатывается в хендлере
+		State:   "awaiting_history_range",
+		Handler: handlers.HandleHistoryRange,
+		Next:    []string{"client_with_trainer", "trainer_managing_org"},
+	})
+	r.Register(bot.Route{
+		State:   "awaiting_exercise_name",
+		Handler: plain(handlers.HandleExerciseNameForStats),
+		Parent:  bot.StateRoot,
+	})
+
+	r.Register(bot.Route{
+		State:   "awaiting_bodyweight",
+		Handler: plain(handlers.HandleBodyweight),
+		Parent:  bot.StateRoot,
+	})
+
+	// ===== ГРУППОВЫЕ ТРЕНИРОВКИ =====
+	r.Register(bot.Route{
+		State:   "joining_group_training",
+		Handler: byIndex(handlers.HandleJoinGroupTraining, "⚠️ Введите номер тренировки или нажмите «❌ Отмена»"),
+		Parent:  bot.StateRoot,
+	})
+	r.Register(bot.Route{
+		State:   "creating_group_training",
+		Handler: plain(handlers.HandleCreateGroupTrainingData),
+		Parent:  bot.StateRoot,
+	})
+
+	return r
+}
+
+// plain адаптирует хендлер, которому нужно только сообщение
+func plain(h func(b *bot.Bot, message *tgbotapi.Message)) bot.StateHandler {
+	return func(b *bot.Bot, message *tgbotapi.Message, _ *models.UserState, _ *models.AccessInfo) {
+		h(b, message)
+	}
+}
+
+// byIndex адаптирует хендлер выбора элемента списка по номеру
+func byIndex(h func(b *bot.Bot, message *tgbotapi.Message, idx int), prompt string) bot.StateHandler {
+	return func(b *bot.Bot, message *tgbotapi.Message, _ *models.UserState, _ *models.AccessInfo) {
+		if idx, err := strconv.Atoi(message.Text); err == nil {
+			h(b, message, idx)
+		} else {
+			b.SendMessage(message.Chat.ID, prompt)
+		}
+	}
+}
+
+// orgEnter возвращает в меню организации по org_id/org_name из данных состояния
+func orgEnter(show func(b *bot.Bot, message *tgbotapi.Message, orgID int64, orgName string), fallback string) bot.EnterHandler {
+	return func(b *bot.Bot, message *tgbotapi.Message, data map[string]interface{}, accessInfo *models.AccessInfo) {
+		orgID, okID := bot.GetStateInt64(data, "org_id")
+		orgName, okName := bot.GetStateString(data, "org_name")
+		if !okID || !okName {
+			b.Router.Navigate(b, message, fallback, nil, accessInfo)
+			return
+		}
+		show(b, message, orgID, orgName)
+	}
+}
+
+// trainerOrgEnter возвращает в панель тренера
+func trainerOrgEnter(b *bot.Bot, message *tgbotapi.Message, data map[string]interface{}, accessInfo *models.AccessInfo) {
+	trainerID, okT := bot.GetStateInt64(data, "trainer_id")
+	orgID, okID := bot.GetStateInt64(data, "org_id")
+	orgName, okName := bot.GetStateString(data, "org_name")
+	if !okT || !okID || !okName {
+		b.Router.Navigate(b, message, bot.StateRoot, nil, accessInfo)
+		return
+	}
+	handlers.ShowTrainerOrgMenu(b, message, trainerID, orgID, orgName)
+}
+
+// handleTrainerViewingClients обрабатывает выбор клиента из списка («N» или «удалить N»)
+func handleTrainerViewingClients(b *bot.Bot, message *tgbotapi.Message, _ *models.UserState, _ *models.AccessInfo) {
+	text := message.Text
+	if strings.HasPrefix(strings.ToLower(text), "удалить ") {
+		parts := strings.Fields(text)
+		if len(parts) >= 2 {
+			if idx, err := strconv.Atoi(parts[1]); err == nil {
+				handlers.HandleRemoveClientByIndex(b, message, idx)
+				return
+			}
+		}
+	}
+	if idx, err := strconv.Atoi(text); err == nil {
+		handlers.HandleSelectClient(b, message, idx)
+	} else {
+		b.SendMessage(message.Chat.ID, "⚠️ Введите номер клиента, «удалить [номер]» или «❌ Отмена»")
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fitness-bot/internal/bot"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// startUpdates запускает получение обновлений в режиме из UPDATE_MODE
+// (polling по умолчанию или webhook) и возвращает канал и функцию остановки
+func startUpdates(b *bot.Bot) (tgbotapi.UpdatesChannel, func(), error) {
+	mode := os.Getenv("UPDATE_MODE")
+	if mode == "" {
+		mode = bot.UpdateModePolling
+	}
+
+	switch mode {
+	case bot.UpdateModePolling:
+		// getUpdates не работает, пока зарегистрирован вебхук
+		if err := b.DeleteWebhook(); err != nil {
+			slog.Warn("Failed to delete webhook", "error", err)
+		}
+
+		u := tgbotapi.NewUpdate(0)
+		u.Timeout = 60
+
+		slog.Info("Receiving updates via long polling")
+		return b.API.GetUpdatesChan(u), b.API.StopReceivingUpdates, nil
+
+	case bot.UpdateModeWebhook:
+		config := bot.WebhookConfig{
+			URL:    os.Getenv("WEBHOOK_URL"),
+			Listen: os.Getenv("WEBHOOK_LISTEN"),
+			Secret: os.Getenv("WEBHOOK_SECRET"),
+		}
+		if config.Listen == "" {
+			config.Listen = ":8080"
+		}
+		if err := config.Validate(); err != nil {
+			return nil, nil, err
+		}
+
+		server := bot.NewWebhookServer(config)
+		if err := server.Start(); err != nil {
+			return nil, nil, err
+		}
+		if err := b.SetWebhook(config); err != nil {
+			server.Shutdown(context.Background())
+			return nil, nil, fmt.Errorf("setWebhook: %w", err)
+		}
+
+		stop := func() {
+			if err := b.DeleteWebhook(); err != nil {
+				slog.Error("Error deleting webhook", "error", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := server.Shutdown(ctx); err != nil {
+				slog.Error("Error stopping webhook server", "error", err)
+			}
+		}
+
+		slog.Info("Receiving updates via webhook", "url", config.URL)
+		return server.Updates(), stop, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unknown UPDATE_MODE %q (expected %q or %q)", mode, bot.UpdateModePolling, bot.UpdateModeWebhook)
+	}
+}
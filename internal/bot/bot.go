@@ -1,9 +1,10 @@
 package bot
 
 import (
+	"context"
 	"fitness-bot/internal/database"
 	"fitness-bot/internal/models"
-	"log"
+	"log/slog"
 	"strings"
 	"sync"
 
@@ -11,28 +12,41 @@ import (
 )
 
 type Bot struct {
-	API           *tgbotapi.BotAPI
-	DB            *database.DB
+	API           Messenger
+	DB            Storage
 	AdminUsername string
-	States        map[int64]*models.UserState
-	mu            sync.RWMutex
+	States        StateStore
+	Router        *Router
+	mu            sync.Mutex // сериализует чтение-изменение-запись состояния
+
+	menuMu sync.Mutex
+	menus  map[int64]string // последнее зарегистрированное меню команд по чатам
+
+	updateLogs updateLogs
+	rest       restTimers
 }
 
-func NewBot(token string, db *database.DB, adminUsername string) (*Bot, error) {
+// NewBot подключается к Telegram. ctx ограничивает ожидание в исходящей очереди (см. NewOutbox).
+func NewBot(ctx context.Context, token string, db *database.DB, adminUsername string) (*Bot, error) {
 	api, err := tgbotapi.NewBotAPI(token)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Printf("Authorized on account %s", api.Self.UserName)
-	log.Printf("Admin username: @%s", adminUsername)
+	slog.Info("Authorized on Telegram", "account", api.Self.UserName, "admin", adminUsername)
+
+	return New(NewOutbox(ctx, api), db, adminUsername, NewPostgresStateStore(db)), nil
+}
 
+// New собирает бота из готового транспорта, данных и хранилища состояний
+// (в тестах - RecordingMessenger, фейк Storage и MemoryStateStore)
+func New(api Messenger, db Storage, adminUsername string, states StateStore) *Bot {
 	return &Bot{
 		API:           api,
 		DB:            db,
 		AdminUsername: database.NormalizeUsername(adminUsername),
-		States:        make(map[int64]*models.UserState),
-	}, nil
+		States:        states,
+	}
 }
 
 func (b *Bot) IsAdmin(username string) bool {
@@ -40,44 +54,67 @@ func (b *Bot) IsAdmin(username string) bool {
 	return strings.EqualFold(normalized, b.AdminUsername)
 }
 
+// GetState возвращает текущее состояние диалога. Изменения в Data нужно
+// сохранять через SetState - сам объект не связан с хранилищем.
 func (b *Bot) GetState(telegramID int64) *models.UserState {
-	b.mu.RLock()
-	defer b.mu.RUnlock()
-	return b.States[telegramID]
+	state, err := b.States.Get(telegramID)
+	if err != nil {
+		b.Log(telegramID).Error("Error loading state", "error", err)
+		return nil
+	}
+	return state
 }
 
 func (b *Bot) SetState(telegramID int64, state string, data map[string]interface{}) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	b.States[telegramID] = &models.UserState{
+	if b.Router != nil {
+		from := ""
+		if current, err := b.States.Get(telegramID); err == nil && current != nil {
+			from = current.State
+		}
+		b.Router.checkTransition(telegramID, from, state)
+	}
+	err := b.States.Set(&models.UserState{
 		TelegramID: telegramID,
 		State:      state,
 		Data:       data,
+	})
+	if err != nil {
+		b.Log(telegramID).Error("Error saving state", "target_state", state, "error", err)
 	}
 }
 
 func (b *Bot) ClearState(telegramID int64) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	delete(b.States, telegramID)
+	if err := b.States.Delete(telegramID); err != nil {
+		b.Log(telegramID).Error("Error clearing state", "error", err)
+	}
+}
+
+// Send отправляет сообщение и логирует ошибку, если доставить его не удалось.
+// Сообщение с ошибкой в разметке переотправляется без неё.
+func (b *Bot) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	return b.send(c, "")
 }
 
 func (b *Bot) SendMessage(chatID int64, text string) {
 	msg := tgbotapi.NewMessage(chatID, text)
-	b.API.Send(msg)
+	b.Send(msg)
 }
 
 func (b *Bot) SendMessageWithKeyboard(chatID int64, text string, keyboard interface{}) {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ReplyMarkup = keyboard
 	msg.ParseMode = "Markdown"
-	b.API.Send(msg)
+	b.Send(msg)
 }
 
 func (b *Bot) SendMessageMarkdown(chatID int64, text string) {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ParseMode = "Markdown"
-	b.API.Send(msg)
+	b.Send(msg)
 }
 
 func (b *Bot) SendWithCancel(chatID int64, text string) {
@@ -97,7 +134,7 @@ func (b *Bot) SendInlineKeyboard(chatID int64, text string, keyboard tgbotapi.In
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ReplyMarkup = keyboard
 	msg.ParseMode = "Markdown"
-	sent, err := b.API.Send(msg)
+	sent, err := b.Send(msg)
 	if err != nil {
 		return 0
 	}
@@ -113,60 +150,74 @@ func (b *Bot) EditMessageText(chatID int64, messageID int, text string, keyboard
 	if keyboard != nil {
 		msg.ReplyMarkup = keyboard
 	}
-	b.API.Send(msg)
+	b.Send(msg)
+}
+
+// EditKeyboard заменяет inline-клавиатуру сообщения, не трогая текст
+func (b *Bot) EditKeyboard(chatID int64, messageID int, keyboard tgbotapi.InlineKeyboardMarkup) {
+	b.Send(tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, keyboard))
 }
 
 // DeleteMessage удаляет сообщение
 func (b *Bot) DeleteMessage(chatID int64, messageID int) {
 	del := tgbotapi.NewDeleteMessage(chatID, messageID)
-	b.API.Send(del)
+	if _, err := b.API.Request(del); err != nil {
+		// Сообщение могли уже удалить вручную - это не страшно
+		slog.Warn("Error deleting message", "chat_id", chatID, "message_id", messageID, "error", err)
+	}
 }
 
 // AnswerCallback отвечает на callback query
 func (b *Bot) AnswerCallback(callbackID string, text string) {
 	callback := tgbotapi.NewCallback(callbackID, text)
-	b.API.Send(callback)
+	if _, err := b.API.Request(callback); err != nil {
+		slog.Warn("Error answering callback", "callback_id", callbackID, "error", err)
+	}
 }
 
 // StoreMessageID сохраняет ID сообщения для последующего удаления
 func (b *Bot) StoreMessageID(telegramID int64, messageID int) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	if b.States[telegramID] != nil {
-		if b.States[telegramID].Data == nil {
-			b.States[telegramID].Data = make(map[string]interface{})
-		}
-		// Сохраняем список сообщений для удаления
-		var msgIDs []int
-		if existing, ok := b.States[telegramID].Data["_message_ids"].([]int); ok {
-			msgIDs = existing
-		}
-		msgIDs = append(msgIDs, messageID)
-		b.States[telegramID].Data["_message_ids"] = msgIDs
+
+	state, err := b.States.Get(telegramID)
+	if err != nil || state == nil {
+		return
+	}
+	if state.Data == nil {
+		state.Data = make(map[string]interface{})
+	}
+	// Сохраняем список сообщений для удаления
+	var msgIDs []int
+	if existing, ok := state.Data["_message_ids"].([]int); ok {
+		msgIDs = existing
+	}
+	state.Data["_message_ids"] = append(msgIDs, messageID)
+	if err := b.States.Set(state); err != nil {
+		b.Log(telegramID).Error("Error saving message id", "error", err)
 	}
 }
 
 // CleanupMessages удаляет все сохранённые сообщения
 func (b *Bot) CleanupMessages(chatID int64, telegramID int64) {
-	b.mu.RLock()
+	b.mu.Lock()
 	var msgIDs []int
-	if state := b.States[telegramID]; state != nil && state.Data != nil {
+	state, err := b.States.Get(telegramID)
+	if err == nil && state != nil && state.Data != nil {
 		if ids, ok := state.Data["_message_ids"].([]int); ok {
 			msgIDs = ids
+			// Очищаем список
+			delete(state.Data, "_message_ids")
+			if err := b.States.Set(state); err != nil {
+				b.Log(telegramID).Error("Error saving state", "error", err)
+			}
 		}
 	}
-	b.mu.RUnlock()
+	b.mu.Unlock()
 
 	for _, msgID := range msgIDs {
 		b.DeleteMessage(chatID, msgID)
 	}
-
-	// Очищаем список
-	b.mu.Lock()
-	if state := b.States[telegramID]; state != nil && state.Data != nil {
-		delete(state.Data, "_message_ids")
-	}
-	b.mu.Unlock()
 }
 
 // GetBreadcrumbs возвращает breadcrumbs навигации
@@ -0,0 +1,50 @@
+package bot
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// SetDefaultCommands регистрирует меню команд, которое видят все пользователи,
+// пока для их чата не задано своё
+func (b *Bot) SetDefaultCommands(commands []tgbotapi.BotCommand) error {
+	_, err := b.API.Request(tgbotapi.NewSetMyCommands(commands...))
+	return err
+}
+
+// SetChatCommands регистрирует меню команд для личного чата пользователя.
+// Запрос отправляется, только если набор команд изменился с прошлого раза.
+func (b *Bot) SetChatCommands(chatID int64, commands []tgbotapi.BotCommand) {
+	key := commandsKey(commands)
+
+	b.menuMu.Lock()
+	if b.menus == nil {
+		b.menus = make(map[int64]string)
+	}
+	if b.menus[chatID] == key {
+		b.menuMu.Unlock()
+		return
+	}
+	b.menus[chatID] = key
+	b.menuMu.Unlock()
+
+	scope := tgbotapi.NewBotCommandScopeChat(chatID)
+	if _, err := b.API.Request(tgbotapi.NewSetMyCommandsWithScope(scope, commands...)); err != nil {
+		// Повторим при следующем сообщении
+		b.menuMu.Lock()
+		delete(b.menus, chatID)
+		b.menuMu.Unlock()
+		slog.Warn("Error setting commands", "chat_id", chatID, "error", err)
+	}
+}
+
+func commandsKey(commands []tgbotapi.BotCommand) string {
+	var sb strings.Builder
+	for _, c := range commands {
+		fmt.Fprintf(&sb, "%s:%s;", c.Command, c.Description)
+	}
+	return sb.String()
+}
@@ -0,0 +1,115 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Dispatcher обрабатывает обновления по порядку внутри одного чата
+// и параллельно между разными чатами - не больше workers одновременно.
+// Если принято queueSize необработанных обновлений, Dispatch блокируется
+// (backpressure: перестаём забирать обновления у Telegram).
+type Dispatcher struct {
+	handle  func(tgbotapi.Update)
+	workers chan struct{} // семафор одновременно работающих обработчиков
+	pending chan struct{} // семафор принятых, но ещё не обработанных обновлений
+
+	mu     sync.Mutex
+	queues map[int64][]tgbotapi.Update // очереди активных чатов
+	wg     sync.WaitGroup              // горутины, разбирающие очереди чатов
+}
+
+func NewDispatcher(workers, queueSize int, handle func(tgbotapi.Update)) *Dispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < workers {
+		queueSize = workers
+	}
+	return &Dispatcher{
+		handle:  handle,
+		workers: make(chan struct{}, workers),
+		pending: make(chan struct{}, queueSize),
+		queues:  make(map[int64][]tgbotapi.Update),
+	}
+}
+
+// Dispatch ставит обновление в очередь его чата.
+// Блокируется, пока в общей очереди нет места, или до отмены ctx.
+func (d *Dispatcher) Dispatch(ctx context.Context, update tgbotapi.Update) error {
+	select {
+	case d.pending <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	key := UpdateChatID(update)
+
+	d.mu.Lock()
+	queue, active := d.queues[key]
+	d.queues[key] = append(queue, update)
+	// У активного чата уже есть горутина, которая разберёт его очередь
+	if !active {
+		d.wg.Add(1)
+		go d.drain(key)
+	}
+	d.mu.Unlock()
+	return nil
+}
+
+// Shutdown ждёт, пока будут обработаны все принятые обновления.
+// Новые обновления к этому моменту уже не должны поступать.
+// Возвращает ошибку ctx, если не дождались.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%d updates still in flight: %w", len(d.pending), ctx.Err())
+	}
+}
+
+// drain по одному обрабатывает обновления чата, пока его очередь не опустеет
+func (d *Dispatcher) drain(key int64) {
+	defer d.wg.Done()
+	d.workers <- struct{}{}
+	defer func() { <-d.workers }()
+
+	for {
+		d.mu.Lock()
+		queue := d.queues[key]
+		if len(queue) == 0 {
+			delete(d.queues, key)
+			d.mu.Unlock()
+			return
+		}
+		update := queue[0]
+		d.queues[key] = queue[1:]
+		d.mu.Unlock()
+
+		d.handle(update)
+		<-d.pending
+	}
+}
+
+// UpdateChatID возвращает чат, к которому относится обновление
+func UpdateChatID(update tgbotapi.Update) int64 {
+	switch {
+	case update.Message != nil && update.Message.Chat != nil:
+		return update.Message.Chat.ID
+	case update.CallbackQuery != nil && update.CallbackQuery.Message != nil && update.CallbackQuery.Message.Chat != nil:
+		return update.CallbackQuery.Message.Chat.ID
+	case update.CallbackQuery != nil && update.CallbackQuery.From != nil:
+		return update.CallbackQuery.From.ID
+	}
+	return 0
+}
@@ -0,0 +1,156 @@
+package bot
+
+import (
+	"encoding/json"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// RawRequest - вызов MakeRequest, записанный RecordingMessenger
+type RawRequest struct {
+	Endpoint string
+	Params   tgbotapi.Params
+}
+
+// RecordingMessenger - Messenger без сети для сценарных тестов:
+// запоминает всё, что бот отправил, и отдаёт обновления из канала Updates.
+type RecordingMessenger struct {
+	mu sync.Mutex
+
+	Messages        []tgbotapi.MessageConfig
+	Photos          []tgbotapi.PhotoConfig
+	Documents       []tgbotapi.DocumentConfig
+	Edits           []tgbotapi.EditMessageTextConfig
+	Deletes         []tgbotapi.DeleteMessageConfig
+	CallbackAnswers []tgbotapi.CallbackConfig
+	Other           []tgbotapi.Chattable
+	RawRequests     []RawRequest
+
+	// Fail, если задана, возвращает ошибку для отправки (например, 429 от Telegram)
+	Fail func(c tgbotapi.Chattable) error
+
+	Updates chan tgbotapi.Update
+
+	nextMessageID int
+	stopOnce      sync.Once
+}
+
+func NewRecordingMessenger() *RecordingMessenger {
+	return &RecordingMessenger{
+		Updates:       make(chan tgbotapi.Update, 100),
+		nextMessageID: 1,
+	}
+}
+
+func (m *RecordingMessenger) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	if err := m.record(c); err != nil {
+		return tgbotapi.Message{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	msg := tgbotapi.Message{MessageID: m.nextMessageID}
+	m.nextMessageID++
+	switch cfg := c.(type) {
+	case tgbotapi.MessageConfig:
+		msg.Chat = &tgbotapi.Chat{ID: cfg.ChatID}
+		msg.Text = cfg.Text
+	case tgbotapi.EditMessageTextConfig:
+		msg.MessageID = cfg.MessageID
+		msg.Chat = &tgbotapi.Chat{ID: cfg.ChatID}
+		msg.Text = cfg.Text
+	case tgbotapi.PhotoConfig:
+		msg.Chat = &tgbotapi.Chat{ID: cfg.ChatID}
+		msg.Caption = cfg.Caption
+	case tgbotapi.DocumentConfig:
+		msg.Chat = &tgbotapi.Chat{ID: cfg.ChatID}
+		msg.Caption = cfg.Caption
+	}
+	return msg, nil
+}
+
+func (m *RecordingMessenger) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	if err := m.record(c); err != nil {
+		return nil, err
+	}
+	return &tgbotapi.APIResponse{Ok: true, Result: json.RawMessage("true")}, nil
+}
+
+func (m *RecordingMessenger) MakeRequest(endpoint string, params tgbotapi.Params) (*tgbotapi.APIResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.RawRequests = append(m.RawRequests, RawRequest{Endpoint: endpoint, Params: params})
+	return &tgbotapi.APIResponse{Ok: true, Result: json.RawMessage("true")}, nil
+}
+
+func (m *RecordingMessenger) GetUpdatesChan(tgbotapi.UpdateConfig) tgbotapi.UpdatesChannel {
+	return m.Updates
+}
+
+func (m *RecordingMessenger) StopReceivingUpdates() {
+	m.stopOnce.Do(func() { close(m.Updates) })
+}
+
+// Texts возвращает тексты всех отправленных сообщений по порядку
+func (m *RecordingMessenger) Texts() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	texts := make([]string, 0, len(m.Messages))
+	for _, msg := range m.Messages {
+		texts = append(texts, msg.Text)
+	}
+	return texts
+}
+
+// LastMessage возвращает последнее отправленное сообщение
+func (m *RecordingMessenger) LastMessage() (tgbotapi.MessageConfig, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.Messages) == 0 {
+		return tgbotapi.MessageConfig{}, false
+	}
+	return m.Messages[len(m.Messages)-1], true
+}
+
+// Reset очищает записанную историю
+func (m *RecordingMessenger) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Messages = nil
+	m.Photos = nil
+	m.Documents = nil
+	m.Edits = nil
+	m.Deletes = nil
+	m.CallbackAnswers = nil
+	m.Other = nil
+	m.RawRequests = nil
+}
+
+func (m *RecordingMessenger) record(c tgbotapi.Chattable) error {
+	if m.Fail != nil {
+		if err := m.Fail(c); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch cfg := c.(type) {
+	case tgbotapi.MessageConfig:
+		m.Messages = append(m.Messages, cfg)
+	case tgbotapi.PhotoConfig:
+		m.Photos = append(m.Photos, cfg)
+	case tgbotapi.DocumentConfig:
+		m.Documents = append(m.Documents, cfg)
+	case tgbotapi.EditMessageTextConfig:
+		m.Edits = append(m.Edits, cfg)
+	case tgbotapi.DeleteMessageConfig:
+		m.Deletes = append(m.Deletes, cfg)
+	case tgbotapi.CallbackConfig:
+		m.CallbackAnswers = append(m.CallbackAnswers, cfg)
+	default:
+		m.Other = append(m.Other, c)
+	}
+	return nil
+}
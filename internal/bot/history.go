@@ -0,0 +1,259 @@
+package bot
+
+import (
+	"fitness-bot/internal/models"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Виды меню фильтров истории тренировок
+const (
+	HistoryGroupsView  = "groups"
+	HistoryPeriodView  = "period"
+	HistoryTrainerView = "trainer"
+	HistoryRangeView   = "range"  // ввод своего периода текстом
+	HistoryExportView  = "export" // выгрузка тренировок по фильтру в CSV
+)
+
+// HistoryPosition - страница истории тренировок и фильтр. Кодируется в callback data,
+// чтобы кнопки работали без состояния диалога: «hist:2:g5.f20240301.t20240331.c7»,
+// история клиента у тренера - «hist:0:k12».
+type HistoryPosition struct {
+	Page   int
+	Filter models.WorkoutFilter
+	Client int64 // связь тренер-клиент, историю которой смотрит тренер; 0 - своя история
+}
+
+// historyDateFormat - формат дат фильтра в callback data
+const historyDateFormat = "20060102"
+
+// String кодирует позицию как «страница:фильтр»
+func (p HistoryPosition) String() string {
+	var parts []string
+	f := p.Filter
+	if f.MuscleGroupID != 0 {
+		parts = append(parts, "g"+strconv.FormatInt(f.MuscleGroupID, 10))
+	}
+	if !f.From.IsZero() {
+		parts = append(parts, "f"+f.From.Format(historyDateFormat))
+	}
+	if !f.To.IsZero() {
+		parts = append(parts, "t"+f.To.Format(historyDateFormat))
+	}
+	if f.TrainerClientID != 0 {
+		parts = append(parts, "c"+strconv.FormatInt(f.TrainerClientID, 10))
+	}
+	if p.Client != 0 {
+		parts = append(parts, "k"+strconv.FormatInt(p.Client, 10))
+	}
+	return strconv.Itoa(p.Page) + ":" + strings.Join(parts, ".")
+}
+
+// ParseHistoryPosition разбирает «страница:фильтр[:остаток]» и возвращает позицию и остаток.
+// Непонятные части пропускаются.
+func ParseHistoryPosition(s string) (HistoryPosition, string) {
+	parts := strings.SplitN(s, ":", 3)
+	var pos HistoryPosition
+	if page, err := strconv.Atoi(parts[0]); err == nil && page > 0 {
+		pos.Page = page
+	}
+	if len(parts) > 1 {
+		for _, item := range strings.Split(parts[1], ".") {
+			if len(item) < 2 {
+				continue
+			}
+			value := item[1:]
+			switch item[0] {
+			case 'g':
+				pos.Filter.MuscleGroupID, _ = strconv.ParseInt(value, 10, 64)
+			case 'f':
+				pos.Filter.From, _ = time.ParseInLocation(historyDateFormat, value, time.Local)
+			case 't':
+				pos.Filter.To, _ = time.ParseInLocation(historyDateFormat, value, time.Local)
+			case 'c':
+				pos.Filter.TrainerClientID, _ = strconv.ParseInt(value, 10, 64)
+			case 'k':
+				pos.Client, _ = strconv.ParseInt(value, 10, 64)
+			}
+		}
+	}
+	rest := ""
+	if len(parts) > 2 {
+		rest = parts[2]
+	}
+	return pos, rest
+}
+
+// historyData - callback data страницы истории (view - меню фильтра, "" - сама страница)
+func historyData(pos HistoryPosition, view string) string {
+	data := "hist:" + pos.String()
+	if view != "" {
+		data += ":" + view
+	}
+	return data
+}
+
+// withFilter - первая страница той же истории с другим фильтром
+func (p HistoryPosition) withFilter(f models.WorkoutFilter) HistoryPosition {
+	return HistoryPosition{Filter: f, Client: p.Client}
+}
+
+// GetInlineHistoryKeyboard создаёт inline-клавиатуру страницы истории: тренировки, листание и фильтры.
+// Фильтр по тренеру показывается, только если тренеров больше одного.
+// У истории связи тренер-клиент есть графики и выгрузка.
+func GetInlineHistoryKeyboard(workouts []*models.Workout, pos HistoryPosition, hasNext, trainerFilter bool) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, w := range workouts {
+		btn := tgbotapi.NewInlineKeyboardButtonData(
+			"🔍 "+w.Date.Format("02.01")+" - "+w.MuscleGroupNames(),
+			formatCallbackData("hview", w.ID)+":"+pos.String(),
+		)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(btn))
+	}
+
+	var nav []tgbotapi.InlineKeyboardButton
+	if pos.Page > 0 {
+		prev := pos
+		prev.Page--
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("◀️ Новее", historyData(prev, "")))
+	}
+	if hasNext {
+		next := pos
+		next.Page++
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("Старше ▶️", historyData(next, "")))
+	}
+	if len(nav) > 0 {
+		rows = append(rows, nav)
+	}
+
+	filters := []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("💪 Группа", historyData(pos, HistoryGroupsView)),
+		tgbotapi.NewInlineKeyboardButtonData("📅 Период", historyData(pos, HistoryPeriodView)),
+	}
+	if trainerFilter {
+		filters = append(filters, tgbotapi.NewInlineKeyboardButtonData("👤 Тренер", historyData(pos, HistoryTrainerView)))
+	}
+	rows = append(rows, filters)
+	if pos.Client != 0 {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📊 Графики", formatCallbackData("cstat", pos.Client)),
+			tgbotapi.NewInlineKeyboardButtonData("📤 Выгрузить CSV", historyData(pos, HistoryExportView)),
+		))
+	}
+	if !pos.Filter.IsEmpty() {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✖️ Сбросить фильтры", historyData(pos.withFilter(models.WorkoutFilter{}), "")),
+		))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// GetInlineHistoryGroupsKeyboard создаёт inline-клавиатуру выбора группы мышц для фильтра истории
+func GetInlineHistoryGroupsKeyboard(groups []*models.MuscleGroupRecord, pos HistoryPosition) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	all := pos.Filter
+	all.MuscleGroupID = 0
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(checkmark(pos.Filter.MuscleGroupID == 0)+"Все группы", historyData(pos.withFilter(all), "")),
+	))
+	for i := 0; i < len(groups); i += 2 {
+		var row []tgbotapi.InlineKeyboardButton
+		for _, g := range groups[i:min(i+2, len(groups))] {
+			f := pos.Filter
+			f.MuscleGroupID = g.ID
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(
+				checkmark(pos.Filter.MuscleGroupID == g.ID)+g.Label(), historyData(pos.withFilter(f), "")))
+		}
+		rows = append(rows, row)
+	}
+	rows = append(rows, historyBackRow(pos))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// historyPeriods - готовые периоды фильтра истории: подпись и число дней, включая сегодня
+var historyPeriods = []struct {
+	label string
+	days  int
+}{
+	{"7 дней", 7},
+	{"30 дней", 30},
+	{"3 месяца", 91},
+	{"Год", 365},
+}
+
+// GetInlineHistoryPeriodKeyboard создаёт inline-клавиатуру выбора периода для фильтра истории
+func GetInlineHistoryPeriodKeyboard(pos HistoryPosition, now time.Time) tgbotapi.InlineKeyboardMarkup {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, p := range historyPeriods {
+		f := pos.Filter
+		f.From = today.AddDate(0, 0, 1-p.days)
+		f.To = time.Time{}
+		selected := pos.Filter.To.IsZero() && pos.Filter.From.Equal(f.From)
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(checkmark(selected)+p.label, historyData(pos.withFilter(f), "")))
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	all := pos.Filter
+	all.From, all.To = time.Time{}, time.Time{}
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(checkmark(all == pos.Filter)+"Всё время", historyData(pos.withFilter(all), "")),
+			tgbotapi.NewInlineKeyboardButtonData("📆 Свои даты", historyData(pos, HistoryRangeView)),
+		),
+		historyBackRow(pos),
+	)
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// GetInlineHistoryTrainersKeyboard создаёт inline-клавиатуру выбора тренера для фильтра истории
+func GetInlineHistoryTrainersKeyboard(trainers []*models.ClientAccessInfo, pos HistoryPosition) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	all := pos.Filter
+	all.TrainerClientID = 0
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(checkmark(pos.Filter.TrainerClientID == 0)+"Все тренеры", historyData(pos.withFilter(all), "")),
+	))
+	for _, t := range trainers {
+		f := pos.Filter
+		f.TrainerClientID = t.TrainerClientID
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(
+			checkmark(pos.Filter.TrainerClientID == t.TrainerClientID)+"@"+t.TrainerUsername+" ("+t.OrganizationName+")",
+			historyData(pos.withFilter(f), ""),
+		)))
+	}
+	rows = append(rows, historyBackRow(pos))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// GetInlineHistoryWorkoutKeyboard создаёт inline-клавиатуру тренировки, открытой из истории.
+// Кнопка «Изменить» - только если тренировку можно править.
+func GetInlineHistoryWorkoutKeyboard(workoutID int64, pos HistoryPosition, editable bool) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if editable {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✏️ Изменить", formatCallbackData("workout", workoutID)),
+		))
+	}
+	rows = append(rows, historyBackRow(pos))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// historyBackRow - кнопка возврата к странице истории
+func historyBackRow(pos HistoryPosition) []tgbotapi.InlineKeyboardButton {
+	return tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("🔙 К истории", historyData(pos, "")))
+}
+
+// checkmark отмечает выбранный вариант
+func checkmark(selected bool) string {
+	if selected {
+		return "✅ "
+	}
+	return ""
+}
@@ -2,12 +2,16 @@ package bot
 
 import (
 	"fitness-bot/internal/models"
+	"fmt"
 	"strconv"
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
+// SettingsText - кнопка настроек в главном меню
+const SettingsText = "⚙️ Настройки"
+
 // GetStartMenuKeyboard возвращает начальное меню на основе доступов пользователя
 func GetStartMenuKeyboard(accessInfo *models.AccessInfo) tgbotapi.ReplyKeyboardMarkup {
 	var rows [][]tgbotapi.KeyboardButton
@@ -54,6 +58,13 @@ func GetStartMenuKeyboard(accessInfo *models.AccessInfo) tgbotapi.ReplyKeyboardM
 		))
 	}
 
+	// Настройки веса - тем, кто записывает или смотрит тренировки
+	if hasActiveTrainer || len(accessInfo.ClientAccess) > 0 {
+		rows = append(rows, tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton(SettingsText),
+		))
+	}
+
 	// Архивные доступы (только просмотр)
 	if len(accessInfo.ArchivedAccess) > 0 {
 		rows = append(rows, tgbotapi.NewKeyboardButtonRow(
@@ -104,6 +115,10 @@ func GetManagerMenuKeyboard() tgbotapi.ReplyKeyboardMarkup {
 			tgbotapi.NewKeyboardButton("➕ Добавить тренера"),
 			tgbotapi.NewKeyboardButton("📋 Список тренеров"),
 		),
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton("💪 Группы мышц"),
+			tgbotapi.NewKeyboardButton("⏱ Отдых по умолчанию"),
+		),
 		tgbotapi.NewKeyboardButtonRow(
 			tgbotapi.NewKeyboardButton("🔙 Главное меню"),
 		),
@@ -121,6 +136,9 @@ func GetTrainerMenuKeyboard() tgbotapi.ReplyKeyboardMarkup {
 			tgbotapi.NewKeyboardButton("📅 Групповые тренировки"),
 			tgbotapi.NewKeyboardButton("📊 Статистика"),
 		),
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton("📋 Шаблоны тренировок"),
+		),
 		tgbotapi.NewKeyboardButtonRow(
 			tgbotapi.NewKeyboardButton("🔙 Главное меню"),
 		),
@@ -138,6 +156,9 @@ func GetClientMenuKeyboard() tgbotapi.ReplyKeyboardMarkup {
 			tgbotapi.NewKeyboardButton("📊 Моя статистика"),
 			tgbotapi.NewKeyboardButton("📅 Групповые тренировки"),
 		),
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton("📋 Программы тренера"),
+		),
 		tgbotapi.NewKeyboardButtonRow(
 			tgbotapi.NewKeyboardButton("🔙 Главное меню"),
 		),
@@ -152,30 +173,80 @@ func GetMainMenuKeyboard(isTrainer bool) tgbotapi.ReplyKeyboardMarkup {
 	return GetClientMenuKeyboard()
 }
 
-func GetMuscleGroupKeyboard() tgbotapi.ReplyKeyboardMarkup {
+// GetMuscleGroupKeyboard возвращает клавиатуру выбора нескольких групп мышц.
+// Выбранные группы отмечены ✅, повторное нажатие снимает выбор.
+func GetMuscleGroupKeyboard(groups []*models.MuscleGroupRecord, selected []int64) tgbotapi.ReplyKeyboardMarkup {
+	var rows [][]tgbotapi.KeyboardButton
+	var row []tgbotapi.KeyboardButton
+	for _, g := range groups {
+		row = append(row, tgbotapi.NewKeyboardButton(MuscleGroupButton(g, containsID(selected, g.ID))))
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	rows = append(rows, tgbotapi.NewKeyboardButtonRow(
+		tgbotapi.NewKeyboardButton(MuscleGroupsDoneText),
+		tgbotapi.NewKeyboardButton("❌ Отмена"),
+	))
+	return tgbotapi.NewReplyKeyboard(rows...)
+}
+
+// Кнопки выбора даты новой тренировки
+const (
+	WorkoutNowText       = "▶️ Начать сейчас"
+	WorkoutYesterdayText = "📅 Вчера"
+)
+
+// GetWorkoutDateKeyboard возвращает клавиатуру выбора даты тренировки
+func GetWorkoutDateKeyboard() tgbotapi.ReplyKeyboardMarkup {
 	return tgbotapi.NewReplyKeyboard(
 		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("💪 Грудь"),
-			tgbotapi.NewKeyboardButton("🦾 Спина"),
-		),
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("🦵 Ноги"),
-			tgbotapi.NewKeyboardButton("🏋️ Плечи"),
+			tgbotapi.NewKeyboardButton(WorkoutNowText),
 		),
 		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("💪 Бицепс"),
-			tgbotapi.NewKeyboardButton("💪 Трицепс"),
+			tgbotapi.NewKeyboardButton(WorkoutYesterdayText),
+			tgbotapi.NewKeyboardButton("❌ Отмена"),
 		),
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("🎯 Пресс"),
-			tgbotapi.NewKeyboardButton("🏃 Кардио"),
+	)
+}
+
+// GetInlineWorkoutDateKeyboard создаёт inline-клавиатуру выбора даты тренировки
+func GetInlineWorkoutDateKeyboard() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(WorkoutNowText, "wdate:now"),
 		),
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("❌ Отмена"),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(WorkoutYesterdayText, "wdate:yesterday"),
+			tgbotapi.NewInlineKeyboardButtonData("❌ Отмена", "wdate:cancel"),
 		),
 	)
 }
 
+// MuscleGroupsDoneText - кнопка завершения выбора групп мышц
+const MuscleGroupsDoneText = "✅ Готово"
+
+// MuscleGroupButton возвращает текст кнопки группы мышц
+func MuscleGroupButton(group *models.MuscleGroupRecord, selected bool) string {
+	if selected {
+		return "✅ " + string(group.Name)
+	}
+	return group.Label()
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
 func GetRoleKeyboard() tgbotapi.ReplyKeyboardMarkup {
 	return tgbotapi.NewReplyKeyboard(
 		tgbotapi.NewKeyboardButtonRow(
@@ -193,6 +264,46 @@ func GetCancelKeyboard() tgbotapi.ReplyKeyboardMarkup {
 	)
 }
 
+// FinishWorkoutText - кнопка завершения тренировки
+const FinishWorkoutText = "✅ Завершить"
+
+// GetWorkoutKeyboard возвращает клавиатуру добавления упражнений в тренировку
+func GetWorkoutKeyboard() tgbotapi.ReplyKeyboardMarkup {
+	return tgbotapi.NewReplyKeyboard(
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton(FinishWorkoutText),
+		),
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton(CancelText),
+		),
+	)
+}
+
+// GetTemplateEditKeyboard возвращает клавиатуру добавления упражнений в шаблон
+func GetTemplateEditKeyboard() tgbotapi.ReplyKeyboardMarkup {
+	return tgbotapi.NewReplyKeyboard(
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton("✅ Сохранить шаблон"),
+		),
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton("❌ Отмена"),
+		),
+	)
+}
+
+// GetTemplateWorkoutKeyboard возвращает клавиатуру тренировки по шаблону
+func GetTemplateWorkoutKeyboard() tgbotapi.ReplyKeyboardMarkup {
+	return tgbotapi.NewReplyKeyboard(
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton("✅ По плану"),
+			tgbotapi.NewKeyboardButton("⏭ Пропустить"),
+		),
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton("🏁 Завершить тренировку"),
+		),
+	)
+}
+
 // ====== INLINE KEYBOARDS ======
 
 // GetInlineOrganizationsKeyboard создаёт inline-клавиатуру для выбора организации
@@ -233,29 +344,46 @@ func GetInlineListKeyboard(items []string, ids []int64, prefix string) tgbotapi.
 	return tgbotapi.NewInlineKeyboardMarkup(rows...)
 }
 
-// GetInlineMuscleGroupKeyboard создаёт inline-клавиатуру для выбора группы мышц
-func GetInlineMuscleGroupKeyboard() tgbotapi.InlineKeyboardMarkup {
-	return tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("💪 Грудь", "muscle:chest"),
-			tgbotapi.NewInlineKeyboardButtonData("🦾 Спина", "muscle:back"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("🦵 Ноги", "muscle:legs"),
-			tgbotapi.NewInlineKeyboardButtonData("🏋️ Плечи", "muscle:shoulders"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("💪 Бицепс", "muscle:biceps"),
-			tgbotapi.NewInlineKeyboardButtonData("💪 Трицепс", "muscle:triceps"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("🎯 Пресс", "muscle:abs"),
-			tgbotapi.NewInlineKeyboardButtonData("🏃 Кардио", "muscle:cardio"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("❌ Отмена", "muscle:cancel"),
-		),
-	)
+// GetInlineMuscleGroupKeyboard создаёт inline-клавиатуру выбора нескольких групп мышц
+func GetInlineMuscleGroupKeyboard(groups []*models.MuscleGroupRecord, selected []int64) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, g := range groups {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(
+			MuscleGroupButton(g, containsID(selected, g.ID)),
+			formatCallbackData("muscle", g.ID),
+		))
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(MuscleGroupsDoneText, "muscle:done"),
+		tgbotapi.NewInlineKeyboardButtonData("❌ Отмена", "muscle:cancel"),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// GetInlineOrgMuscleGroupsKeyboard создаёт inline-клавиатуру групп мышц организации:
+// удаление своих групп и добавление новой
+func GetInlineOrgMuscleGroupsKeyboard(groups []*models.MuscleGroupRecord) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, g := range groups {
+		if g.OrganizationID == nil {
+			continue
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🗑 "+g.Label(), formatCallbackData("mgroup", g.ID)+":delete"),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("➕ Добавить группу", "mgroup:new"),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
 }
 
 // GetInlineClientActionsKeyboard создаёт inline-клавиатуру для действий с клиентом
@@ -282,6 +410,33 @@ func GetInlineClientActionsKeyboard(clientID int64, isActive bool) tgbotapi.Inli
 	return tgbotapi.NewInlineKeyboardMarkup(rows...)
 }
 
+// GetInlineClientStatsKeyboard создаёт inline-клавиатуру статистики связи тренер-клиент: упражнения для графиков
+// (cstat:<trainer_client_id>:<catalog_id>) и переход к истории
+func GetInlineClientStatsKeyboard(trainerClientID int64, exercises []*models.CatalogExercise) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i := 0; i < len(exercises); i += 2 {
+		var row []tgbotapi.InlineKeyboardButton
+		for _, e := range exercises[i:min(i+2, len(exercises))] {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(
+				"📈 "+e.Name,
+				formatCallbackData("cstat", trainerClientID)+":"+strconv.FormatInt(e.ID, 10),
+			))
+		}
+		rows = append(rows, row)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("📋 История тренировок", historyData(HistoryPosition{Client: trainerClientID}, "")),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// GetInlineClientExerciseStatsKeyboard создаёт inline-клавиатуру возврата к статистике связи тренер-клиент
+func GetInlineClientExerciseStatsKeyboard(trainerClientID int64) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🔙 К статистике", formatCallbackData("cstat", trainerClientID)),
+	))
+}
+
 // GetInlineConfirmKeyboard создаёт inline-клавиатуру для подтверждения
 func GetInlineConfirmKeyboard(prefix string) tgbotapi.InlineKeyboardMarkup {
 	return tgbotapi.NewInlineKeyboardMarkup(
@@ -304,6 +459,163 @@ func GetInlineFinishKeyboard() tgbotapi.InlineKeyboardMarkup {
 	)
 }
 
+// GetInlineRestKeyboard создаёт inline-клавиатуру таймера отдыха
+func GetInlineRestKeyboard() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⏹ Стоп", "rest:stop"),
+		),
+	)
+}
+
+// GetInlineSettingsKeyboard создаёт inline-клавиатуру настроек: единицы веса и свой вес
+func GetInlineSettingsKeyboard(unit models.WeightUnit, shareWorkouts bool) tgbotapi.InlineKeyboardMarkup {
+	kg, lb := "Килограммы", "Фунты (lb)"
+	if unit == models.UnitLb {
+		lb = "✅ " + lb
+	} else {
+		kg = "✅ " + kg
+	}
+	share := "👁 Показывать тренерам тренировки без них"
+	if shareWorkouts {
+		share = "🙈 Скрыть от тренеров тренировки без них"
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(kg, "settings:kg"),
+			tgbotapi.NewInlineKeyboardButtonData(lb, "settings:lb"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🧍 Указать свой вес", "settings:bodyweight"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(share, "settings:share"),
+		),
+	)
+}
+
+// GetInlineWorkoutEditKeyboard создаёт inline-клавиатуру редактирования тренировки
+func GetInlineWorkoutEditKeyboard(workoutID int64, exercises []*models.Exercise) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, ex := range exercises {
+		btn := tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf("✏️ %d. %s", i+1, ex.Name),
+			formatCallbackData("exedit", ex.ID),
+		)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(btn))
+	}
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🗑 Удалить тренировку", formatCallbackData("workout", workoutID)+":delete"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔙 К списку", formatCallbackData("workout", workoutID)+":back"),
+		),
+	)
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// GetInlineExerciseEditKeyboard создаёт inline-клавиатуру редактирования упражнения
+func GetInlineExerciseEditKeyboard(exercise *models.Exercise) tgbotapi.InlineKeyboardMarkup {
+	data := formatCallbackData("exedit", exercise.ID)
+	values := tgbotapi.NewInlineKeyboardButtonData("🏋️ Подходы (вес и повторения)", data+":sets")
+	if exercise.Cardio != nil {
+		values = tgbotapi.NewInlineKeyboardButtonData("🏃 Дистанция, время, пульс", data+":cardio")
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📝 Название", data+":name"),
+			tgbotapi.NewInlineKeyboardButtonData("🔢 Порядок", data+":order"),
+		),
+		tgbotapi.NewInlineKeyboardRow(values),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🗑 Удалить упражнение", data+":delete"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔙 К тренировке", formatCallbackData("workout", exercise.WorkoutID)),
+		),
+	)
+}
+
+// GetInlineDeleteConfirmKeyboard создаёт inline-клавиатуру подтверждения удаления.
+// «Нет» возвращает к карточке объекта prefix:id
+func GetInlineDeleteConfirmKeyboard(prefix string, id int64) tgbotapi.InlineKeyboardMarkup {
+	data := formatCallbackData(prefix, id)
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Да, удалить", data+":delete_confirm"),
+			tgbotapi.NewInlineKeyboardButtonData("❌ Нет", data),
+		),
+	)
+}
+
+// GetInlineTemplateListKeyboard создаёт inline-клавиатуру шаблонов организации
+func GetInlineTemplateListKeyboard(templates []*models.WorkoutTemplate) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, t := range templates {
+		btn := tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf("📋 %s (%d упр.)", t.Name, len(t.Exercises)),
+			formatCallbackData("template", t.ID),
+		)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(btn))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("➕ Новый шаблон", "template:new"),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// GetInlineTemplateKeyboard создаёт inline-клавиатуру карточки шаблона
+func GetInlineTemplateKeyboard(templateID int64) tgbotapi.InlineKeyboardMarkup {
+	data := formatCallbackData("template", templateID)
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("👤 Назначить клиентам", data+":assign"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🗑 Удалить шаблон", data+":delete"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔙 К списку", data+":back"),
+		),
+	)
+}
+
+// GetInlineTemplateAssignKeyboard создаёт inline-клавиатуру назначения шаблона клиентам.
+// Нажатие на клиента назначает шаблон или снимает назначение.
+func GetInlineTemplateAssignKeyboard(templateID int64, clients []*models.ClientWithInfo, assigned map[int64]bool) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, c := range clients {
+		name := c.Client.Username
+		if c.FullName != "" {
+			name = c.FullName
+		}
+		mark := "▫️ "
+		if assigned[c.Client.ID] {
+			mark = "✅ "
+		}
+		btn := tgbotapi.NewInlineKeyboardButtonData(
+			mark+name,
+			formatCallbackData("tassign", templateID)+":"+strconv.FormatInt(c.Client.ID, 10),
+		)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(btn))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🔙 К шаблону", formatCallbackData("template", templateID)),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// GetInlineAssignedTemplatesKeyboard создаёт inline-клавиатуру запуска тренировки по шаблону
+func GetInlineAssignedTemplatesKeyboard(templates []*models.WorkoutTemplate) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, t := range templates {
+		btn := tgbotapi.NewInlineKeyboardButtonData("▶️ "+t.Name, formatCallbackData("tstart", t.ID))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(btn))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
 // formatCallbackData форматирует callback data с ID
 func formatCallbackData(prefix string, id int64) string {
 	return prefix + ":" + strconv.FormatInt(id, 10)
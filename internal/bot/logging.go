@@ -0,0 +1,102 @@
+package bot
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// NewLogger создаёт логгер приложения.
+// format - "json" или "text" (по умолчанию), level - debug/info/warn/error.
+func NewLogger(w io.Writer, format, level string) *slog.Logger {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		lvl = slog.LevelInfo
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	if strings.EqualFold(format, "json") {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// UpdateLog - логгер одного входящего обновления.
+// Помнит, логировались ли ошибки, чтобы в конце записать исход обработки.
+type UpdateLog struct {
+	*slog.Logger
+	failed *atomic.Bool
+}
+
+// NewUpdateLog оборачивает логгер с атрибутами обновления
+func NewUpdateLog(logger *slog.Logger) *UpdateLog {
+	failed := &atomic.Bool{}
+	return &UpdateLog{
+		Logger: slog.New(&outcomeHandler{Handler: logger.Handler(), failed: failed}),
+		failed: failed,
+	}
+}
+
+// Failed сообщает, была ли во время обработки залогирована ошибка
+func (l *UpdateLog) Failed() bool {
+	return l.failed.Load()
+}
+
+// outcomeHandler отмечает записи уровня Error
+type outcomeHandler struct {
+	slog.Handler
+	failed *atomic.Bool
+}
+
+func (h *outcomeHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelError {
+		h.failed.Store(true)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *outcomeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &outcomeHandler{Handler: h.Handler.WithAttrs(attrs), failed: h.failed}
+}
+
+func (h *outcomeHandler) WithGroup(name string) slog.Handler {
+	return &outcomeHandler{Handler: h.Handler.WithGroup(name), failed: h.failed}
+}
+
+// updateLogs - логгеры обновлений, которые сейчас обрабатываются, по пользователям.
+// Обновления одного чата обрабатываются по очереди, поэтому на пользователя он один.
+type updateLogs struct {
+	mu   sync.Mutex
+	logs map[int64]*UpdateLog
+}
+
+// BindLog делает log логгером пользователя до вызова возвращённой функции
+func (b *Bot) BindLog(telegramID int64, log *UpdateLog) (unbind func()) {
+	b.updateLogs.mu.Lock()
+	defer b.updateLogs.mu.Unlock()
+	if b.updateLogs.logs == nil {
+		b.updateLogs.logs = make(map[int64]*UpdateLog)
+	}
+	b.updateLogs.logs[telegramID] = log
+
+	return func() {
+		b.updateLogs.mu.Lock()
+		defer b.updateLogs.mu.Unlock()
+		if b.updateLogs.logs[telegramID] == log {
+			delete(b.updateLogs.logs, telegramID)
+		}
+	}
+}
+
+// Log возвращает логгер текущего обновления пользователя
+// (с update_id, user_id, state и т.п.) или общий, если обновления нет
+func (b *Bot) Log(telegramID int64) *slog.Logger {
+	b.updateLogs.mu.Lock()
+	defer b.updateLogs.mu.Unlock()
+	if log, ok := b.updateLogs.logs[telegramID]; ok {
+		return log.Logger
+	}
+	return slog.Default().With("user_id", telegramID)
+}
@@ -0,0 +1,21 @@
+package bot
+
+import (
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Messenger - транспорт до Telegram Bot API.
+// *tgbotapi.BotAPI удовлетворяет интерфейсу, в тестах используется RecordingMessenger.
+type Messenger interface {
+	// Send отправляет сообщение/правку и возвращает отправленное сообщение
+	Send(c tgbotapi.Chattable) (tgbotapi.Message, error)
+	// Request выполняет метод API, результат которого не является сообщением
+	// (удаление, ответ на callback, setMyCommands и т.п.)
+	Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error)
+	// MakeRequest вызывает метод API напрямую - для параметров, которых нет в конфигах библиотеки
+	MakeRequest(endpoint string, params tgbotapi.Params) (*tgbotapi.APIResponse, error)
+	GetUpdatesChan(config tgbotapi.UpdateConfig) tgbotapi.UpdatesChannel
+	StopReceivingUpdates()
+}
+
+var _ Messenger = (*tgbotapi.BotAPI)(nil)
@@ -0,0 +1,345 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Лимиты Telegram на исходящие сообщения.
+// Короткие всплески разрешены: ответ на команду часто состоит из 2-3 сообщений.
+const (
+	globalSendInterval  = time.Second / 30 // не больше 30 сообщений в секунду всего
+	privateSendInterval = time.Second      // не больше 1 сообщения в секунду в личный чат
+	groupSendInterval   = time.Minute / 20 // не больше 20 сообщений в минуту в группу
+
+	globalSendBurst = 30
+	chatSendBurst   = 3
+)
+
+// Параметры повторов
+const (
+	maxSendAttempts = 5
+	baseRetryDelay  = 500 * time.Millisecond
+	// Дольше запрос в очереди не живёт: обработчик не должен ждать отправку
+	// дольше этого, и всё успевает уйти (или отмениться) за SHUTDOWN_TIMEOUT
+	maxSendWait = 10 * time.Second
+)
+
+// errSendTimeout - запрос не успел уйти за maxSendWait
+var errSendTimeout = errors.New("send timeout exceeded")
+
+// limiter выдаёт моменты отправки в среднем раз в interval,
+// допуская всплеск до burst отправок подряд.
+// Вызывающие встают в очередь по порядку вызова reserve.
+type limiter struct {
+	interval time.Duration
+	burst    int
+	next     time.Time // момент, когда «очередь» limiter-а полностью освободится
+}
+
+// reserve занимает ближайший свободный слот и возвращает, сколько до него ждать
+func (l *limiter) reserve(now time.Time) time.Duration {
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now) - l.allowance()
+	l.next = l.next.Add(l.interval)
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// pause запрещает отправки до until (после 429)
+func (l *limiter) pause(until time.Time) {
+	if earliest := until.Add(l.allowance()); l.next.Before(earliest) {
+		l.next = earliest
+	}
+}
+
+func (l *limiter) allowance() time.Duration {
+	return time.Duration(l.burst-1) * l.interval
+}
+
+// outboundRequest - запрос в исходящей очереди чата
+type outboundRequest struct {
+	what     string
+	send     func() error
+	deadline time.Time  // позже запрос уже не отправляется
+	done     chan error // результат отправки, буфер на одно значение
+}
+
+// Outbox - Messenger-обёртка с исходящей очередью: соблюдает общий лимит
+// и лимиты на чат, повторяет отправку при 429 и сетевых ошибках.
+// У каждого чата своя очередь, её разбирает отдельная горутина - ожидание лимита
+// и повторы идут в ней, а вызывающий только ждёт результата (не дольше maxSendWait).
+// Запросы вне чатов (ответы на callback, удаления) в очередь не встают и
+// выполняются сразу в горутине вызывающего: их сдерживает только общий лимит.
+// Ошибку возвращает только когда повторять бессмысленно или время вышло.
+type Outbox struct {
+	api Messenger
+	ctx context.Context // при отмене ожидающие запросы бросаются
+
+	mu     sync.Mutex
+	global limiter
+	chats  map[int64]*limiter
+	queues map[int64][]*outboundRequest // очереди чатов, у которых есть горутина отправки
+}
+
+// NewOutbox создаёт очередь поверх api. Отмена ctx прерывает ожидание в очереди
+// и повторы - её нужно отменять, только когда отправлять уже поздно.
+func NewOutbox(ctx context.Context, api Messenger) *Outbox {
+	return &Outbox{
+		api:    api,
+		ctx:    ctx,
+		global: limiter{interval: globalSendInterval, burst: globalSendBurst},
+		chats:  make(map[int64]*limiter),
+		queues: make(map[int64][]*outboundRequest),
+	}
+}
+
+var _ Messenger = (*Outbox)(nil)
+
+func (o *Outbox) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	var msg tgbotapi.Message
+	err := o.do(fmt.Sprintf("%T", c), outboundChatID(c), func() error {
+		var err error
+		msg, err = o.api.Send(c)
+		return err
+	})
+	return msg, err
+}
+
+func (o *Outbox) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	var resp *tgbotapi.APIResponse
+	err := o.do(fmt.Sprintf("%T", c), outboundChatID(c), func() error {
+		var err error
+		resp, err = o.api.Request(c)
+		return err
+	})
+	return resp, err
+}
+
+func (o *Outbox) MakeRequest(endpoint string, params tgbotapi.Params) (*tgbotapi.APIResponse, error) {
+	var resp *tgbotapi.APIResponse
+	err := o.do(endpoint, 0, func() error {
+		var err error
+		resp, err = o.api.MakeRequest(endpoint, params)
+		return err
+	})
+	return resp, err
+}
+
+func (o *Outbox) GetUpdatesChan(config tgbotapi.UpdateConfig) tgbotapi.UpdatesChannel {
+	return o.api.GetUpdatesChan(config)
+}
+
+func (o *Outbox) StopReceivingUpdates() {
+	o.api.StopReceivingUpdates()
+}
+
+// do ставит запрос в очередь чата и ждёт результата.
+// chatID == 0 - запрос не относится к чату: его не за кем ставить, он отправляется сразу
+// (ограничен только общим лимитом), иначе 429 или медленный ответ на один такой запрос
+// задержал бы ответы на нажатия кнопок всех пользователей.
+func (o *Outbox) do(what string, chatID int64, send func() error) error {
+	req := &outboundRequest{
+		what:     what,
+		send:     send,
+		deadline: time.Now().Add(maxSendWait),
+		done:     make(chan error, 1),
+	}
+	if chatID == 0 {
+		return o.deliver(chatID, req)
+	}
+
+	o.mu.Lock()
+	queue, active := o.queues[chatID]
+	o.queues[chatID] = append(queue, req)
+	// У активного чата уже есть горутина, которая разберёт его очередь
+	if !active {
+		go o.drain(chatID)
+	}
+	o.mu.Unlock()
+
+	// Запросы впереди в очереди укладываются в свои, более ранние сроки,
+	// поэтому ответ приходит не позже deadline (плюс время последнего запроса к API)
+	select {
+	case err := <-req.done:
+		return err
+	case <-o.ctx.Done():
+		return fmt.Errorf("%s: %w", what, o.ctx.Err())
+	}
+}
+
+// drain по одному отправляет запросы чата, пока его очередь не опустеет
+func (o *Outbox) drain(chatID int64) {
+	for {
+		o.mu.Lock()
+		queue := o.queues[chatID]
+		if len(queue) == 0 {
+			delete(o.queues, chatID)
+			o.mu.Unlock()
+			return
+		}
+		req := queue[0]
+		o.queues[chatID] = queue[1:]
+		o.mu.Unlock()
+
+		req.done <- o.deliver(chatID, req)
+	}
+}
+
+// deliver выполняет запрос с учётом лимитов и повторяет его при временных ошибках
+func (o *Outbox) deliver(chatID int64, req *outboundRequest) error {
+	var err error
+	for attempt := 1; attempt <= maxSendAttempts; attempt++ {
+		if waitErr := o.wait(o.reserve(chatID), req.deadline); waitErr != nil {
+			if err != nil {
+				return fmt.Errorf("%s: %w (last error: %v)", req.what, waitErr, err)
+			}
+			return fmt.Errorf("%s: %w", req.what, waitErr)
+		}
+
+		err = req.send()
+		if err == nil {
+			return nil
+		}
+
+		delay, retry := retryDelay(err, attempt)
+		if !retry || attempt == maxSendAttempts {
+			break
+		}
+		if time.Now().Add(delay).After(req.deadline) {
+			return fmt.Errorf("%s: retry in %s exceeds send timeout: %w", req.what, delay, err)
+		}
+
+		slog.Warn("Telegram request failed, retrying",
+			"request", req.what, "chat_id", chatID, "attempt", attempt, "delay", delay.String(), "error", err)
+		o.pause(chatID, time.Now().Add(delay))
+	}
+	return fmt.Errorf("%s: %w", req.what, err)
+}
+
+// wait ждёт d, если успевает до deadline и ctx очереди не отменён
+func (o *Outbox) wait(d time.Duration, deadline time.Time) error {
+	if time.Now().Add(d).After(deadline) {
+		return errSendTimeout
+	}
+	if err := o.ctx.Err(); err != nil {
+		return err
+	}
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-o.ctx.Done():
+		return o.ctx.Err()
+	}
+}
+
+// reserve занимает слот в общей очереди и в очереди чата
+func (o *Outbox) reserve(chatID int64) time.Duration {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := time.Now()
+	wait := o.global.reserve(now)
+	if chatID != 0 {
+		// Слот чата считаем от момента, когда подойдёт общая очередь
+		if chatWait := o.chat(chatID).reserve(now.Add(wait)); chatWait > 0 {
+			wait += chatWait
+		}
+	}
+	return wait
+}
+
+// pause откладывает отправки в чат (или все отправки, если чат не указан)
+func (o *Outbox) pause(chatID int64, until time.Time) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if chatID == 0 {
+		o.global.pause(until)
+		return
+	}
+	o.chat(chatID).pause(until)
+}
+
+// chat возвращает лимитер чата, попутно забывая давно простаивающие
+func (o *Outbox) chat(chatID int64) *limiter {
+	l, ok := o.chats[chatID]
+	if ok {
+		return l
+	}
+
+	if len(o.chats) >= 1000 {
+		idle := time.Now().Add(-2 * time.Minute)
+		for id, cl := range o.chats {
+			if cl.next.Before(idle) {
+				delete(o.chats, id)
+			}
+		}
+	}
+
+	interval := privateSendInterval
+	if chatID < 0 {
+		// У групп и каналов отрицательные ID
+		interval = groupSendInterval
+	}
+	l = &limiter{interval: interval, burst: chatSendBurst}
+	o.chats[chatID] = l
+	return l
+}
+
+// retryDelay решает, стоит ли повторять запрос, и через сколько
+func retryDelay(err error, attempt int) (time.Duration, bool) {
+	backoff := baseRetryDelay << (attempt - 1)
+
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.Code == 429:
+			if apiErr.RetryAfter > 0 {
+				return time.Duration(apiErr.RetryAfter) * time.Second, true
+			}
+			return backoff, true
+		case apiErr.Code >= 500:
+			return backoff, true
+		default:
+			// 400/403 и т.п. - запрос некорректен или бот заблокирован, повтор не поможет
+			return 0, false
+		}
+	}
+
+	// Не ответ API - сетевая ошибка или обрыв соединения
+	return backoff, true
+}
+
+// outboundChatID возвращает чат, в который уходит сообщение, или 0,
+// если запрос не подпадает под лимиты на чат (ответ на callback, удаление и т.п.)
+func outboundChatID(c tgbotapi.Chattable) int64 {
+	switch cfg := c.(type) {
+	case tgbotapi.MessageConfig:
+		return cfg.ChatID
+	case tgbotapi.PhotoConfig:
+		return cfg.ChatID
+	case tgbotapi.DocumentConfig:
+		return cfg.ChatID
+	case tgbotapi.EditMessageTextConfig:
+		return cfg.ChatID
+	case tgbotapi.EditMessageReplyMarkupConfig:
+		return cfg.ChatID
+	}
+	return 0
+}
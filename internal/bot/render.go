@@ -0,0 +1,264 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+type partKind int
+
+const (
+	partPlain partKind = iota
+	partBold
+	partItalic
+	partCode
+	partPre
+	partMention
+)
+
+// Part - кусок сообщения с одним видом оформления
+type Part struct {
+	kind partKind
+	text string
+}
+
+// Plain - обычный текст, экранируется при отрисовке
+func Plain(text string) Part { return Part{kind: partPlain, text: text} }
+
+// Bold - жирный текст
+func Bold(text string) Part { return Part{kind: partBold, text: text} }
+
+// Italic - курсив
+func Italic(text string) Part { return Part{kind: partItalic, text: text} }
+
+// Code - моноширинный фрагмент в строке
+func Code(text string) Part { return Part{kind: partCode, text: text} }
+
+// Pre - моноширинный блок
+func Pre(text string) Part { return Part{kind: partPre, text: text} }
+
+// Mention - упоминание пользователя по username (с @ или без)
+func Mention(username string) Part {
+	return Part{kind: partMention, text: "@" + strings.TrimPrefix(username, "@")}
+}
+
+// Text - сообщение из типизированных частей.
+// Пользовательские данные (username, названия) попадают в него только через части,
+// поэтому всегда корректно экранируются.
+type Text []Part
+
+// NewText собирает сообщение из частей
+func NewText(parts ...Part) Text {
+	return Text(parts)
+}
+
+// Add дописывает части в конец сообщения
+func (t Text) Add(parts ...Part) Text {
+	return append(t, parts...)
+}
+
+// Line дописывает части и перевод строки
+func (t Text) Line(parts ...Part) Text {
+	return append(append(t, parts...), Plain("\n"))
+}
+
+// Render отрисовывает сообщение в указанном режиме разметки
+// (tgbotapi.ModeMarkdownV2, tgbotapi.ModeHTML или "" - без разметки)
+func (t Text) Render(parseMode string) string {
+	switch parseMode {
+	case tgbotapi.ModeMarkdownV2:
+		return t.MarkdownV2()
+	case tgbotapi.ModeHTML:
+		return t.HTML()
+	default:
+		return t.String()
+	}
+}
+
+// MarkdownV2 отрисовывает сообщение в MarkdownV2
+func (t Text) MarkdownV2() string {
+	var sb strings.Builder
+	for _, p := range t {
+		switch p.kind {
+		case partBold:
+			sb.WriteString("*" + EscapeMarkdownV2(p.text) + "*")
+		case partItalic:
+			sb.WriteString("_" + EscapeMarkdownV2(p.text) + "_")
+		case partCode:
+			sb.WriteString("`" + escapeMarkdownV2Code(p.text) + "`")
+		case partPre:
+			sb.WriteString("```\n" + escapeMarkdownV2Code(p.text) + "\n```")
+		default:
+			sb.WriteString(EscapeMarkdownV2(p.text))
+		}
+	}
+	return sb.String()
+}
+
+// HTML отрисовывает сообщение в HTML-разметке Telegram
+func (t Text) HTML() string {
+	var sb strings.Builder
+	for _, p := range t {
+		text := html.EscapeString(p.text)
+		switch p.kind {
+		case partBold:
+			sb.WriteString("<b>" + text + "</b>")
+		case partItalic:
+			sb.WriteString("<i>" + text + "</i>")
+		case partCode:
+			sb.WriteString("<code>" + text + "</code>")
+		case partPre:
+			sb.WriteString("<pre>" + text + "</pre>")
+		default:
+			sb.WriteString(text)
+		}
+	}
+	return sb.String()
+}
+
+// String возвращает сообщение без разметки
+func (t Text) String() string {
+	var sb strings.Builder
+	for _, p := range t {
+		sb.WriteString(p.text)
+	}
+	return sb.String()
+}
+
+var markdownV2Replacer = strings.NewReplacer(
+	"\\", "\\\\",
+	"_", "\\_",
+	"*", "\\*",
+	"[", "\\[",
+	"]", "\\]",
+	"(", "\\(",
+	")", "\\)",
+	"~", "\\~",
+	"`", "\\`",
+	">", "\\>",
+	"#", "\\#",
+	"+", "\\+",
+	"-", "\\-",
+	"=", "\\=",
+	"|", "\\|",
+	"{", "\\{",
+	"}", "\\}",
+	".", "\\.",
+	"!", "\\!",
+)
+
+// EscapeMarkdownV2 экранирует текст для режима MarkdownV2
+func EscapeMarkdownV2(text string) string {
+	return markdownV2Replacer.Replace(text)
+}
+
+// Внутри code/pre в MarkdownV2 экранируются только ` и \
+var markdownV2CodeReplacer = strings.NewReplacer("\\", "\\\\", "`", "\\`")
+
+func escapeMarkdownV2Code(text string) string {
+	return markdownV2CodeReplacer.Replace(text)
+}
+
+// SendText отправляет сообщение в MarkdownV2. keyboard может быть nil.
+// Возвращает ID отправленного сообщения или 0.
+func (b *Bot) SendText(chatID int64, text Text, keyboard interface{}) int {
+	msg := tgbotapi.NewMessage(chatID, text.MarkdownV2())
+	msg.ParseMode = tgbotapi.ModeMarkdownV2
+	if keyboard != nil {
+		msg.ReplyMarkup = keyboard
+	}
+	sent, err := b.send(msg, text.String())
+	if err != nil {
+		return 0
+	}
+	return sent.MessageID
+}
+
+// SendInlineText отправляет сообщение с inline-клавиатурой и сохраняет его ID для очистки
+func (b *Bot) SendInlineText(chatID int64, text Text, keyboard tgbotapi.InlineKeyboardMarkup) int {
+	msgID := b.SendText(chatID, text, keyboard)
+	if msgID != 0 {
+		b.StoreMessageID(chatID, msgID)
+	}
+	return msgID
+}
+
+// EditText заменяет текст сообщения. keyboard может быть nil.
+func (b *Bot) EditText(chatID int64, messageID int, text Text, keyboard *tgbotapi.InlineKeyboardMarkup) {
+	msg := tgbotapi.NewEditMessageText(chatID, messageID, text.MarkdownV2())
+	msg.ParseMode = tgbotapi.ModeMarkdownV2
+	if keyboard != nil {
+		msg.ReplyMarkup = keyboard
+	}
+	b.send(msg, text.String())
+}
+
+// send отправляет сообщение, а если Telegram не смог разобрать разметку -
+// повторяет без неё, подставив plain (пустой plain - тот же текст).
+// Ошибки логируются, вызывающему они нужны только для ветвления.
+func (b *Bot) send(c tgbotapi.Chattable, plain string) (tgbotapi.Message, error) {
+	sent, err := b.API.Send(c)
+	if err != nil && isParseError(err) {
+		if fallback, ok := withoutParseMode(c, plain); ok {
+			b.Log(outboundChatID(c)).Warn("Telegram could not parse markup, resending as plain text", "request", fmt.Sprintf("%T", c), "chat_id", outboundChatID(c), "error", err)
+			sent, err = b.API.Send(fallback)
+		}
+	}
+	if err != nil && !isNotModified(err) {
+		// В личных чатах chatID совпадает с telegram ID - ошибка попадёт в лог обновления
+		b.Log(outboundChatID(c)).Error("Error sending to Telegram", "request", fmt.Sprintf("%T", c), "chat_id", outboundChatID(c), "error", err)
+	}
+	return sent, err
+}
+
+// isParseError - Telegram отклонил сообщение из-за ошибки в разметке
+func isParseError(err error) bool {
+	var apiErr *tgbotapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == 400 && strings.Contains(apiErr.Message, "can't parse entities")
+}
+
+// isNotModified - Telegram отклоняет правку, которая ничего не меняет (повторное нажатие кнопки)
+func isNotModified(err error) bool {
+	return strings.Contains(err.Error(), "message is not modified")
+}
+
+// withoutParseMode возвращает копию сообщения без разметки
+func withoutParseMode(c tgbotapi.Chattable, plain string) (tgbotapi.Chattable, bool) {
+	switch cfg := c.(type) {
+	case tgbotapi.MessageConfig:
+		if cfg.ParseMode == "" {
+			return nil, false
+		}
+		cfg.ParseMode = ""
+		cfg.Entities = nil
+		if plain != "" {
+			cfg.Text = plain
+		}
+		return cfg, true
+	case tgbotapi.EditMessageTextConfig:
+		if cfg.ParseMode == "" {
+			return nil, false
+		}
+		cfg.ParseMode = ""
+		cfg.Entities = nil
+		if plain != "" {
+			cfg.Text = plain
+		}
+		return cfg, true
+	case tgbotapi.PhotoConfig:
+		if cfg.ParseMode == "" {
+			return nil, false
+		}
+		cfg.ParseMode = ""
+		cfg.CaptionEntities = nil
+		if plain != "" {
+			cfg.Caption = plain
+		}
+		return cfg, true
+	}
+	return nil, false
+}
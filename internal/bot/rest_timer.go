@@ -0,0 +1,127 @@
+package bot
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// restTick - как часто обновляется сообщение с отсчётом.
+// Чаще не стоит: правки считаются в лимит сообщений в чат.
+const restTick = 5 * time.Second
+
+// restTimers - таймеры отдыха по пользователям, не больше одного на пользователя.
+// Новый таймер заменяет старый: отдых считается от последнего записанного подхода.
+type restTimers struct {
+	mu     sync.Mutex
+	timers map[int64]*restTimer
+}
+
+type restTimer struct {
+	stop    chan struct{}
+	stopped bool // stop уже закрыт
+}
+
+// StartRest запускает отсчёт отдыха: одно сообщение обновляется до конца отдыха,
+// затем отдельным сообщением приходит уведомление. Прежний таймер пользователя останавливается.
+func (b *Bot) StartRest(chatID, telegramID int64, seconds int) {
+	if seconds <= 0 {
+		return
+	}
+	until := time.Now().Add(time.Duration(seconds) * time.Second)
+
+	timer := &restTimer{stop: make(chan struct{})}
+	b.rest.mu.Lock()
+	if b.rest.timers == nil {
+		b.rest.timers = make(map[int64]*restTimer)
+	}
+	if old, ok := b.rest.timers[telegramID]; ok {
+		old.close()
+	}
+	b.rest.timers[telegramID] = timer
+	b.rest.mu.Unlock()
+
+	// Не SendInlineText: сообщение таймера не должно удаляться при очистке меню
+	msgID := b.SendText(chatID, restText(time.Until(until)), GetInlineRestKeyboard())
+
+	go b.runRest(chatID, telegramID, msgID, until, timer)
+}
+
+// StopRest останавливает таймер отдыха пользователя. false - таймера не было.
+func (b *Bot) StopRest(telegramID int64) bool {
+	b.rest.mu.Lock()
+	defer b.rest.mu.Unlock()
+	timer, ok := b.rest.timers[telegramID]
+	if !ok {
+		return false
+	}
+	timer.close()
+	delete(b.rest.timers, telegramID)
+	return true
+}
+
+// StopAllRest останавливает все таймеры (при остановке бота)
+func (b *Bot) StopAllRest() {
+	b.rest.mu.Lock()
+	defer b.rest.mu.Unlock()
+	for id, timer := range b.rest.timers {
+		timer.close()
+		delete(b.rest.timers, id)
+	}
+}
+
+func (b *Bot) runRest(chatID, telegramID int64, msgID int, until time.Time, timer *restTimer) {
+	ticker := time.NewTicker(restTick)
+	defer ticker.Stop()
+	deadline := time.NewTimer(time.Until(until))
+	defer deadline.Stop()
+
+	keyboard := GetInlineRestKeyboard()
+	for {
+		select {
+		case <-timer.stop:
+			if msgID != 0 {
+				b.EditText(chatID, msgID, NewText(Plain("⏹ Отдых остановлен")), nil)
+			}
+			return
+		case <-ticker.C:
+			if msgID != 0 {
+				b.EditText(chatID, msgID, restText(time.Until(until)), &keyboard)
+			}
+		case <-deadline.C:
+			// Таймер могли заменить новым как раз сейчас - тогда молчим
+			b.rest.mu.Lock()
+			current := b.rest.timers[telegramID] == timer
+			if current {
+				delete(b.rest.timers, telegramID)
+			}
+			b.rest.mu.Unlock()
+			if !current {
+				continue
+			}
+			if msgID != 0 {
+				b.EditText(chatID, msgID, NewText(Plain("✅ Отдых окончен")), nil)
+			}
+			b.SendText(chatID, NewText(Plain("🔔 Отдых окончен - следующий подход!")), nil)
+			return
+		}
+	}
+}
+
+// close останавливает горутину таймера; вызывается под restTimers.mu
+func (t *restTimer) close() {
+	if !t.stopped {
+		close(t.stop)
+		t.stopped = true
+	}
+}
+
+// restText - сообщение с оставшимся временем отдыха
+func restText(left time.Duration) Text {
+	if left < 0 {
+		left = 0
+	}
+	// Округляем вверх: «0:00» показываем только в конце
+	secs := int((left + time.Second - 1) / time.Second)
+	return NewText(Plain("⏱ Отдых: "), Bold(fmt.Sprintf("%d:%02d", secs/60, secs%60)))
+}
@@ -0,0 +1,141 @@
+package bot
+
+import (
+	"fitness-bot/internal/models"
+	"log/slog"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// CancelText - кнопка «назад» во всех диалогах
+	CancelText = "❌ Отмена"
+	// MainMenuText - кнопка возврата в главное меню
+	MainMenuText = "🔙 Главное меню"
+
+	// StateRoot - виртуальное состояние главного меню (состояние очищено)
+	StateRoot = "root"
+)
+
+// StateHandler обрабатывает сообщение в текущем состоянии диалога
+type StateHandler func(b *Bot, message *tgbotapi.Message, state *models.UserState, accessInfo *models.AccessInfo)
+
+// EnterHandler показывает экран состояния при возврате к нему по «❌ Отмена».
+// data - данные состояния, из которого вернулись (org_id, trainer_id и т.п.)
+type EnterHandler func(b *Bot, message *tgbotapi.Message, data map[string]interface{}, accessInfo *models.AccessInfo)
+
+// Route описывает одно состояние диалога
+type Route struct {
+	State   string
+	Handler StateHandler // nil для виртуальных состояний (меню без состояния)
+	Enter   EnterHandler // nil, если в состояние нельзя вернуться по «Отмене»
+	// Parent - куда ведёт «❌ Отмена». Пустая строка - хендлер обрабатывает отмену сам.
+	Parent string
+	// Next - состояния, в которые разрешён переход из этого
+	Next []string
+}
+
+// Router маршрутизирует сообщения по состояниям диалога
+type Router struct {
+	routes map[string]*Route
+}
+
+func NewRouter() *Router {
+	return &Router{routes: make(map[string]*Route)}
+}
+
+// Register добавляет состояние в роутер
+func (r *Router) Register(route Route) {
+	if _, exists := r.routes[route.State]; exists {
+		slog.Warn("Router: state registered twice", "state", route.State)
+	}
+	rt := route
+	r.routes[route.State] = &rt
+}
+
+// Handle обрабатывает сообщение пользователя в текущем состоянии
+func (r *Router) Handle(b *Bot, message *tgbotapi.Message, state *models.UserState, accessInfo *models.AccessInfo) {
+	if message.Text == MainMenuText {
+		r.Navigate(b, message, StateRoot, nil, accessInfo)
+		return
+	}
+
+	route, ok := r.routes[state.State]
+	if !ok || route.Handler == nil {
+		b.Log(message.From.ID).Warn("Router: unknown state, resetting to main menu", "state", state.State)
+		r.Navigate(b, message, StateRoot, nil, accessInfo)
+		return
+	}
+
+	if message.Text == CancelText && route.Parent != "" {
+		b.CleanupMessages(message.Chat.ID, message.From.ID)
+		r.Navigate(b, message, route.Parent, state.Data, accessInfo)
+		return
+	}
+
+	route.Handler(b, message, state, accessInfo)
+}
+
+// Cancel выполняет «❌ Отмена» для текущего состояния: возврат к родителю или в главное меню
+func (r *Router) Cancel(b *Bot, message *tgbotapi.Message, state *models.UserState, accessInfo *models.AccessInfo) {
+	b.CleanupMessages(message.Chat.ID, message.From.ID)
+	if state == nil {
+		r.Navigate(b, message, StateRoot, nil, accessInfo)
+		return
+	}
+	parent := StateRoot
+	if route, ok := r.routes[state.State]; ok && route.Parent != "" {
+		parent = route.Parent
+	}
+	r.Navigate(b, message, parent, state.Data, accessInfo)
+}
+
+// Navigate переводит пользователя в состояние target, показывая его экран
+func (r *Router) Navigate(b *Bot, message *tgbotapi.Message, target string, data map[string]interface{}, accessInfo *models.AccessInfo) {
+	route, ok := r.routes[target]
+	if !ok || route.Enter == nil {
+		if target != StateRoot {
+			b.Log(message.From.ID).Warn("Router: cannot enter state, falling back to main menu", "target_state", target)
+		}
+		if root, ok := r.routes[StateRoot]; ok && root.Enter != nil {
+			root.Enter(b, message, nil, accessInfo)
+			return
+		}
+		b.ClearState(message.From.ID)
+		return
+	}
+	route.Enter(b, message, data, accessInfo)
+}
+
+// CanTransition проверяет, разрешён ли переход между состояниями.
+// Из «пустого» состояния можно попасть куда угодно (это вход из меню),
+// очистка состояния и возврат к родителю разрешены всегда.
+func (r *Router) CanTransition(from, to string) bool {
+	if from == "" || from == to || to == "" || to == StateRoot {
+		return true
+	}
+	route, ok := r.routes[from]
+	if !ok {
+		return false
+	}
+	if route.Parent == to {
+		return true
+	}
+	for _, next := range route.Next {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTransition логирует неизвестные и недопустимые переходы
+func (r *Router) checkTransition(telegramID int64, from, to string) {
+	if _, ok := r.routes[to]; !ok {
+		slog.Warn("Router: transition to unknown state", "user_id", telegramID, "from", from, "to", to)
+		return
+	}
+	if !r.CanTransition(from, to) {
+		slog.Warn("Router: illegal transition", "user_id", telegramID, "from", from, "to", to)
+	}
+}
@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"fitness-bot/internal/database"
+	"fitness-bot/internal/models"
+	"sync"
+)
+
+// StateStore хранит состояния диалогов пользователей
+type StateStore interface {
+	// Get возвращает состояние пользователя или nil, если его нет
+	Get(telegramID int64) (*models.UserState, error)
+	// Set сохраняет состояние, заменяя предыдущее
+	Set(state *models.UserState) error
+	// Delete удаляет состояние пользователя
+	Delete(telegramID int64) error
+}
+
+// MemoryStateStore хранит состояния в памяти процесса (используется в тестах)
+type MemoryStateStore struct {
+	mu     sync.RWMutex
+	states map[int64]*models.UserState
+}
+
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{
+		states: make(map[int64]*models.UserState),
+	}
+}
+
+// Get возвращает копию состояния, чтобы изменения попадали в хранилище только через Set
+func (s *MemoryStateStore) Get(telegramID int64) (*models.UserState, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	state := s.states[telegramID]
+	if state == nil {
+		return nil, nil
+	}
+	return &models.UserState{
+		TelegramID: state.TelegramID,
+		State:      state.State,
+		Data:       CopyStateData(state.Data),
+	}, nil
+}
+
+func (s *MemoryStateStore) Set(state *models.UserState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[state.TelegramID] = &models.UserState{
+		TelegramID: state.TelegramID,
+		State:      state.State,
+		Data:       CopyStateData(state.Data),
+	}
+	return nil
+}
+
+func (s *MemoryStateStore) Delete(telegramID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.states, telegramID)
+	return nil
+}
+
+// PostgresStateStore хранит состояния в таблице user_states
+type PostgresStateStore struct {
+	db *database.DB
+}
+
+func NewPostgresStateStore(db *database.DB) *PostgresStateStore {
+	return &PostgresStateStore{db: db}
+}
+
+func (s *PostgresStateStore) Get(telegramID int64) (*models.UserState, error) {
+	record, err := s.db.GetUserStateRecord(telegramID)
+	if err != nil || record == nil {
+		return nil, err
+	}
+	data, err := DecodeStateData(record.Data)
+	if err != nil {
+		return nil, err
+	}
+	return &models.UserState{
+		TelegramID: record.TelegramID,
+		State:      record.State,
+		Data:       data,
+	}, nil
+}
+
+func (s *PostgresStateStore) Set(state *models.UserState) error {
+	raw, err := EncodeStateData(state.Data)
+	if err != nil {
+		return err
+	}
+	return s.db.SaveUserStateRecord(&models.UserStateRecord{
+		TelegramID: state.TelegramID,
+		State:      state.State,
+		Data:       raw,
+	})
+}
+
+func (s *PostgresStateStore) Delete(telegramID int64) error {
+	return s.db.DeleteUserStateRecord(telegramID)
+}
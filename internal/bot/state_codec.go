@@ -0,0 +1,114 @@
+package bot
+
+import (
+	"encoding/json"
+	"fitness-bot/internal/models"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// encodedValue - значение из UserState.Data вместе с именем его типа,
+// чтобы при восстановлении получить тот же Go-тип, а не map/float64
+type encodedValue struct {
+	Type  string          `json:"t"`
+	Value json.RawMessage `json:"v,omitempty"`
+}
+
+const nilTypeName = "nil"
+
+var (
+	stateTypesMu sync.RWMutex
+	stateTypes   = map[string]reflect.Type{}
+	stateNames   = map[reflect.Type]string{}
+)
+
+// RegisterStateType регистрирует тип, который можно хранить в UserState.Data.
+// name сохраняется в БД, поэтому его нельзя менять после выкладки.
+func RegisterStateType(name string, sample interface{}) {
+	t := reflect.TypeOf(sample)
+	stateTypesMu.Lock()
+	defer stateTypesMu.Unlock()
+	stateTypes[name] = t
+	stateNames[t] = name
+}
+
+func init() {
+	RegisterStateType("int", int(0))
+	RegisterStateType("int64", int64(0))
+	RegisterStateType("float64", float64(0))
+	RegisterStateType("string", "")
+	RegisterStateType("bool", false)
+	RegisterStateType("[]int", []int(nil))
+	RegisterStateType("[]int64", []int64(nil))
+	RegisterStateType("*int64", (*int64)(nil))
+
+	RegisterStateType("[]*Organization", []*models.Organization(nil))
+	RegisterStateType("[]*OrganizationManager", []*models.OrganizationManager(nil))
+	RegisterStateType("[]*OrganizationTrainer", []*models.OrganizationTrainer(nil))
+	RegisterStateType("[]*ManagerOrgInfo", []*models.ManagerOrgInfo(nil))
+	RegisterStateType("[]*TrainerOrgInfo", []*models.TrainerOrgInfo(nil))
+	RegisterStateType("[]*ClientAccessInfo", []*models.ClientAccessInfo(nil))
+	RegisterStateType("[]*ClientWithInfo", []*models.ClientWithInfo(nil))
+	RegisterStateType("*ClientWithInfo", (*models.ClientWithInfo)(nil))
+	RegisterStateType("[]*GroupTraining", []*models.GroupTraining(nil))
+}
+
+// EncodeStateData сериализует данные состояния вместе с типами значений
+func EncodeStateData(data map[string]interface{}) ([]byte, error) {
+	encoded := make(map[string]encodedValue, len(data))
+
+	stateTypesMu.RLock()
+	defer stateTypesMu.RUnlock()
+
+	for key, value := range data {
+		if value == nil {
+			encoded[key] = encodedValue{Type: nilTypeName}
+			continue
+		}
+		name, ok := stateNames[reflect.TypeOf(value)]
+		if !ok {
+			return nil, fmt.Errorf("state key %q: unregistered type %T", key, value)
+		}
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("state key %q: %w", key, err)
+		}
+		encoded[key] = encodedValue{Type: name, Value: raw}
+	}
+
+	return json.Marshal(encoded)
+}
+
+// DecodeStateData восстанавливает данные состояния с исходными типами
+func DecodeStateData(raw []byte) (map[string]interface{}, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	var encoded map[string]encodedValue
+	if err := json.Unmarshal(raw, &encoded); err != nil {
+		return nil, err
+	}
+
+	stateTypesMu.RLock()
+	defer stateTypesMu.RUnlock()
+
+	data := make(map[string]interface{}, len(encoded))
+	for key, ev := range encoded {
+		if ev.Type == nilTypeName {
+			data[key] = nil
+			continue
+		}
+		t, ok := stateTypes[ev.Type]
+		if !ok {
+			return nil, fmt.Errorf("state key %q: unknown type %q", key, ev.Type)
+		}
+		ptr := reflect.New(t)
+		if err := json.Unmarshal(ev.Value, ptr.Interface()); err != nil {
+			return nil, fmt.Errorf("state key %q: %w", key, err)
+		}
+		data[key] = ptr.Elem().Interface()
+	}
+	return data, nil
+}
@@ -0,0 +1,97 @@
+package bot
+
+import (
+	"fitness-bot/internal/database"
+	"fitness-bot/internal/models"
+	"time"
+)
+
+// Storage - данные бота, с которыми работают обработчики.
+// *database.DB удовлетворяет интерфейсу, в сценарных тестах его подменяет фейк в памяти.
+type Storage interface {
+	// Пользователи
+	EnsureUser(telegramID int64, username, fullName string) error
+	GetUserByTelegramID(telegramID int64) (*models.User, error)
+	SetBodyweight(telegramID int64, kg float64) error
+	SetShareWorkouts(telegramID int64, share bool) error
+	SetWeightUnit(telegramID int64, unit models.WeightUnit) error
+
+	// Доступ: менеджеры, тренеры, клиенты
+	AddClient(trainerID int64, username string) error
+	AddManager(orgID int64, username string) error
+	AddTrainer(orgID int64, username string) error
+	GetClientAccess(telegramID int64, username string, activeOnly bool) ([]*models.ClientAccessInfo, error)
+	GetOrganizationManagers(orgID int64) ([]*models.OrganizationManager, error)
+	GetOrganizationTrainers(orgID int64) ([]*models.OrganizationTrainer, error)
+	GetTrainerClientByID(id int64) (*models.TrainerClient, error)
+	GetTrainerClients(trainerID int64) ([]*models.ClientWithInfo, error)
+	GetUserAccessInfo(telegramID int64, username string) (*models.AccessInfo, error)
+	IsOrganizationManager(orgID, telegramID int64, username string) (bool, error)
+	LinkTelegramID(telegramID int64, username string) error
+	RemoveClient(trainerID int64, username string) error
+	RemoveManager(orgID int64, username string) error
+	RemoveTrainer(orgID int64, username string) error
+
+	// Организации
+	CreateOrganization(org *models.Organization) error
+	GetAllOrganizations() ([]*models.Organization, error)
+	GetOrganizationByID(id int64) (*models.Organization, error)
+	SetOrganizationRestSeconds(orgID int64, seconds int) error
+
+	// Группы мышц
+	CreateMuscleGroup(group *models.MuscleGroupRecord) (bool, error)
+	DeleteMuscleGroup(id int64) error
+	GetMuscleGroupByID(id int64) (*models.MuscleGroupRecord, error)
+	GetMuscleGroups(orgID *int64) ([]*models.MuscleGroupRecord, error)
+	GetMuscleGroupsByIDs(ids []int64, orgID *int64) ([]models.MuscleGroupRecord, error)
+
+	// Групповые тренировки
+	CreateGroupTraining(gt *models.GroupTraining) error
+	GetParticipantCount(trainingID int64) (int, error)
+	GetUpcomingGroupTrainings(orgID int64) ([]*models.GroupTraining, error)
+	JoinGroupTraining(trainingID, userID int64) error
+
+	// Тренировки и упражнения
+	CanEditWorkout(workout *models.Workout, telegramID int64, username string) (bool, error)
+	CancelWorkout(workoutID int64) error
+	CreateExercise(exercise *models.Exercise) error
+	CreateWorkout(workout *models.Workout) error
+	DeleteExercise(id int64) error
+	DeleteWorkout(id int64) error
+	FinishWorkout(workoutID int64, finishedAt *time.Time) (models.WorkoutStatus, error)
+	GetExerciseByID(id int64) (*models.Exercise, error)
+	GetExerciseStats(scope models.WorkoutScope, catalogIDs []int64, from, to time.Time) ([]*models.Exercise, error)
+	GetExercisesByWorkout(workoutID int64) ([]*models.Exercise, error)
+	GetFullWorkoutHistory(scope models.WorkoutScope, filter models.WorkoutFilter) ([]*models.Workout, error)
+	GetPreviousExercises(scope models.WorkoutScope, catalogIDs []int64, before time.Time, exceptWorkoutID int64) (map[int64]*models.Exercise, error)
+	GetWorkoutByID(id int64) (*models.Workout, error)
+	GetWorkoutHistory(scope models.WorkoutScope, filter models.WorkoutFilter, offset, limit int) ([]*models.Workout, error)
+	GetWorkoutHistoryMuscleGroups(scope models.WorkoutScope) ([]*models.MuscleGroupRecord, error)
+	IsClientTrainer(trainerClientID, telegramID int64, username string) (bool, error)
+	MoveExercise(exerciseID int64, position int) error
+	ReplaceExerciseSets(exerciseID int64, sets []models.ExerciseSet) error
+	UpdateCardio(exerciseID int64, cardio *models.CardioEntry) error
+	UpdateExercise(exercise *models.Exercise) error
+
+	// Справочник упражнений
+	FindClientCatalogExercises(telegramID int64, name string) (*database.CatalogMatch, []int64, error)
+	GetCatalogExerciseByID(id int64) (*models.CatalogExercise, error)
+	GetPerformedCatalogExercises(scope models.WorkoutScope) ([]*models.CatalogExercise, error)
+	GetWorkoutOrganizationID(workout *models.Workout) (*int64, error)
+	ResolveCatalogExercise(name string, orgID *int64, muscleGroup models.MuscleGroup) (*database.CatalogMatch, error)
+
+	// Шаблоны тренировок
+	AddTemplateExercise(exercise *models.TemplateExercise) error
+	AssignTemplate(templateID, trainerClientID int64) error
+	CreateTemplate(template *models.WorkoutTemplate) error
+	DeleteTemplate(id int64) error
+	GetAssignedTemplates(trainerClientID int64) ([]*models.WorkoutTemplate, error)
+	GetOrganizationTemplates(orgID int64) ([]*models.WorkoutTemplate, error)
+	GetTemplateAssignees(templateID int64) ([]int64, error)
+	GetTemplateByID(id int64) (*models.WorkoutTemplate, error)
+	GetTemplateTrainerID(template *models.WorkoutTemplate, telegramID int64, username string) (int64, error)
+	IsTemplateAssigned(templateID, trainerClientID int64) (bool, error)
+	UnassignTemplate(templateID, trainerClientID int64) error
+}
+
+var _ Storage = (*database.DB)(nil)
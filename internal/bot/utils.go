@@ -4,25 +4,15 @@ import (
 	"strings"
 )
 
-// escapeMarkdown экранирует символы, которые ломают Markdown форматирование
+// EscapeMarkdown экранирует пользовательский текст для legacy-режима Markdown
+// (ParseMode "Markdown"), в котором служебными являются только _ * ` и [.
+// Для новых сообщений используйте Text - он экранирует MarkdownV2 сам.
 func EscapeMarkdown(text string) string {
 	replacer := strings.NewReplacer(
 		"_", "\\_",
 		"*", "\\*",
-		"[", "\\[",
-		"]", "\\]",
 		"`", "\\`",
-		"~", "\\~",
-		">", "\\>",
-		"#", "\\#",
-		"+", "\\+",
-		"-", "\\-",
-		"=", "\\=",
-		"|", "\\|",
-		"{", "\\{",
-		"}", "\\}",
-		".", "\\.",
-		"!", "\\!",
+		"[", "\\[",
 	)
 	return replacer.Replace(text)
 }
@@ -73,3 +63,12 @@ func GetStateString(data map[string]interface{}, key string) (string, bool) {
 	str, ok := v.(string)
 	return str, ok
 }
+
+// GetStateInt64s безопасно извлекает []int64 из данных состояния
+func GetStateInt64s(data map[string]interface{}, key string) []int64 {
+	if data == nil {
+		return nil
+	}
+	ids, _ := data[key].([]int64)
+	return ids
+}
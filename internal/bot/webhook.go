@@ -0,0 +1,163 @@
+package bot
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// SecretTokenHeader - заголовок, в котором Telegram присылает секрет вебхука
+const SecretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// Режимы получения обновлений
+const (
+	UpdateModePolling = "polling"
+	UpdateModeWebhook = "webhook"
+)
+
+// Telegram допускает в секрете только A-Z, a-z, 0-9, _ и -
+var secretTokenPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+// WebhookConfig - настройки режима вебхука
+type WebhookConfig struct {
+	URL    string // публичный https-адрес, который регистрируется в Telegram
+	Listen string // адрес HTTP-сервера за reverse proxy, например ":8080"
+	Secret string // значение X-Telegram-Bot-Api-Secret-Token
+}
+
+// Validate проверяет настройки вебхука
+func (c WebhookConfig) Validate() error {
+	u, err := url.Parse(c.URL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("WEBHOOK_URL must be an absolute https URL, got %q", c.URL)
+	}
+	if c.Listen == "" {
+		return errors.New("WEBHOOK_LISTEN is required")
+	}
+	if !secretTokenPattern.MatchString(c.Secret) {
+		return errors.New("WEBHOOK_SECRET must be 1-256 characters of A-Z, a-z, 0-9, _ and -")
+	}
+	return nil
+}
+
+// path возвращает путь, на который Telegram будет присылать обновления
+func (c WebhookConfig) path() string {
+	u, err := url.Parse(c.URL)
+	if err != nil || u.Path == "" {
+		return "/"
+	}
+	return u.Path
+}
+
+// WebhookServer принимает обновления от Telegram по HTTP
+type WebhookServer struct {
+	config  WebhookConfig
+	updates chan tgbotapi.Update
+	done    chan struct{} // закрывается при остановке сервера
+	server  *http.Server
+}
+
+func NewWebhookServer(config WebhookConfig) *WebhookServer {
+	ws := &WebhookServer{
+		config:  config,
+		updates: make(chan tgbotapi.Update, 100),
+		done:    make(chan struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.Handle(config.path(), ws)
+	ws.server = &http.Server{
+		Addr:              config.Listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return ws
+}
+
+// Updates возвращает канал принятых обновлений
+func (ws *WebhookServer) Updates() tgbotapi.UpdatesChannel {
+	return ws.updates
+}
+
+// ServeHTTP проверяет секрет и передаёт обновление в канал
+func (ws *WebhookServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := r.Header.Get(SecretTokenHeader)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(ws.config.Secret)) != 1 {
+		slog.Warn("Webhook: rejected request with invalid secret token", "remote_addr", r.RemoteAddr)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var update tgbotapi.Update
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&update); err != nil {
+		slog.Warn("Webhook: bad update payload", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Если обработчики не успевают, держим запрос - Telegram повторит доставку сам
+	select {
+	case ws.updates <- update:
+		w.WriteHeader(http.StatusOK)
+	case <-r.Context().Done():
+		w.WriteHeader(http.StatusServiceUnavailable)
+	case <-ws.done:
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+}
+
+// Start запускает HTTP-сервер в фоне
+func (ws *WebhookServer) Start() error {
+	ln, err := net.Listen("tcp", ws.config.Listen)
+	if err != nil {
+		return fmt.Errorf("webhook listen %s: %w", ws.config.Listen, err)
+	}
+	go func() {
+		if err := ws.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Webhook server stopped", "error", err)
+		}
+	}()
+	slog.Info("Webhook server listening", "addr", ws.config.Listen, "path", ws.config.path())
+	return nil
+}
+
+// Shutdown останавливает приём запросов и закрывает канал обновлений.
+// Запросы, ждущие места в канале, получают 503 - Telegram доставит их повторно.
+func (ws *WebhookServer) Shutdown(ctx context.Context) error {
+	close(ws.done)
+	if err := ws.server.Shutdown(ctx); err != nil {
+		// Часть обработчиков ещё работает - канал не закрываем, чтобы они не упали на отправке
+		return err
+	}
+	close(ws.updates)
+	return nil
+}
+
+// SetWebhook регистрирует вебхук в Telegram вместе с секретом
+func (b *Bot) SetWebhook(config WebhookConfig) error {
+	params := tgbotapi.Params{}
+	params["url"] = config.URL
+	params["secret_token"] = config.Secret
+	_, err := b.API.MakeRequest("setWebhook", params)
+	return err
+}
+
+// DeleteWebhook снимает вебхук, чтобы снова можно было получать обновления через getUpdates
+func (b *Bot) DeleteWebhook() error {
+	_, err := b.API.Request(tgbotapi.DeleteWebhookConfig{})
+	return err
+}
@@ -0,0 +1,111 @@
+// Package catalog нормализует названия упражнений и сопоставляет их со справочником
+package catalog
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Normalize приводит название упражнения к виду для сравнения:
+// нижний регистр, «ё» → «е», без лишних пробелов и знаков препинания по краям
+func Normalize(name string) string {
+	name = strings.ToLower(name)
+	name = strings.ReplaceAll(name, "ё", "е")
+	name = strings.Join(strings.Fields(name), " ")
+	return strings.TrimFunc(name, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSpace(r)
+	})
+}
+
+// Clean убирает лишние пробелы в названии, сохраняя написание для показа
+func Clean(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// Entry - упражнение справочника для сопоставления: ID и нормализованные название и синонимы
+type Entry struct {
+	ID    int64
+	Names []string
+}
+
+// Match ищет упражнение справочника по названию.
+// Сначала точное совпадение нормализованного названия или синонима, затем ближайшее
+// по расстоянию Левенштейна в пределах порога. Неоднозначное нечёткое совпадение
+// (два упражнения на одинаковом расстоянии) не засчитывается.
+// Возвращает ID (0 - не найдено) и признак нечёткого совпадения.
+func Match(name string, entries []Entry) (id int64, fuzzy bool) {
+	normalized := Normalize(name)
+	if normalized == "" {
+		return 0, false
+	}
+
+	for _, e := range entries {
+		for _, n := range e.Names {
+			if n == normalized {
+				return e.ID, false
+			}
+		}
+	}
+
+	limit := maxDistance(normalized)
+	best, bestDistance, ambiguous := int64(0), limit+1, false
+	for _, e := range entries {
+		entryDistance := limit + 1
+		for _, n := range e.Names {
+			if d := Distance(normalized, n); d < entryDistance {
+				entryDistance = d
+			}
+		}
+		switch {
+		case entryDistance < bestDistance:
+			best, bestDistance, ambiguous = e.ID, entryDistance, false
+		case entryDistance == bestDistance && entryDistance <= limit && e.ID != best:
+			ambiguous = true
+		}
+	}
+	if best == 0 || ambiguous {
+		return 0, false
+	}
+	return best, true
+}
+
+// maxDistance - допустимое число опечаток: примерно одна на пять букв, не больше трёх
+func maxDistance(name string) int {
+	n := len([]rune(name)) / 5
+	if n < 1 {
+		return 1
+	}
+	if n > 3 {
+		return 3
+	}
+	return n
+}
+
+// Distance возвращает расстояние Левенштейна между строками (по символам, а не байтам)
+func Distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 {
+		return len(rb)
+	}
+	if len(rb) == 0 {
+		return len(ra)
+	}
+
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
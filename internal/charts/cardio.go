@@ -0,0 +1,185 @@
+package charts
+
+import (
+	"bytes"
+	"fitness-bot/internal/models"
+	"fmt"
+	"time"
+
+	"github.com/wcharczuk/go-chart/v2"
+)
+
+// GenerateCardioChart строит график дистанции и темпа (на второй оси) по кардио-упражнениям.
+// Темп отложен только для тренировок, где известны и дистанция, и время.
+// По одной точке график не строится - nil.
+func GenerateCardioChart(exercises []*models.Exercise, exerciseName string) ([]byte, error) {
+	var distX, paceX []time.Time
+	var distY, paceY []float64
+
+	for i := len(exercises) - 1; i >= 0; i-- {
+		c := exercises[i].Cardio
+		if c == nil {
+			continue
+		}
+		if c.DistanceKm > 0 {
+			distX = append(distX, exercises[i].CreatedAt)
+			distY = append(distY, c.DistanceKm)
+		}
+		if pace, ok := c.PaceSecondsPerKm(); ok {
+			paceX = append(paceX, exercises[i].CreatedAt)
+			paceY = append(paceY, pace)
+		}
+	}
+	if len(distX) < 2 {
+		return nil, nil
+	}
+
+	series := []chart.Series{
+		chart.TimeSeries{
+			Name: "Дистанция",
+			Style: chart.Style{
+				StrokeColor: chart.ColorBlue,
+				StrokeWidth: 2,
+			},
+			XValues: distX,
+			YValues: distY,
+		},
+	}
+	if len(paceX) > 1 {
+		series = append(series, chart.TimeSeries{
+			Name: "Темп",
+			Style: chart.Style{
+				StrokeColor: chart.ColorOrange,
+				StrokeWidth: 2,
+			},
+			YAxis:   chart.YAxisSecondary,
+			XValues: paceX,
+			YValues: paceY,
+		})
+	}
+
+	graph := chart.Chart{
+		Title: "Кардио: " + exerciseName,
+		TitleStyle: chart.Style{
+			FontSize: 16,
+		},
+		Width:  800,
+		Height: 400,
+		XAxis: chart.XAxis{
+			Name: "Дата",
+			Style: chart.Style{
+				FontSize: 10,
+			},
+			ValueFormatter: chart.TimeValueFormatterWithFormat("02.01"),
+		},
+		YAxis: chart.YAxis{
+			Name: "Дистанция (км)",
+			Style: chart.Style{
+				FontSize: 10,
+			},
+		},
+		YAxisSecondary: chart.YAxis{
+			Name: "Темп (мин/км)",
+			Style: chart.Style{
+				FontSize: 10,
+			},
+			ValueFormatter: paceFormatter,
+		},
+		Series: series,
+	}
+	graph.Elements = []chart.Renderable{chart.Legend(&graph)}
+
+	return render(graph)
+}
+
+// GenerateHeartRateChart строит график среднего и максимального пульса.
+// nil - пульс записан меньше двух раз.
+func GenerateHeartRateChart(exercises []*models.Exercise, exerciseName string) ([]byte, error) {
+	var avgX, maxX []time.Time
+	var avgY, maxY []float64
+
+	for i := len(exercises) - 1; i >= 0; i-- {
+		c := exercises[i].Cardio
+		if c == nil {
+			continue
+		}
+		if c.AvgHeartRate != nil {
+			avgX = append(avgX, exercises[i].CreatedAt)
+			avgY = append(avgY, float64(*c.AvgHeartRate))
+		}
+		if c.MaxHeartRate != nil {
+			maxX = append(maxX, exercises[i].CreatedAt)
+			maxY = append(maxY, float64(*c.MaxHeartRate))
+		}
+	}
+	if len(avgX) < 2 {
+		return nil, nil
+	}
+
+	series := []chart.Series{
+		chart.TimeSeries{
+			Name: "Средний",
+			Style: chart.Style{
+				StrokeColor: chart.ColorBlue,
+				StrokeWidth: 2,
+			},
+			XValues: avgX,
+			YValues: avgY,
+		},
+	}
+	if len(maxX) > 1 {
+		series = append(series, chart.TimeSeries{
+			Name: "Максимальный",
+			Style: chart.Style{
+				StrokeColor: chart.ColorRed,
+				StrokeWidth: 2,
+			},
+			XValues: maxX,
+			YValues: maxY,
+		})
+	}
+
+	graph := chart.Chart{
+		Title: "Пульс: " + exerciseName,
+		TitleStyle: chart.Style{
+			FontSize: 16,
+		},
+		Width:  800,
+		Height: 400,
+		XAxis: chart.XAxis{
+			Name: "Дата",
+			Style: chart.Style{
+				FontSize: 10,
+			},
+			ValueFormatter: chart.TimeValueFormatterWithFormat("02.01"),
+		},
+		YAxis: chart.YAxis{
+			Name: "Пульс (уд/мин)",
+			Style: chart.Style{
+				FontSize: 10,
+			},
+		},
+		Series: series,
+	}
+	graph.Elements = []chart.Renderable{chart.Legend(&graph)}
+
+	return render(graph)
+}
+
+// paceFormatter подписывает ось темпа как «5:30»
+func paceFormatter(v interface{}) string {
+	seconds, ok := v.(float64)
+	if !ok {
+		return ""
+	}
+	s := int(seconds + 0.5)
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+func render(graph chart.Chart) ([]byte, error) {
+	buffer := bytes.NewBuffer([]byte{})
+	if err := graph.Render(chart.PNG, buffer); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
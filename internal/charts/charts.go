@@ -8,17 +8,42 @@ import (
 	"github.com/wcharczuk/go-chart/v2"
 )
 
-func GenerateProgressChart(exercises []*models.Exercise, exerciseName string) ([]byte, error) {
+// GenerateProgressChart строит график нагрузки лучшего подхода по тренировкам, вес - в единицах unit.
+// У упражнений со своим весом нагрузка - свой вес плюс отягощение (минус помощь).
+func GenerateProgressChart(exercises []*models.Exercise, exerciseName string, unit models.WeightUnit) ([]byte, error) {
 	if len(exercises) == 0 {
 		return nil, nil
 	}
 
+	// Если свой вес где-то известен, тренировки без него на график не попадают:
+	// отягощение без своего веса с полной нагрузкой не сравнить
+	withBodyweight := false
+	for _, ex := range exercises {
+		if ex.Bodyweight && ex.BodyweightKg != nil {
+			withBodyweight = true
+			break
+		}
+	}
+	yName := "Вес (" + unit.Label() + ")"
+	if withBodyweight {
+		yName = "Нагрузка со своим весом (" + unit.Label() + ")"
+	}
+
 	var xValues []time.Time
 	var yValues []float64
 
+	// По каждой тренировке берём самый тяжёлый рабочий подход
 	for i := len(exercises) - 1; i >= 0; i-- {
-		xValues = append(xValues, exercises[i].CreatedAt)
-		yValues = append(yValues, exercises[i].Weight)
+		ex := exercises[i]
+		top := ex.TopSet()
+		if top == nil || (withBodyweight && ex.Bodyweight && ex.BodyweightKg == nil) {
+			continue
+		}
+		xValues = append(xValues, ex.CreatedAt)
+		yValues = append(yValues, unit.FromKg(ex.Load(*top)))
+	}
+	if len(xValues) == 0 {
+		return nil, nil
 	}
 
 	graph := chart.Chart{
@@ -36,7 +61,7 @@ func GenerateProgressChart(exercises []*models.Exercise, exerciseName string) ([
 			ValueFormatter: chart.TimeValueFormatterWithFormat("02.01"),
 		},
 		YAxis: chart.YAxis{
-			Name: "Вес (кг)",
+			Name: yName,
 			Style: chart.Style{
 				FontSize: 10,
 			},
@@ -226,7 +226,7 @@ func (db *DB) GetClientAccess(telegramID int64, username string, activeOnly bool
 	return result, err
 }
 
-// GetTrainerClients возвращает всех клиентов тренера
+// GetTrainerClients возвращает всех клиентов тренера с числом завершённых тренировок
 func (db *DB) GetTrainerClients(trainerID int64) ([]*models.ClientWithInfo, error) {
 	type ClientQueryResult struct {
 		models.TrainerClient
@@ -239,7 +239,7 @@ func (db *DB) GetTrainerClients(trainerID int64) ([]*models.ClientWithInfo, erro
 	err := db.GORM.Table("trainer_clients tc").
 		Select("tc.*, COALESCE(u.full_name, '') as full_name, COUNT(w.id) as workout_count, MAX(w.date) as last_workout").
 		Joins("LEFT JOIN users u ON tc.telegram_id = u.telegram_id").
-		Joins("LEFT JOIN workouts w ON w.trainer_client_id = tc.id").
+		Joins("LEFT JOIN workouts w ON w.trainer_client_id = tc.id AND w.deleted_at IS NULL AND w.status = ?", models.WorkoutCompleted).
 		Where("tc.trainer_id = ?", trainerID).
 		Group("tc.id, tc.trainer_id, tc.username, tc.telegram_id, tc.is_active, tc.created_at, tc.deactivated_at, u.full_name").
 		Order("tc.is_active DESC, tc.created_at DESC").
@@ -0,0 +1,195 @@
+package database
+
+import (
+	"fitness-bot/internal/catalog"
+	"fitness-bot/internal/models"
+	"fmt"
+
+	"gorm.io/gorm/clause"
+)
+
+// CatalogMatch - результат поиска упражнения в справочнике
+type CatalogMatch struct {
+	Entry *models.CatalogExercise
+	Fuzzy bool // найдено по похожему названию, а не точно
+}
+
+// GetCatalogExercises возвращает общий справочник и упражнения организации (orgID может быть nil)
+func (db *DB) GetCatalogExercises(orgID *int64) ([]*models.CatalogExercise, error) {
+	var entries []*models.CatalogExercise
+	query := db.GORM.Preload("Aliases")
+	if orgID != nil {
+		query = query.Where("organization_id IS NULL OR organization_id = ?", *orgID)
+	} else {
+		query = query.Where("organization_id IS NULL")
+	}
+	err := query.Order("name").Find(&entries).Error
+	return entries, err
+}
+
+// GetCatalogExerciseByID возвращает упражнение справочника по ID
+func (db *DB) GetCatalogExerciseByID(id int64) (*models.CatalogExercise, error) {
+	var entry models.CatalogExercise
+	err := db.GORM.Preload("Aliases").First(&entry, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// FindCatalogExercise ищет упражнение по названию в общем справочнике и справочнике организации.
+// Упражнение организации важнее общего с тем же названием. nil - не найдено.
+func (db *DB) FindCatalogExercise(name string, orgID *int64) (*CatalogMatch, error) {
+	entries, err := db.GetCatalogExercises(orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Сначала ищем среди упражнений организации, затем в общем справочнике
+	var own, global []catalog.Entry
+	byID := make(map[int64]*models.CatalogExercise, len(entries))
+	for _, e := range entries {
+		byID[e.ID] = e
+		if e.OrganizationID != nil {
+			own = append(own, catalogEntry(e))
+		} else {
+			global = append(global, catalogEntry(e))
+		}
+	}
+
+	for _, scope := range [][]catalog.Entry{own, global} {
+		if id, fuzzy := catalog.Match(name, scope); id != 0 && !fuzzy {
+			return &CatalogMatch{Entry: byID[id]}, nil
+		}
+	}
+	if id, fuzzy := catalog.Match(name, append(own, global...)); id != 0 {
+		return &CatalogMatch{Entry: byID[id], Fuzzy: fuzzy}, nil
+	}
+	return nil, nil
+}
+
+// ResolveCatalogExercise находит упражнение справочника по названию, а если его нет -
+// добавляет новое в справочник организации. Без организации общий справочник не пополняется
+// (иначе в нём окажутся чужие опечатки) - тогда nil, и упражнение остаётся без привязки.
+func (db *DB) ResolveCatalogExercise(name string, orgID *int64, muscleGroup models.MuscleGroup) (*CatalogMatch, error) {
+	match, err := db.FindCatalogExercise(name, orgID)
+	if err != nil || match != nil || orgID == nil {
+		return match, err
+	}
+
+	normalized := catalog.Normalize(name)
+	if normalized == "" {
+		return nil, fmt.Errorf("empty exercise name")
+	}
+	entry := &models.CatalogExercise{
+		OrganizationID: orgID,
+		Name:           name,
+		NormalizedName: normalized,
+		MuscleGroup:    muscleGroup,
+	}
+	// Такое же упражнение могли добавить параллельно - тогда берём существующее
+	res := db.GORM.Clauses(clause.OnConflict{DoNothing: true}).Create(entry)
+	if res.Error != nil {
+		return nil, fmt.Errorf("create catalog exercise: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		entry = &models.CatalogExercise{}
+		err := db.GORM.Where("normalized_name = ? AND organization_id = ?", normalized, *orgID).First(entry).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &CatalogMatch{Entry: entry}, nil
+}
+
+// GetWorkoutOrganizationID возвращает организацию тренера, с которым записана тренировка.
+// nil - тренировка записана без тренера.
+func (db *DB) GetWorkoutOrganizationID(workout *models.Workout) (*int64, error) {
+	if workout.TrainerClientID == nil {
+		return nil, nil
+	}
+	var orgID int64
+	err := db.GORM.Table("trainer_clients tc").
+		Select("ot.organization_id").
+		Joins("JOIN organization_trainers ot ON ot.id = tc.trainer_id").
+		Where("tc.id = ?", *workout.TrainerClientID).
+		Scan(&orgID).Error
+	if err != nil {
+		return nil, err
+	}
+	if orgID == 0 {
+		return nil, nil
+	}
+	return &orgID, nil
+}
+
+// GetClientCatalogExercises возвращает упражнения справочника, которые клиент уже выполнял
+func (db *DB) GetClientCatalogExercises(telegramID int64) ([]*models.CatalogExercise, error) {
+	var entries []*models.CatalogExercise
+	err := db.GORM.
+		Preload("Aliases").
+		Where("id IN (?)", db.GORM.Table("exercises").
+			Select("exercises.catalog_id").
+			Joins("JOIN workouts ON workouts.id = exercises.workout_id AND workouts.deleted_at IS NULL").
+			Where("workouts.client_telegram_id = ? AND exercises.deleted_at IS NULL", telegramID)).
+		Order("name").
+		Find(&entries).Error
+	return entries, err
+}
+
+// GetPerformedCatalogExercises возвращает упражнения справочника из завершённых тренировок scope -
+// сначала те, что выполнялись чаще
+func (db *DB) GetPerformedCatalogExercises(scope models.WorkoutScope) ([]*models.CatalogExercise, error) {
+	performed := db.GORM.Table("exercises").
+		Select("exercises.catalog_id, COUNT(*) AS times").
+		Joins("JOIN workouts ON workouts.id = exercises.workout_id AND workouts.deleted_at IS NULL").
+		Scopes(inScope(scope)).
+		Where("exercises.deleted_at IS NULL AND exercises.catalog_id IS NOT NULL").
+		Group("exercises.catalog_id")
+
+	var entries []*models.CatalogExercise
+	err := db.GORM.Unscoped().
+		Joins("JOIN (?) performed ON performed.catalog_id = exercise_catalog.id", performed).
+		Order("performed.times DESC, exercise_catalog.name").
+		Find(&entries).Error
+	return entries, err
+}
+
+// FindClientCatalogExercises ищет по названию среди упражнений, которые клиент выполнял.
+// Возвращает найденное упражнение и ID всех упражнений справочника с тем же названием
+// (одноимённые упражнения разных организаций считаются одним).
+func (db *DB) FindClientCatalogExercises(telegramID int64, name string) (*CatalogMatch, []int64, error) {
+	entries, err := db.GetClientCatalogExercises(telegramID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	candidates := make([]catalog.Entry, 0, len(entries))
+	byID := make(map[int64]*models.CatalogExercise, len(entries))
+	for _, e := range entries {
+		byID[e.ID] = e
+		candidates = append(candidates, catalogEntry(e))
+	}
+	id, fuzzy := catalog.Match(name, candidates)
+	if id == 0 {
+		return nil, nil, nil
+	}
+
+	match := &CatalogMatch{Entry: byID[id], Fuzzy: fuzzy}
+	var ids []int64
+	for _, e := range entries {
+		if e.NormalizedName == match.Entry.NormalizedName {
+			ids = append(ids, e.ID)
+		}
+	}
+	return match, ids, nil
+}
+
+// catalogEntry готовит упражнение справочника к сопоставлению по названию и синонимам
+func catalogEntry(e *models.CatalogExercise) catalog.Entry {
+	entry := catalog.Entry{ID: e.ID, Names: []string{e.NormalizedName}}
+	for _, a := range e.Aliases {
+		entry.Names = append(entry.Names, a.NormalizedAlias)
+	}
+	return entry
+}
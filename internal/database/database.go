@@ -3,7 +3,9 @@ package database
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"gorm.io/driver/postgres"
@@ -46,11 +48,18 @@ func NewDB(ctx context.Context) (*DB, error) {
 		os.Getenv("DB_NAME"),
 	)
 
-	// Настройка логгера GORM
-	gormLogger := logger.Default.LogMode(logger.Info)
-	if os.Getenv("APP_ENV") == "production" {
-		gormLogger = logger.Default.LogMode(logger.Error)
+	// Логгер GORM пишет в общий slog: ошибки и медленные запросы всегда,
+	// все SQL-запросы - только при DB_LOG_SQL=true
+	logLevel := logger.Warn
+	if os.Getenv("DB_LOG_SQL") == "true" {
+		logLevel = logger.Info
 	}
+	gormLogger := logger.NewSlogLogger(slog.Default().With("component", "gorm"), logger.Config{
+		SlowThreshold:             slowQueryThreshold(),
+		LogLevel:                  logLevel,
+		IgnoreRecordNotFoundError: true,
+		ParameterizedQueries:      true,
+	})
 
 	// Подключение через GORM
 	gormDB, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
@@ -74,6 +83,26 @@ func NewDB(ctx context.Context) (*DB, error) {
 	}, nil
 }
 
+// slowQueryThreshold - порог медленного запроса из DB_SLOW_QUERY (по умолчанию 200ms)
+func slowQueryThreshold() time.Duration {
+	if value := os.Getenv("DB_SLOW_QUERY"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+		slog.Warn("Invalid DB_SLOW_QUERY, using default", "value", value)
+	}
+	return 200 * time.Millisecond
+}
+
+// WithContext возвращает DB, запросы GORM которой выполняются в ctx:
+// при его отмене они прерываются. Соединения общие с исходной DB.
+func (db *DB) WithContext(ctx context.Context) *DB {
+	return &DB{
+		Pool: db.Pool,
+		GORM: db.GORM.WithContext(ctx),
+	}
+}
+
 func (db *DB) Close() {
 	db.Pool.Close()
 
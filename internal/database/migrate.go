@@ -3,7 +3,7 @@ package database
 import (
 	"embed"
 	"fmt"
-	"log"
+	"log/slog"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
@@ -32,9 +32,9 @@ func RunMigrations(databaseURL string) error {
 	}
 
 	if err == migrate.ErrNoChange {
-		log.Println("Migrations: no changes")
+		slog.Info("Migrations: no changes")
 	} else {
-		log.Println("Migrations: applied successfully")
+		slog.Info("Migrations: applied successfully")
 	}
 
 	return nil
@@ -0,0 +1,2 @@
+-- 000002_user_states.down.sql
+DROP TABLE IF EXISTS user_states;
@@ -0,0 +1,11 @@
+-- 000002_user_states.up.sql
+-- Состояния диалогов пользователей (переживают перезапуск бота)
+
+CREATE TABLE IF NOT EXISTS user_states (
+    telegram_id BIGINT PRIMARY KEY,
+    state VARCHAR(100) NOT NULL,
+    data JSONB NOT NULL DEFAULT '{}',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_states_updated_at ON user_states(updated_at);
@@ -0,0 +1,22 @@
+-- 000003_exercise_sets.down.sql
+ALTER TABLE exercises
+    ADD COLUMN IF NOT EXISTS sets INTEGER,
+    ADD COLUMN IF NOT EXISTS reps INTEGER,
+    ADD COLUMN IF NOT EXISTS weight DECIMAL(10,2);
+
+-- Сворачиваем подходы обратно: число рабочих подходов, повторения и вес самого тяжёлого из них
+UPDATE exercises e
+SET sets = agg.sets, reps = agg.reps, weight = agg.weight
+FROM (
+    SELECT DISTINCT ON (exercise_id)
+        exercise_id,
+        COUNT(*) OVER (PARTITION BY exercise_id) AS sets,
+        reps,
+        weight
+    FROM exercise_sets
+    WHERE deleted_at IS NULL AND set_type <> 'warmup'
+    ORDER BY exercise_id, weight DESC, reps DESC
+) agg
+WHERE agg.exercise_id = e.id;
+
+DROP TABLE IF EXISTS exercise_sets;
@@ -0,0 +1,30 @@
+-- 000003_exercise_sets.up.sql
+-- Подходы упражнений: вместо одной строки «подходы × повторения × вес» на упражнение
+
+CREATE TABLE IF NOT EXISTS exercise_sets (
+    id SERIAL PRIMARY KEY,
+    exercise_id INTEGER NOT NULL REFERENCES exercises(id) ON DELETE CASCADE,
+    set_index INTEGER NOT NULL,
+    reps INTEGER NOT NULL,
+    weight DECIMAL(10,2) NOT NULL DEFAULT 0,
+    set_type VARCHAR(20) NOT NULL DEFAULT 'working',
+    rpe DECIMAL(3,1) CHECK (rpe IS NULL OR (rpe >= 1 AND rpe <= 10)),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_exercise_sets_exercise_id ON exercise_sets(exercise_id);
+CREATE INDEX IF NOT EXISTS idx_exercise_sets_deleted_at ON exercise_sets(deleted_at);
+
+-- Каждая старая запись превращается в `sets` одинаковых рабочих подходов
+INSERT INTO exercise_sets (exercise_id, set_index, reps, weight, set_type, created_at, updated_at, deleted_at)
+SELECT e.id, s.idx, COALESCE(e.reps, 0), COALESCE(e.weight, 0), 'working', e.created_at, e.updated_at, e.deleted_at
+FROM exercises e
+CROSS JOIN LATERAL generate_series(1, GREATEST(COALESCE(e.sets, 0), 1)) AS s(idx)
+WHERE COALESCE(e.sets, 0) > 0 OR COALESCE(e.reps, 0) > 0 OR COALESCE(e.weight, 0) > 0;
+
+ALTER TABLE exercises
+    DROP COLUMN IF EXISTS sets,
+    DROP COLUMN IF EXISTS reps,
+    DROP COLUMN IF EXISTS weight;
@@ -0,0 +1,5 @@
+-- 000004_exercise_catalog.down.sql
+DROP INDEX IF EXISTS idx_exercises_catalog_id;
+ALTER TABLE exercises DROP COLUMN IF EXISTS catalog_id;
+DROP TABLE IF EXISTS exercise_catalog_aliases;
+DROP TABLE IF EXISTS exercise_catalog;
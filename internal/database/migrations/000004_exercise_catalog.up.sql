@@ -0,0 +1,155 @@
+-- 000004_exercise_catalog.up.sql
+-- Справочник упражнений: общий и по организациям, с синонимами.
+-- Упражнения тренировок ссылаются на справочник, статистика считается по ссылке.
+
+CREATE TABLE IF NOT EXISTS exercise_catalog (
+    id SERIAL PRIMARY KEY,
+    organization_id INTEGER REFERENCES organizations(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    normalized_name VARCHAR(255) NOT NULL,
+    muscle_group VARCHAR(50),
+    equipment VARCHAR(100),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+-- Одно название на область видимости (общий справочник или организация)
+CREATE UNIQUE INDEX IF NOT EXISTS idx_exercise_catalog_scope_name
+    ON exercise_catalog (COALESCE(organization_id, 0), normalized_name)
+    WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_exercise_catalog_organization_id ON exercise_catalog(organization_id);
+CREATE INDEX IF NOT EXISTS idx_exercise_catalog_deleted_at ON exercise_catalog(deleted_at);
+
+CREATE TABLE IF NOT EXISTS exercise_catalog_aliases (
+    id SERIAL PRIMARY KEY,
+    catalog_id INTEGER NOT NULL REFERENCES exercise_catalog(id) ON DELETE CASCADE,
+    alias VARCHAR(255) NOT NULL,
+    normalized_alias VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (catalog_id, normalized_alias)
+);
+
+CREATE INDEX IF NOT EXISTS idx_exercise_catalog_aliases_normalized ON exercise_catalog_aliases(normalized_alias);
+
+ALTER TABLE exercises ADD COLUMN IF NOT EXISTS catalog_id INTEGER REFERENCES exercise_catalog(id);
+CREATE INDEX IF NOT EXISTS idx_exercises_catalog_id ON exercises(catalog_id);
+
+-- Та же нормализация, что catalog.Normalize: регистр, ё/е, пробелы, знаки по краям.
+-- Нужна только для заполнения данных ниже.
+CREATE OR REPLACE FUNCTION pg_temp.normalize_exercise_name(name TEXT) RETURNS TEXT AS $$
+    SELECT regexp_replace(
+        regexp_replace(replace(lower(name), 'ё', 'е'), '\s+', ' ', 'g'),
+        '^[[:punct:][:space:]]+|[[:punct:][:space:]]+$', '', 'g')
+$$ LANGUAGE SQL IMMUTABLE;
+
+-- Общий справочник
+INSERT INTO exercise_catalog (name, normalized_name, muscle_group, equipment)
+SELECT v.name, pg_temp.normalize_exercise_name(v.name), v.muscle_group, v.equipment
+FROM (VALUES
+    ('Жим лёжа', 'Грудь', 'Штанга'),
+    ('Жим гантелей лёжа', 'Грудь', 'Гантели'),
+    ('Жим на наклонной скамье', 'Грудь', 'Штанга'),
+    ('Разводка гантелей', 'Грудь', 'Гантели'),
+    ('Отжимания', 'Грудь', 'Собственный вес'),
+    ('Отжимания на брусьях', 'Трицепс', 'Собственный вес'),
+    ('Подтягивания', 'Спина', 'Собственный вес'),
+    ('Тяга штанги в наклоне', 'Спина', 'Штанга'),
+    ('Тяга верхнего блока', 'Спина', 'Тренажёр'),
+    ('Тяга горизонтального блока', 'Спина', 'Тренажёр'),
+    ('Становая тяга', 'Спина', 'Штанга'),
+    ('Приседания', 'Ноги', 'Штанга'),
+    ('Жим ногами', 'Ноги', 'Тренажёр'),
+    ('Выпады', 'Ноги', 'Гантели'),
+    ('Румынская тяга', 'Ноги', 'Штанга'),
+    ('Разгибания ног', 'Ноги', 'Тренажёр'),
+    ('Сгибания ног', 'Ноги', 'Тренажёр'),
+    ('Жим стоя', 'Плечи', 'Штанга'),
+    ('Жим гантелей сидя', 'Плечи', 'Гантели'),
+    ('Махи в стороны', 'Плечи', 'Гантели'),
+    ('Подъём штанги на бицепс', 'Бицепс', 'Штанга'),
+    ('Молотки', 'Бицепс', 'Гантели'),
+    ('Французский жим', 'Трицепс', 'Штанга'),
+    ('Разгибания на блоке', 'Трицепс', 'Тренажёр'),
+    ('Скручивания', 'Пресс', 'Собственный вес'),
+    ('Планка', 'Пресс', 'Собственный вес'),
+    ('Бег', 'Кардио', NULL),
+    ('Велотренажёр', 'Кардио', 'Тренажёр')
+) AS v(name, muscle_group, equipment)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO exercise_catalog_aliases (catalog_id, alias, normalized_alias)
+SELECT c.id, v.alias, pg_temp.normalize_exercise_name(v.alias)
+FROM (VALUES
+    ('Жим лёжа', 'Жим штанги лёжа'),
+    ('Жим лёжа', 'Жим лежа на горизонтальной скамье'),
+    ('Жим лёжа', 'Bench press'),
+    ('Жим на наклонной скамье', 'Жим на наклонной'),
+    ('Жим на наклонной скамье', 'Жим лёжа под углом'),
+    ('Разводка гантелей', 'Разведение гантелей лёжа'),
+    ('Отжимания на брусьях', 'Брусья'),
+    ('Подтягивания', 'Подтягивания на турнике'),
+    ('Подтягивания', 'Pull-ups'),
+    ('Тяга штанги в наклоне', 'Тяга в наклоне'),
+    ('Тяга верхнего блока', 'Вертикальная тяга'),
+    ('Тяга горизонтального блока', 'Горизонтальная тяга'),
+    ('Становая тяга', 'Становая'),
+    ('Становая тяга', 'Deadlift'),
+    ('Приседания', 'Присед'),
+    ('Приседания', 'Приседания со штангой'),
+    ('Приседания', 'Squat'),
+    ('Жим ногами', 'Жим ногами в тренажёре'),
+    ('Жим стоя', 'Армейский жим'),
+    ('Жим стоя', 'Жим штанги стоя'),
+    ('Махи в стороны', 'Разведение гантелей в стороны'),
+    ('Подъём штанги на бицепс', 'Бицепс со штангой'),
+    ('Подъём штанги на бицепс', 'Сгибания рук со штангой'),
+    ('Молотки', 'Молотковые сгибания'),
+    ('Разгибания на блоке', 'Разгибания рук на блоке'),
+    ('Скручивания', 'Пресс'),
+    ('Велотренажёр', 'Велосипед')
+) AS v(name, alias)
+JOIN exercise_catalog c ON c.organization_id IS NULL AND c.name = v.name
+ON CONFLICT DO NOTHING;
+
+-- Привязываем уже записанные упражнения к общему справочнику по названию и синонимам
+UPDATE exercises e
+SET catalog_id = c.id
+FROM exercise_catalog c
+WHERE e.catalog_id IS NULL
+  AND c.organization_id IS NULL
+  AND c.normalized_name = pg_temp.normalize_exercise_name(e.name);
+
+UPDATE exercises e
+SET catalog_id = a.catalog_id
+FROM exercise_catalog_aliases a
+JOIN exercise_catalog c ON c.id = a.catalog_id AND c.organization_id IS NULL
+WHERE e.catalog_id IS NULL
+  AND a.normalized_alias = pg_temp.normalize_exercise_name(e.name);
+
+-- Остальные названия становятся упражнениями организации тренера.
+-- Тренировки без тренера общий справочник не пополняют - их упражнения остаются без привязки.
+INSERT INTO exercise_catalog (organization_id, name, normalized_name, muscle_group)
+SELECT DISTINCT ON (ot.organization_id, pg_temp.normalize_exercise_name(e.name))
+    ot.organization_id, trim(e.name), pg_temp.normalize_exercise_name(e.name), w.muscle_group
+FROM exercises e
+JOIN workouts w ON w.id = e.workout_id
+LEFT JOIN trainer_clients tc ON tc.id = w.trainer_client_id
+LEFT JOIN organization_trainers ot ON ot.id = tc.trainer_id
+WHERE e.catalog_id IS NULL
+  AND ot.organization_id IS NOT NULL
+  AND pg_temp.normalize_exercise_name(e.name) <> ''
+ORDER BY ot.organization_id, pg_temp.normalize_exercise_name(e.name), e.created_at
+ON CONFLICT DO NOTHING;
+
+UPDATE exercises e
+SET catalog_id = c.id
+FROM workouts w
+LEFT JOIN trainer_clients tc ON tc.id = w.trainer_client_id
+LEFT JOIN organization_trainers ot ON ot.id = tc.trainer_id,
+     exercise_catalog c
+WHERE e.catalog_id IS NULL
+  AND w.id = e.workout_id
+  AND c.deleted_at IS NULL
+  AND c.organization_id = ot.organization_id
+  AND c.normalized_name = pg_temp.normalize_exercise_name(e.name);
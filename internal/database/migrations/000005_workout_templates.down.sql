@@ -0,0 +1,6 @@
+-- 000005_workout_templates.down.sql
+DROP INDEX IF EXISTS idx_workouts_template_id;
+ALTER TABLE workouts DROP COLUMN IF EXISTS template_id;
+DROP TABLE IF EXISTS workout_template_assignments;
+DROP TABLE IF EXISTS workout_template_exercises;
+DROP TABLE IF EXISTS workout_templates;
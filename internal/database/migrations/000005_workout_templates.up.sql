@@ -0,0 +1,52 @@
+-- 000005_workout_templates.up.sql
+-- Шаблоны тренировок организации и их назначение клиентам тренера
+
+CREATE TABLE IF NOT EXISTS workout_templates (
+    id SERIAL PRIMARY KEY,
+    organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    trainer_id INTEGER REFERENCES organization_trainers(id) ON DELETE SET NULL,
+    name VARCHAR(255) NOT NULL,
+    muscle_group VARCHAR(50) NOT NULL,
+    notes TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_workout_templates_organization_id ON workout_templates(organization_id);
+CREATE INDEX IF NOT EXISTS idx_workout_templates_deleted_at ON workout_templates(deleted_at);
+
+CREATE TABLE IF NOT EXISTS workout_template_exercises (
+    id SERIAL PRIMARY KEY,
+    template_id INTEGER NOT NULL REFERENCES workout_templates(id) ON DELETE CASCADE,
+    catalog_id INTEGER REFERENCES exercise_catalog(id),
+    name VARCHAR(255) NOT NULL,
+    "order" INTEGER NOT NULL,
+    target_sets INTEGER NOT NULL CHECK (target_sets > 0),
+    target_reps INTEGER NOT NULL CHECK (target_reps > 0),
+    target_weight DECIMAL(10,2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_workout_template_exercises_template_id ON workout_template_exercises(template_id);
+CREATE INDEX IF NOT EXISTS idx_workout_template_exercises_deleted_at ON workout_template_exercises(deleted_at);
+
+CREATE TABLE IF NOT EXISTS workout_template_assignments (
+    id SERIAL PRIMARY KEY,
+    template_id INTEGER NOT NULL REFERENCES workout_templates(id) ON DELETE CASCADE,
+    trainer_client_id INTEGER NOT NULL REFERENCES trainer_clients(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+-- Шаблон назначается клиенту один раз (снятое назначение можно вернуть)
+CREATE UNIQUE INDEX IF NOT EXISTS idx_workout_template_assignments_unique
+    ON workout_template_assignments (template_id, trainer_client_id)
+    WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_workout_template_assignments_trainer_client_id ON workout_template_assignments(trainer_client_id);
+
+-- Тренировка помнит шаблон, по которому её начали
+ALTER TABLE workouts ADD COLUMN IF NOT EXISTS template_id INTEGER REFERENCES workout_templates(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_workouts_template_id ON workouts(template_id);
@@ -0,0 +1,31 @@
+-- 000006_muscle_groups.down.sql
+-- Возвращаем одну строку muscle_group: группы тренировки через запятую
+ALTER TABLE workouts ADD COLUMN IF NOT EXISTS muscle_group VARCHAR(50) NOT NULL DEFAULT '';
+ALTER TABLE workout_templates ADD COLUMN IF NOT EXISTS muscle_group VARCHAR(50) NOT NULL DEFAULT '';
+
+UPDATE workouts w
+SET muscle_group = LEFT(g.names, 50)
+FROM (
+    SELECT wmg.workout_id, STRING_AGG(mg.name, ', ' ORDER BY mg.sort_order, mg.name) AS names
+    FROM workout_muscle_groups wmg
+    JOIN muscle_groups mg ON mg.id = wmg.muscle_group_id
+    GROUP BY wmg.workout_id
+) g
+WHERE g.workout_id = w.id;
+
+UPDATE workout_templates t
+SET muscle_group = LEFT(g.names, 50)
+FROM (
+    SELECT tmg.template_id, STRING_AGG(mg.name, ', ' ORDER BY mg.sort_order, mg.name) AS names
+    FROM workout_template_muscle_groups tmg
+    JOIN muscle_groups mg ON mg.id = tmg.muscle_group_id
+    GROUP BY tmg.template_id
+) g
+WHERE g.template_id = t.id;
+
+ALTER TABLE workouts ALTER COLUMN muscle_group DROP DEFAULT;
+ALTER TABLE workout_templates ALTER COLUMN muscle_group DROP DEFAULT;
+
+DROP TABLE IF EXISTS workout_template_muscle_groups;
+DROP TABLE IF EXISTS workout_muscle_groups;
+DROP TABLE IF EXISTS muscle_groups;
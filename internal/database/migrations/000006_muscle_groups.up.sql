@@ -0,0 +1,72 @@
+-- 000006_muscle_groups.up.sql
+-- Группы мышц: справочник (встроенные и группы организаций) и связь многие-ко-многим
+-- с тренировками и шаблонами вместо одной строки muscle_group
+
+CREATE TABLE IF NOT EXISTS muscle_groups (
+    id SERIAL PRIMARY KEY,
+    organization_id INTEGER REFERENCES organizations(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    emoji VARCHAR(16) NOT NULL DEFAULT '🔹',
+    sort_order INTEGER NOT NULL DEFAULT 100,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+-- Одно название на область видимости (встроенные или организация)
+CREATE UNIQUE INDEX IF NOT EXISTS idx_muscle_groups_scope_name
+    ON muscle_groups (COALESCE(organization_id, 0), LOWER(name))
+    WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_muscle_groups_organization_id ON muscle_groups(organization_id);
+CREATE INDEX IF NOT EXISTS idx_muscle_groups_deleted_at ON muscle_groups(deleted_at);
+
+INSERT INTO muscle_groups (name, emoji, sort_order) VALUES
+    ('Грудь', '💪', 1),
+    ('Спина', '🦾', 2),
+    ('Ноги', '🦵', 3),
+    ('Плечи', '🏋️', 4),
+    ('Бицепс', '💪', 5),
+    ('Трицепс', '💪', 6),
+    ('Пресс', '🎯', 7),
+    ('Кардио', '🏃', 8);
+
+-- Значения, которых нет среди встроенных, становятся встроенными группами
+INSERT INTO muscle_groups (name)
+SELECT DISTINCT ON (LOWER(src.muscle_group)) src.muscle_group
+FROM (
+    SELECT muscle_group FROM workouts
+    UNION
+    SELECT muscle_group FROM workout_templates
+) src
+WHERE src.muscle_group <> ''
+  AND NOT EXISTS (
+      SELECT 1 FROM muscle_groups mg
+      WHERE mg.organization_id IS NULL AND LOWER(mg.name) = LOWER(src.muscle_group)
+  );
+
+CREATE TABLE IF NOT EXISTS workout_muscle_groups (
+    workout_id INTEGER NOT NULL REFERENCES workouts(id) ON DELETE CASCADE,
+    muscle_group_id INTEGER NOT NULL REFERENCES muscle_groups(id) ON DELETE CASCADE,
+    PRIMARY KEY (workout_id, muscle_group_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_workout_muscle_groups_muscle_group_id ON workout_muscle_groups(muscle_group_id);
+
+CREATE TABLE IF NOT EXISTS workout_template_muscle_groups (
+    template_id INTEGER NOT NULL REFERENCES workout_templates(id) ON DELETE CASCADE,
+    muscle_group_id INTEGER NOT NULL REFERENCES muscle_groups(id) ON DELETE CASCADE,
+    PRIMARY KEY (template_id, muscle_group_id)
+);
+
+INSERT INTO workout_muscle_groups (workout_id, muscle_group_id)
+SELECT w.id, mg.id
+FROM workouts w
+JOIN muscle_groups mg ON mg.organization_id IS NULL AND LOWER(mg.name) = LOWER(w.muscle_group);
+
+INSERT INTO workout_template_muscle_groups (template_id, muscle_group_id)
+SELECT t.id, mg.id
+FROM workout_templates t
+JOIN muscle_groups mg ON mg.organization_id IS NULL AND LOWER(mg.name) = LOWER(t.muscle_group);
+
+ALTER TABLE workouts DROP COLUMN IF EXISTS muscle_group;
+ALTER TABLE workout_templates DROP COLUMN IF EXISTS muscle_group;
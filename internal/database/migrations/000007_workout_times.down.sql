@@ -0,0 +1,4 @@
+-- 000007_workout_times.down.sql
+
+ALTER TABLE workouts DROP COLUMN IF EXISTS finished_at;
+ALTER TABLE workouts DROP COLUMN IF EXISTS started_at;
@@ -0,0 +1,9 @@
+-- 000007_workout_times.up.sql
+-- Время начала и окончания тренировки: тренировку можно записать задним числом,
+-- а по «Завершить» фиксируется окончание
+
+ALTER TABLE workouts ADD COLUMN IF NOT EXISTS started_at TIMESTAMP;
+ALTER TABLE workouts ADD COLUMN IF NOT EXISTS finished_at TIMESTAMP;
+
+-- До этой миграции date всегда было временем создания тренировки
+UPDATE workouts SET started_at = date WHERE started_at IS NULL;
@@ -0,0 +1,3 @@
+-- 000008_rest_timer.down.sql
+
+ALTER TABLE organizations DROP COLUMN IF EXISTS default_rest_seconds;
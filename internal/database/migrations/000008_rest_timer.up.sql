@@ -0,0 +1,5 @@
+-- 000008_rest_timer.up.sql
+-- Отдых между подходами по умолчанию для организации (0 - таймер не запускается).
+-- Отдых конкретного упражнения хранится в exercises.rest_seconds
+
+ALTER TABLE organizations ADD COLUMN IF NOT EXISTS default_rest_seconds INTEGER NOT NULL DEFAULT 0;
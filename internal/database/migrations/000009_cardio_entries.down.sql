@@ -0,0 +1,3 @@
+-- 000009_cardio_entries.down.sql
+
+DROP TABLE IF EXISTS cardio_entries;
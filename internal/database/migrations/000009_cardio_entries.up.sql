@@ -0,0 +1,20 @@
+-- 000009_cardio_entries.up.sql
+-- Кардио-показатели упражнения: дистанция, время, пульс, набор высоты.
+-- У кардио-упражнения нет подходов - вместо них одна запись здесь
+
+CREATE TABLE IF NOT EXISTS cardio_entries (
+    id SERIAL PRIMARY KEY,
+    exercise_id INTEGER NOT NULL REFERENCES exercises(id) ON DELETE CASCADE,
+    distance_km DECIMAL(8,3) NOT NULL DEFAULT 0 CHECK (distance_km >= 0),
+    duration_seconds INTEGER NOT NULL DEFAULT 0 CHECK (duration_seconds >= 0),
+    avg_heart_rate INTEGER CHECK (avg_heart_rate IS NULL OR avg_heart_rate BETWEEN 30 AND 250),
+    max_heart_rate INTEGER CHECK (max_heart_rate IS NULL OR max_heart_rate BETWEEN 30 AND 250),
+    elevation_m INTEGER,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP,
+    CHECK (distance_km > 0 OR duration_seconds > 0)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cardio_entries_exercise_id ON cardio_entries(exercise_id);
+CREATE INDEX IF NOT EXISTS idx_cardio_entries_deleted_at ON cardio_entries(deleted_at);
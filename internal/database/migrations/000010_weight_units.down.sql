@@ -0,0 +1,9 @@
+-- 000010_weight_units.down.sql
+
+ALTER TABLE exercises
+    DROP COLUMN IF EXISTS bodyweight_kg,
+    DROP COLUMN IF EXISTS bodyweight;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS bodyweight_kg,
+    DROP COLUMN IF EXISTS weight_unit;
@@ -0,0 +1,19 @@
+-- 000010_weight_units.up.sql
+-- Единицы веса пользователя (вес в базе по-прежнему в кг) и свой вес
+-- для упражнений с собственным весом
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS weight_unit VARCHAR(2) NOT NULL DEFAULT 'kg' CHECK (weight_unit IN ('kg', 'lb')),
+    ADD COLUMN IF NOT EXISTS bodyweight_kg DECIMAL(5,2) CHECK (bodyweight_kg IS NULL OR bodyweight_kg > 0);
+
+-- У упражнений со своим весом вес подхода - отягощение (больше 0) или помощь (меньше 0),
+-- а bodyweight_kg - свой вес клиента на момент тренировки
+ALTER TABLE exercises
+    ADD COLUMN IF NOT EXISTS bodyweight BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS bodyweight_kg DECIMAL(5,2);
+
+-- Уже записанные подтягивания, брусья и отжимания - с собственным весом
+UPDATE exercises e
+SET bodyweight = TRUE
+FROM exercise_catalog c
+WHERE e.catalog_id = c.id AND c.equipment = 'Собственный вес';
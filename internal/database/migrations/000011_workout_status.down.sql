@@ -0,0 +1,4 @@
+-- 000011_workout_status.down.sql
+
+DROP INDEX IF EXISTS idx_workouts_status;
+ALTER TABLE workouts DROP COLUMN IF EXISTS status;
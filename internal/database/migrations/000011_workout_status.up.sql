@@ -0,0 +1,18 @@
+-- 000011_workout_status.up.sql
+-- Статус тренировки: черновик, пока добавляются упражнения, завершённая или отменённая.
+-- В историю, статистику и счётчики попадают только завершённые
+
+ALTER TABLE workouts ADD COLUMN IF NOT EXISTS status VARCHAR(10) NOT NULL DEFAULT 'completed'
+    CHECK (status IN ('draft', 'completed', 'cancelled'));
+-- Уже записанные тренировки считаются завершёнными, новые создаются черновиками
+ALTER TABLE workouts ALTER COLUMN status SET DEFAULT 'draft';
+
+CREATE INDEX IF NOT EXISTS idx_workouts_status ON workouts(status);
+
+-- Пустые тренировки, оставшиеся после отмены, больше не нужны
+-- (начатые в последние часы ещё могут заполняться)
+UPDATE workouts w
+SET status = 'cancelled', deleted_at = NOW()
+WHERE w.deleted_at IS NULL
+  AND w.created_at < NOW() - INTERVAL '6 hours'
+  AND NOT EXISTS (SELECT 1 FROM exercises e WHERE e.workout_id = w.id AND e.deleted_at IS NULL);
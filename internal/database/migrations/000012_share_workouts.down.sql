@@ -0,0 +1,4 @@
+-- 000012_share_workouts.down.sql
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS share_workouts;
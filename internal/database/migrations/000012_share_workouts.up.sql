@@ -0,0 +1,5 @@
+-- 000012_share_workouts.up.sql
+-- Клиент может показать тренерам и тренировки, записанные без тренера
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS share_workouts BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"fitness-bot/internal/models"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetMuscleGroups возвращает встроенные группы мышц и группы организации (orgID может быть nil)
+func (db *DB) GetMuscleGroups(orgID *int64) ([]*models.MuscleGroupRecord, error) {
+	var groups []*models.MuscleGroupRecord
+	err := muscleGroupScope(db.GORM, orgID).
+		Order("sort_order, name").
+		Find(&groups).Error
+	return groups, err
+}
+
+// GetMuscleGroupsByIDs возвращает выбранные группы мышц, доступные организации.
+// Чужие и удалённые группы пропускаются.
+func (db *DB) GetMuscleGroupsByIDs(ids []int64, orgID *int64) ([]models.MuscleGroupRecord, error) {
+	var groups []models.MuscleGroupRecord
+	if len(ids) == 0 {
+		return groups, nil
+	}
+	err := muscleGroupScope(db.GORM, orgID).
+		Where("id IN ?", ids).
+		Order("sort_order, name").
+		Find(&groups).Error
+	return groups, err
+}
+
+// GetMuscleGroupByID возвращает группу мышц по ID
+func (db *DB) GetMuscleGroupByID(id int64) (*models.MuscleGroupRecord, error) {
+	var group models.MuscleGroupRecord
+	err := db.GORM.First(&group, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// CreateMuscleGroup добавляет группу мышц организации.
+// false - группа с таким названием уже есть среди встроенных или в организации.
+func (db *DB) CreateMuscleGroup(group *models.MuscleGroupRecord) (bool, error) {
+	var count int64
+	err := muscleGroupScope(db.GORM.Model(&models.MuscleGroupRecord{}), group.OrganizationID).
+		Where("LOWER(name) = ?", strings.ToLower(string(group.Name))).
+		Count(&count).Error
+	if err != nil || count > 0 {
+		return false, err
+	}
+	res := db.GORM.Clauses(clause.OnConflict{DoNothing: true}).Create(group)
+	return res.RowsAffected > 0, res.Error
+}
+
+// DeleteMuscleGroup мягко удаляет группу мышц организации.
+// В записанных тренировках группа остаётся.
+func (db *DB) DeleteMuscleGroup(id int64) error {
+	return db.GORM.Delete(&models.MuscleGroupRecord{}, id).Error
+}
+
+// IsOrganizationManager проверяет, является ли пользователь активным менеджером организации
+func (db *DB) IsOrganizationManager(orgID, telegramID int64, username string) (bool, error) {
+	username = NormalizeUsername(username)
+	var count int64
+	err := db.GORM.Model(&models.OrganizationManager{}).
+		Where("organization_id = ? AND is_active = ?", orgID, true).
+		Where("telegram_id = ? OR username = ?", telegramID, username).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// muscleGroupScope ограничивает запрос встроенными группами и группами организации
+func muscleGroupScope(tx *gorm.DB, orgID *int64) *gorm.DB {
+	if orgID != nil {
+		return tx.Where("organization_id IS NULL OR organization_id = ?", *orgID)
+	}
+	return tx.Where("organization_id IS NULL")
+}
+
+// allMuscleGroups предзагружает группы тренировки, включая удалённые из справочника -
+// в истории тренировка сохраняет свои группы
+func allMuscleGroups(tx *gorm.DB) *gorm.DB {
+	return tx.Unscoped().Order("sort_order, name")
+}
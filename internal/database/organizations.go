@@ -35,3 +35,10 @@ func (db *DB) GetOrganizationByID(id int64) (*models.Organization, error) {
 	}
 	return &org, nil
 }
+
+// SetOrganizationRestSeconds задаёт отдых между подходами по умолчанию (0 - без таймера)
+func (db *DB) SetOrganizationRestSeconds(orgID int64, seconds int) error {
+	return db.GORM.Model(&models.Organization{}).
+		Where("id = ?", orgID).
+		Update("default_rest_seconds", seconds).Error
+}
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"errors"
+	"fitness-bot/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetUserStateRecord возвращает сохранённое состояние диалога (nil, если его нет)
+func (db *DB) GetUserStateRecord(telegramID int64) (*models.UserStateRecord, error) {
+	var record models.UserStateRecord
+	err := db.GORM.Where("telegram_id = ?", telegramID).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// SaveUserStateRecord создаёт или перезаписывает состояние диалога
+func (db *DB) SaveUserStateRecord(record *models.UserStateRecord) error {
+	return db.GORM.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "telegram_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"state", "data", "updated_at"}),
+	}).Create(record).Error
+}
+
+// DeleteUserStateRecord удаляет состояние диалога
+func (db *DB) DeleteUserStateRecord(telegramID int64) error {
+	return db.GORM.Where("telegram_id = ?", telegramID).Delete(&models.UserStateRecord{}).Error
+}
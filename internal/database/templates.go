@@ -0,0 +1,136 @@
+package database
+
+import (
+	"fitness-bot/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateTemplate создаёт шаблон тренировки вместе с его упражнениями и группами мышц
+func (db *DB) CreateTemplate(template *models.WorkoutTemplate) error {
+	return db.GORM.Omit("MuscleGroups.*").Create(template).Error
+}
+
+// AddTemplateExercise добавляет упражнение в конец шаблона
+func (db *DB) AddTemplateExercise(exercise *models.TemplateExercise) error {
+	return db.GORM.Transaction(func(tx *gorm.DB) error {
+		var last int
+		err := tx.Model(&models.TemplateExercise{}).
+			Where("template_id = ?", exercise.TemplateID).
+			Select("COALESCE(MAX(\"order\"), 0)").
+			Scan(&last).Error
+		if err != nil {
+			return err
+		}
+		exercise.Order = last + 1
+		return tx.Create(exercise).Error
+	})
+}
+
+// GetTemplateByID возвращает шаблон с упражнениями по порядку
+func (db *DB) GetTemplateByID(id int64) (*models.WorkoutTemplate, error) {
+	var template models.WorkoutTemplate
+	err := db.GORM.
+		Preload("Exercises", orderedTemplateExercises).
+		Preload("MuscleGroups", allMuscleGroups).
+		First(&template, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// GetOrganizationTemplates возвращает шаблоны организации
+func (db *DB) GetOrganizationTemplates(orgID int64) ([]*models.WorkoutTemplate, error) {
+	var templates []*models.WorkoutTemplate
+	err := db.GORM.
+		Preload("Exercises", orderedTemplateExercises).
+		Preload("MuscleGroups", allMuscleGroups).
+		Where("organization_id = ?", orgID).
+		Order("name").
+		Find(&templates).Error
+	return templates, err
+}
+
+// orderedTemplateExercises сортирует предзагруженные упражнения шаблона
+func orderedTemplateExercises(tx *gorm.DB) *gorm.DB {
+	return tx.Order("\"order\" ASC")
+}
+
+// DeleteTemplate мягко удаляет шаблон, его упражнения и назначения.
+// Тренировки, начатые по шаблону, остаются.
+func (db *DB) DeleteTemplate(id int64) error {
+	return db.GORM.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("template_id = ?", id).Delete(&models.TemplateAssignment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("template_id = ?", id).Delete(&models.TemplateExercise{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.WorkoutTemplate{}, id).Error
+	})
+}
+
+// GetTemplateTrainerID возвращает ID активного тренера пользователя в организации шаблона.
+// 0 - пользователь не может управлять шаблоном.
+func (db *DB) GetTemplateTrainerID(template *models.WorkoutTemplate, telegramID int64, username string) (int64, error) {
+	username = NormalizeUsername(username)
+	var trainers []models.OrganizationTrainer
+	err := db.GORM.
+		Where("organization_id = ? AND is_active = ?", template.OrganizationID, true).
+		Where("telegram_id = ? OR username = ?", telegramID, username).
+		Limit(1).
+		Find(&trainers).Error
+	if err != nil || len(trainers) == 0 {
+		return 0, err
+	}
+	return trainers[0].ID, nil
+}
+
+// AssignTemplate назначает шаблон клиенту (повторное назначение ничего не меняет)
+func (db *DB) AssignTemplate(templateID, trainerClientID int64) error {
+	return db.GORM.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.TemplateAssignment{
+		TemplateID:      templateID,
+		TrainerClientID: trainerClientID,
+	}).Error
+}
+
+// UnassignTemplate снимает назначение шаблона клиенту
+func (db *DB) UnassignTemplate(templateID, trainerClientID int64) error {
+	return db.GORM.
+		Where("template_id = ? AND trainer_client_id = ?", templateID, trainerClientID).
+		Delete(&models.TemplateAssignment{}).Error
+}
+
+// GetTemplateAssignees возвращает ID клиентов (trainer_clients.id), которым назначен шаблон
+func (db *DB) GetTemplateAssignees(templateID int64) ([]int64, error) {
+	var ids []int64
+	err := db.GORM.Model(&models.TemplateAssignment{}).
+		Where("template_id = ?", templateID).
+		Pluck("trainer_client_id", &ids).Error
+	return ids, err
+}
+
+// GetAssignedTemplates возвращает шаблоны, назначенные клиенту
+func (db *DB) GetAssignedTemplates(trainerClientID int64) ([]*models.WorkoutTemplate, error) {
+	var templates []*models.WorkoutTemplate
+	err := db.GORM.
+		Preload("Exercises", orderedTemplateExercises).
+		Preload("MuscleGroups", allMuscleGroups).
+		Where("id IN (?)", db.GORM.Model(&models.TemplateAssignment{}).
+			Select("template_id").
+			Where("trainer_client_id = ?", trainerClientID)).
+		Order("name").
+		Find(&templates).Error
+	return templates, err
+}
+
+// IsTemplateAssigned проверяет, назначен ли шаблон клиенту
+func (db *DB) IsTemplateAssigned(templateID, trainerClientID int64) (bool, error) {
+	var count int64
+	err := db.GORM.Model(&models.TemplateAssignment{}).
+		Where("template_id = ? AND trainer_client_id = ?", templateID, trainerClientID).
+		Count(&count).Error
+	return count > 0, err
+}
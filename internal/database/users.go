@@ -46,3 +46,24 @@ func (db *DB) GetUserByUsername(username string) (*models.User, error) {
 	}
 	return &user, nil
 }
+
+// SetWeightUnit сохраняет единицы веса пользователя
+func (db *DB) SetWeightUnit(telegramID int64, unit models.WeightUnit) error {
+	return db.GORM.Model(&models.User{}).
+		Where("telegram_id = ?", telegramID).
+		Update("weight_unit", unit).Error
+}
+
+// SetBodyweight сохраняет свой вес пользователя в кг
+func (db *DB) SetBodyweight(telegramID int64, kg float64) error {
+	return db.GORM.Model(&models.User{}).
+		Where("telegram_id = ?", telegramID).
+		Update("bodyweight_kg", kg).Error
+}
+
+// SetShareWorkouts включает или выключает показ тренерам тренировок без тренера
+func (db *DB) SetShareWorkouts(telegramID int64, share bool) error {
+	return db.GORM.Model(&models.User{}).
+		Where("telegram_id = ?", telegramID).
+		Update("share_workouts", share).Error
+}
@@ -2,47 +2,107 @@ package database
 
 import (
 	"fitness-bot/internal/models"
+	"fmt"
 	"time"
+
+	"gorm.io/gorm"
 )
 
-// CreateWorkout создаёт новую тренировку
+// CreateWorkout создаёт новую тренировку и связывает её с группами мышц из workout.MuscleGroups
 func (db *DB) CreateWorkout(workout *models.Workout) error {
-	return db.GORM.Create(workout).Error
+	return db.GORM.Omit("MuscleGroups.*").Create(workout).Error
 }
 
-// GetWorkoutsByClientTelegramID возвращает последние тренировки клиента
-func (db *DB) GetWorkoutsByClientTelegramID(telegramID int64, limit int) ([]*models.Workout, error) {
-	var workouts []*models.Workout
-	err := db.GORM.
-		Where("client_telegram_id = ?", telegramID).
-		Order("date DESC").
-		Limit(limit).
-		Find(&workouts).Error
-	return workouts, err
+// withMuscleGroup оставляет тренировки, в которые входит группа мышц с названием name
+// (встроенная или любой организации). name - строка или подзапрос, возвращающий название.
+func withMuscleGroup(db *gorm.DB, name interface{}) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("workouts.id IN (?)", db.Table("workout_muscle_groups wmg").
+			Select("wmg.workout_id").
+			Joins("JOIN muscle_groups mg ON mg.id = wmg.muscle_group_id").
+			Where("LOWER(mg.name) = LOWER(?)", name))
+	}
 }
 
-// GetWorkoutsByTrainerClient возвращает тренировки клиента через trainer_client_id
-func (db *DB) GetWorkoutsByTrainerClient(trainerClientID int64, limit int) ([]*models.Workout, error) {
+// inScope отбирает завершённые тренировки из scope
+func inScope(scope models.WorkoutScope) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Where("workouts.status = ?", models.WorkoutCompleted)
+		switch {
+		case scope.TrainerClientID == 0:
+			return tx.Where("workouts.client_telegram_id = ?", scope.ClientTelegramID)
+		case scope.Independent:
+			return tx.Where("workouts.trainer_client_id = ? OR (workouts.client_telegram_id = ? AND workouts.trainer_client_id IS NULL)",
+				scope.TrainerClientID, scope.ClientTelegramID)
+		default:
+			return tx.Where("workouts.trainer_client_id = ?", scope.TrainerClientID)
+		}
+	}
+}
+
+// GetWorkoutHistory возвращает страницу завершённых тренировок из scope по фильтру - от новых к старым,
+// сразу с группами мышц, упражнениями, подходами и кардио-показателями
+func (db *DB) GetWorkoutHistory(scope models.WorkoutScope, filter models.WorkoutFilter, offset, limit int) ([]*models.Workout, error) {
 	var workouts []*models.Workout
-	err := db.GORM.
-		Where("trainer_client_id = ?", trainerClientID).
-		Order("date DESC").
+	err := db.historyQuery(scope, filter).
+		Order("workouts.date DESC, workouts.id DESC").
+		Offset(offset).
 		Limit(limit).
 		Find(&workouts).Error
 	return workouts, err
 }
 
-// GetWorkoutsByMuscleGroup возвращает тренировки по группе мышц
-func (db *DB) GetWorkoutsByMuscleGroup(telegramID int64, muscleGroup models.MuscleGroup) ([]*models.Workout, error) {
+// GetFullWorkoutHistory возвращает все завершённые тренировки из scope по фильтру - от старых к новым,
+// с тем же содержимым, что и GetWorkoutHistory
+func (db *DB) GetFullWorkoutHistory(scope models.WorkoutScope, filter models.WorkoutFilter) ([]*models.Workout, error) {
 	var workouts []*models.Workout
-	err := db.GORM.
-		Where("client_telegram_id = ? AND muscle_group = ?", telegramID, muscleGroup).
-		Order("date DESC").
+	err := db.historyQuery(scope, filter).
+		Order("workouts.date ASC, workouts.id ASC").
 		Find(&workouts).Error
 	return workouts, err
 }
 
-// CreateExercise создаёт новое упражнение в тренировке
+// historyQuery - запрос тренировок истории с предзагрузкой, без сортировки и страниц
+func (db *DB) historyQuery(scope models.WorkoutScope, filter models.WorkoutFilter) *gorm.DB {
+	query := db.GORM.
+		Preload("MuscleGroups", allMuscleGroups).
+		Preload("Exercises", orderedExercises).
+		Preload("Exercises.Sets", orderedSets).
+		Preload("Exercises.Cardio").
+		Scopes(inScope(scope))
+	if filter.MuscleGroupID != 0 {
+		// Группу ищем по названию: у организаций могут быть свои группы с тем же названием
+		name := db.GORM.Model(&models.MuscleGroupRecord{}).Unscoped().Select("name").Where("id = ?", filter.MuscleGroupID)
+		query = query.Scopes(withMuscleGroup(db.GORM, gorm.Expr("(?)", name)))
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("workouts.date >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("workouts.date < ?", filter.To.AddDate(0, 0, 1))
+	}
+	if filter.TrainerClientID != 0 {
+		query = query.Where("workouts.trainer_client_id = ?", filter.TrainerClientID)
+	}
+	return query
+}
+
+// GetWorkoutHistoryMuscleGroups возвращает группы мышц завершённых тренировок из scope - по одной на название
+func (db *DB) GetWorkoutHistoryMuscleGroups(scope models.WorkoutScope) ([]*models.MuscleGroupRecord, error) {
+	var groups []*models.MuscleGroupRecord
+	err := db.GORM.Unscoped().
+		Where("id IN (?)", db.GORM.Table("workout_muscle_groups wmg").
+			Select("MIN(mg.id)").
+			Joins("JOIN muscle_groups mg ON mg.id = wmg.muscle_group_id").
+			Joins("JOIN workouts ON workouts.id = wmg.workout_id AND workouts.deleted_at IS NULL").
+			Scopes(inScope(scope)).
+			Group("LOWER(mg.name)")).
+		Order("sort_order, name").
+		Find(&groups).Error
+	return groups, err
+}
+
+// CreateExercise создаёт новое упражнение в тренировке вместе с его подходами
 func (db *DB) CreateExercise(exercise *models.Exercise) error {
 	return db.GORM.Create(exercise).Error
 }
@@ -51,30 +111,306 @@ func (db *DB) CreateExercise(exercise *models.Exercise) error {
 func (db *DB) GetExercisesByWorkout(workoutID int64) ([]*models.Exercise, error) {
 	var exercises []*models.Exercise
 	err := db.GORM.
+		Preload("Sets", orderedSets).
+		Preload("Cardio").
 		Where("workout_id = ?", workoutID).
 		Order("\"order\" ASC").
 		Find(&exercises).Error
 	return exercises, err
 }
 
-// GetExerciseStats возвращает статистику упражнения за период
-func (db *DB) GetExerciseStats(telegramID int64, exerciseName string, from, to time.Time) ([]*models.Exercise, error) {
+// GetExerciseStats возвращает выполнения упражнений справочника catalogIDs
+// в завершённых тренировках из scope за период
+func (db *DB) GetExerciseStats(scope models.WorkoutScope, catalogIDs []int64, from, to time.Time) ([]*models.Exercise, error) {
 	var exercises []*models.Exercise
 	err := db.GORM.
-		Joins("JOIN workouts ON exercises.workout_id = workouts.id").
-		Where("workouts.client_telegram_id = ? AND exercises.name = ? AND workouts.date BETWEEN ? AND ?",
-			telegramID, exerciseName, from, to).
+		Preload("Sets", orderedSets).
+		Preload("Cardio").
+		Joins("JOIN workouts ON exercises.workout_id = workouts.id AND workouts.deleted_at IS NULL").
+		Scopes(inScope(scope)).
+		Where("exercises.catalog_id IN ? AND workouts.date BETWEEN ? AND ?", catalogIDs, from, to).
 		Order("workouts.date DESC").
 		Find(&exercises).Error
 	return exercises, err
 }
 
+// GetPreviousExercises возвращает для каждого упражнения справочника catalogIDs его последнее
+// выполнение в тренировках из scope раньше before, не считая тренировку exceptWorkoutID
+func (db *DB) GetPreviousExercises(scope models.WorkoutScope, catalogIDs []int64, before time.Time, exceptWorkoutID int64) (map[int64]*models.Exercise, error) {
+	if len(catalogIDs) == 0 {
+		return nil, nil
+	}
+	var exercises []*models.Exercise
+	err := db.GORM.
+		Preload("Sets", orderedSets).
+		Preload("Cardio").
+		Select("DISTINCT ON (exercises.catalog_id) exercises.*").
+		Joins("JOIN workouts ON exercises.workout_id = workouts.id AND workouts.deleted_at IS NULL").
+		Scopes(inScope(scope)).
+		Where("exercises.catalog_id IN ? AND workouts.date < ? AND workouts.id <> ?", catalogIDs, before, exceptWorkoutID).
+		Order("exercises.catalog_id, workouts.date DESC, exercises.\"order\" DESC").
+		Find(&exercises).Error
+	if err != nil {
+		return nil, err
+	}
+	previous := make(map[int64]*models.Exercise, len(exercises))
+	for _, ex := range exercises {
+		previous[*ex.CatalogID] = ex
+	}
+	return previous, nil
+}
+
 // GetWorkoutByID возвращает тренировку по ID
 func (db *DB) GetWorkoutByID(id int64) (*models.Workout, error) {
 	var workout models.Workout
-	err := db.GORM.First(&workout, id).Error
+	err := db.GORM.Preload("MuscleGroups", allMuscleGroups).First(&workout, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &workout, nil
 }
+
+// GetExerciseByID возвращает упражнение по ID
+func (db *DB) GetExerciseByID(id int64) (*models.Exercise, error) {
+	var exercise models.Exercise
+	err := db.GORM.Preload("Sets", orderedSets).Preload("Cardio").First(&exercise, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &exercise, nil
+}
+
+// orderedExercises сортирует предзагруженные упражнения в порядке выполнения
+func orderedExercises(tx *gorm.DB) *gorm.DB {
+	return tx.Order("\"order\" ASC")
+}
+
+// orderedSets сортирует предзагруженные подходы по номеру
+func orderedSets(tx *gorm.DB) *gorm.DB {
+	return tx.Order("set_index ASC")
+}
+
+// FinishWorkout завершает черновик тренировки и, если задано, записывает время окончания.
+// Черновик без упражнений не сохраняется, а отменяется. Возвращает итоговый статус тренировки.
+func (db *DB) FinishWorkout(workoutID int64, finishedAt *time.Time) (models.WorkoutStatus, error) {
+	status := models.WorkoutCompleted
+	err := db.GORM.Transaction(func(tx *gorm.DB) error {
+		var workout models.Workout
+		if err := tx.First(&workout, workoutID).Error; err != nil {
+			return err
+		}
+		if workout.Status != models.WorkoutDraft {
+			status = workout.Status
+			return nil
+		}
+
+		var exercises int64
+		if err := tx.Model(&models.Exercise{}).Where("workout_id = ?", workoutID).Count(&exercises).Error; err != nil {
+			return err
+		}
+		if exercises == 0 {
+			status = models.WorkoutCancelled
+			return cancelWorkout(tx, workoutID)
+		}
+
+		updates := map[string]interface{}{"status": models.WorkoutCompleted}
+		if finishedAt != nil && workout.FinishedAt == nil {
+			updates["finished_at"] = *finishedAt
+		}
+		return tx.Model(&workout).Updates(updates).Error
+	})
+	return status, err
+}
+
+// CancelWorkout отменяет черновик тренировки: он удаляется вместе с уже добавленными упражнениями.
+// Завершённые тренировки не трогает.
+func (db *DB) CancelWorkout(workoutID int64) error {
+	return db.GORM.Transaction(func(tx *gorm.DB) error {
+		var workout models.Workout
+		if err := tx.First(&workout, workoutID).Error; err != nil {
+			return err
+		}
+		if workout.Status != models.WorkoutDraft {
+			return nil
+		}
+		return cancelWorkout(tx, workoutID)
+	})
+}
+
+// cancelWorkout помечает тренировку отменённой и мягко удаляет её
+func cancelWorkout(tx *gorm.DB, workoutID int64) error {
+	if err := tx.Model(&models.Workout{}).Where("id = ?", workoutID).Update("status", models.WorkoutCancelled).Error; err != nil {
+		return err
+	}
+	return deleteWorkout(tx, workoutID)
+}
+
+// SweepDraftWorkouts закрывает брошенные черновики - те, в которые с staleBefore ничего не добавляли:
+// черновики с упражнениями завершаются (время окончания остаётся неизвестным), пустые - отменяются.
+func (db *DB) SweepDraftWorkouts(staleBefore time.Time) (completed, cancelled int64, err error) {
+	const hasExercises = "EXISTS (SELECT 1 FROM exercises e WHERE e.workout_id = workouts.id AND e.deleted_at IS NULL)"
+	const lastActivity = "COALESCE((SELECT MAX(e.created_at) FROM exercises e WHERE e.workout_id = workouts.id AND e.deleted_at IS NULL), workouts.created_at)"
+
+	res := db.GORM.Model(&models.Workout{}).
+		Where("status = ? AND "+hasExercises+" AND "+lastActivity+" < ?", models.WorkoutDraft, staleBefore).
+		Update("status", models.WorkoutCompleted)
+	if res.Error != nil {
+		return 0, 0, res.Error
+	}
+	completed = res.RowsAffected
+
+	res = db.GORM.Model(&models.Workout{}).
+		Where("status = ? AND NOT "+hasExercises+" AND created_at < ?", models.WorkoutDraft, staleBefore).
+		Updates(map[string]interface{}{"status": models.WorkoutCancelled, "deleted_at": time.Now()})
+	if res.Error != nil {
+		return completed, 0, res.Error
+	}
+	return completed, res.RowsAffected, nil
+}
+
+// UpdateExercise сохраняет изменённое название упражнения, его ссылку на справочник
+// и отметку упражнения со своим весом
+func (db *DB) UpdateExercise(exercise *models.Exercise) error {
+	return db.GORM.Model(exercise).
+		Select("name", "catalog_id", "bodyweight", "bodyweight_kg").
+		Updates(exercise).Error
+}
+
+// ReplaceExerciseSets заменяет подходы упражнения новыми (старые удаляются мягко)
+func (db *DB) ReplaceExerciseSets(exerciseID int64, sets []models.ExerciseSet) error {
+	return db.GORM.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("exercise_id = ?", exerciseID).Delete(&models.ExerciseSet{}).Error; err != nil {
+			return err
+		}
+		if len(sets) == 0 {
+			return nil
+		}
+		for i := range sets {
+			sets[i].ID = 0
+			sets[i].ExerciseID = exerciseID
+		}
+		return tx.Create(&sets).Error
+	})
+}
+
+// UpdateCardio сохраняет новые кардио-показатели упражнения
+func (db *DB) UpdateCardio(exerciseID int64, cardio *models.CardioEntry) error {
+	return db.GORM.Model(&models.CardioEntry{}).
+		Where("exercise_id = ?", exerciseID).
+		Select("distance_km", "duration_seconds", "avg_heart_rate", "max_heart_rate", "elevation_m").
+		Updates(cardio).Error
+}
+
+// MoveExercise ставит упражнение на позицию position (с 1) и перенумеровывает остальные
+func (db *DB) MoveExercise(exerciseID int64, position int) error {
+	return db.GORM.Transaction(func(tx *gorm.DB) error {
+		var exercise models.Exercise
+		if err := tx.First(&exercise, exerciseID).Error; err != nil {
+			return err
+		}
+
+		var exercises []*models.Exercise
+		err := tx.Where("workout_id = ? AND id <> ?", exercise.WorkoutID, exercise.ID).
+			Order("\"order\" ASC, id ASC").
+			Find(&exercises).Error
+		if err != nil {
+			return err
+		}
+
+		if position < 1 {
+			position = 1
+		}
+		if position > len(exercises)+1 {
+			position = len(exercises) + 1
+		}
+		ordered := make([]*models.Exercise, 0, len(exercises)+1)
+		ordered = append(ordered, exercises[:position-1]...)
+		ordered = append(ordered, &exercise)
+		ordered = append(ordered, exercises[position-1:]...)
+
+		for i, ex := range ordered {
+			if ex.Order == i+1 {
+				continue
+			}
+			if err := tx.Model(ex).Update("order", i+1).Error; err != nil {
+				return fmt.Errorf("renumber exercise %d: %w", ex.ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteExercise мягко удаляет упражнение вместе с подходами и кардио-показателями
+func (db *DB) DeleteExercise(id int64) error {
+	return db.GORM.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("exercise_id = ?", id).Delete(&models.ExerciseSet{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("exercise_id = ?", id).Delete(&models.CardioEntry{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Exercise{}, id).Error
+	})
+}
+
+// DeleteWorkout мягко удаляет тренировку вместе с её упражнениями, подходами и кардио-показателями
+func (db *DB) DeleteWorkout(id int64) error {
+	return db.GORM.Transaction(func(tx *gorm.DB) error {
+		return deleteWorkout(tx, id)
+	})
+}
+
+func deleteWorkout(tx *gorm.DB, id int64) error {
+	exerciseIDs := tx.Model(&models.Exercise{}).Select("id").Where("workout_id = ?", id)
+	if err := tx.Where("exercise_id IN (?)", exerciseIDs).Delete(&models.ExerciseSet{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("exercise_id IN (?)", exerciseIDs).Delete(&models.CardioEntry{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("workout_id = ?", id).Delete(&models.Exercise{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&models.Workout{}, id).Error
+}
+
+// CanEditWorkout проверяет, может ли пользователь менять тренировку:
+// это её владелец или активный тренер, к которому привязан клиент.
+// Тренировки архивной (деактивированной) связи тренер-клиент - только для просмотра.
+func (db *DB) CanEditWorkout(workout *models.Workout, telegramID int64, username string) (bool, error) {
+	if workout.TrainerClientID == nil {
+		return workout.ClientTelegramID == telegramID, nil
+	}
+	active, err := db.IsActiveTrainerClient(*workout.TrainerClientID)
+	if err != nil || !active {
+		return false, err
+	}
+	if workout.ClientTelegramID == telegramID {
+		return true, nil
+	}
+	return db.IsClientTrainer(*workout.TrainerClientID, telegramID, username)
+}
+
+// IsActiveTrainerClient проверяет, что связь тренер-клиент не деактивирована и не удалена
+func (db *DB) IsActiveTrainerClient(trainerClientID int64) (bool, error) {
+	var count int64
+	err := db.GORM.Model(&models.TrainerClient{}).
+		Where("id = ? AND is_active", trainerClientID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// IsClientTrainer проверяет, что пользователь - действующий тренер связи тренер-клиент trainerClientID
+func (db *DB) IsClientTrainer(trainerClientID, telegramID int64, username string) (bool, error) {
+	username = NormalizeUsername(username)
+	var count int64
+	err := db.GORM.Table("trainer_clients tc").
+		Joins("JOIN organization_trainers ot ON ot.id = tc.trainer_id").
+		Where("tc.id = ? AND tc.deleted_at IS NULL AND ot.is_active AND ot.deleted_at IS NULL", trainerClientID).
+		Where("ot.telegram_id = ? OR ot.username = ?", telegramID, username).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"fmt"
-	"log"
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -45,12 +44,6 @@ func HandleCreateOrganization(b *bot.Bot, message *tgbotapi.Message) {
 
 // HandleCreateOrganizationName — ввод названия
 func HandleCreateOrganizationName(b *bot.Bot, message *tgbotapi.Message) {
-	if message.Text == "❌ Отмена" {
-		b.ClearState(message.From.ID)
-		HandleAdminMenu(b, message)
-		return
-	}
-
 	orgName := strings.TrimSpace(message.Text)
 	if orgName == "" {
 		b.SendWithCancel(message.Chat.ID, "Название не может быть пустым. Попробуйте ещё раз:")
@@ -67,12 +60,6 @@ func HandleCreateOrganizationName(b *bot.Bot, message *tgbotapi.Message) {
 func HandleCreateOrganizationCode(b *bot.Bot, message *tgbotapi.Message) {
 	state := b.GetState(message.From.ID)
 
-	if message.Text == "❌ Отмена" {
-		b.ClearState(message.From.ID)
-		HandleAdminMenu(b, message)
-		return
-	}
-
 	orgCode := strings.ToUpper(strings.TrimSpace(message.Text))
 	if orgCode == "" {
 		b.SendWithCancel(message.Chat.ID, "Код не может быть пустым. Введите заново:")
@@ -86,7 +73,6 @@ func HandleCreateOrganizationCode(b *bot.Bot, message *tgbotapi.Message) {
 		HandleAdminMenu(b, message)
 		return
 	}
-	escapedName := bot.EscapeMarkdown(orgName)
 
 	org := &models.Organization{
 		Name: orgName,
@@ -98,14 +84,14 @@ func HandleCreateOrganizationCode(b *bot.Bot, message *tgbotapi.Message) {
 			b.SendWithCancel(message.Chat.ID, "Организация с таким кодом уже существует. Введите другой код:")
 			return
 		}
-		log.Printf("Error creating organization (admin: %s): %v", message.From.UserName, err)
+		b.Log(message.From.ID).Error("Error creating organization", "error", err)
 		b.SendError(message.Chat.ID, "Ошибка при создания организации.")
 		return
 	}
 
 	b.ClearState(message.From.ID)
-	b.SendSuccess(message.Chat.ID,
-		fmt.Sprintf("Организация *%s* (код: `%s`) успешно создана!", escapedName, orgCode),
+	b.SendText(message.Chat.ID,
+		bot.NewText(bot.Plain("✅ Организация "), bot.Bold(orgName), bot.Plain(" (код: "), bot.Code(orgCode), bot.Plain(") успешно создана!")),
 		bot.GetAdminMenuKeyboard())
 }
 
@@ -118,7 +104,7 @@ func HandleListOrganizations(b *bot.Bot, message *tgbotapi.Message) {
 
 	orgs, err := b.DB.GetAllOrganizations()
 	if err != nil {
-		log.Printf("Error getting organizations: %v", err)
+		b.Log(message.From.ID).Error("Error getting organizations", "error", err)
 		b.SendError(message.Chat.ID, "Ошибка при получении списка организаций.")
 		return
 	}
@@ -153,19 +139,23 @@ func HandleSelectOrganization(b *bot.Bot, message *tgbotapi.Message, idx int) {
 	}
 
 	org := orgs[idx-1]
+	ShowAdminOrgMenu(b, message, org.ID, org.Name)
+}
 
+// ShowAdminOrgMenu показывает управление организацией (админ)
+func ShowAdminOrgMenu(b *bot.Bot, message *tgbotapi.Message, orgID int64, orgName string) {
 	// Очищаем старые сообщения
 	b.CleanupMessages(message.Chat.ID, message.From.ID)
 
 	b.SetState(message.From.ID, "admin_managing_org", map[string]interface{}{
-		"org_id":   org.ID,
-		"org_name": org.Name,
+		"org_id":   orgID,
+		"org_name": orgName,
 	})
 
-	breadcrumbs := bot.GetBreadcrumbs("🏠 Главная", "⚙️ Админ", "🏢 "+org.Name)
-	text := breadcrumbs + "Выберите действие:"
+	breadcrumbs := bot.GetBreadcrumbs("🏠 Главная", "⚙️ Админ", "🏢 "+orgName)
+	text := bot.NewText(bot.Plain(breadcrumbs + "Выберите действие:"))
 
-	b.SendMessageWithKeyboard(
+	b.SendText(
 		message.Chat.ID,
 		text,
 		bot.GetOrgManageKeyboard(),
@@ -207,16 +197,6 @@ func HandleAddManagerUsername(b *bot.Bot, message *tgbotapi.Message) {
 		return
 	}
 
-	if message.Text == "❌ Отмена" {
-		b.SetState(message.From.ID, "admin_managing_org", bot.CopyStateData(state.Data))
-		b.SendMessageWithKeyboard(
-			message.Chat.ID,
-			fmt.Sprintf("Управление организацией *%s*", bot.EscapeMarkdown(orgName)),
-			bot.GetOrgManageKeyboard(),
-		)
-		return
-	}
-
 	username := database.NormalizeUsername(message.Text)
 	if username == "" {
 		b.SendWithCancel(message.Chat.ID, "Некорректный username. Введите в формате @username:")
@@ -224,14 +204,18 @@ func HandleAddManagerUsername(b *bot.Bot, message *tgbotapi.Message) {
 	}
 
 	if err := b.DB.AddManager( orgID, username); err != nil {
-		log.Printf("Error adding manager @%s (admin: %s): %v", username, message.From.UserName, err)
+		b.Log(message.From.ID).Error("Error adding manager", "manager", username, "error", err)
 
 		// Более понятные ошибки
 		errStr := err.Error()
 		if strings.Contains(errStr, "not found") {
-			b.SendWithCancel(message.Chat.ID, fmt.Sprintf("Пользователь @%s не найден в системе. Пусть сначала запустит бота.", username))
+			b.SendText(message.Chat.ID,
+				bot.NewText(bot.Plain("Пользователь "), bot.Mention(username), bot.Plain(" не найден в системе. Пусть сначала запустит бота.")),
+				bot.GetCancelKeyboard())
 		} else if strings.Contains(errStr, "already") {
-			b.SendWithCancel(message.Chat.ID, fmt.Sprintf("Пользователь @%s уже является менеджером этой организации.", username))
+			b.SendText(message.Chat.ID,
+				bot.NewText(bot.Plain("Пользователь "), bot.Mention(username), bot.Plain(" уже является менеджером этой организации.")),
+				bot.GetCancelKeyboard())
 		} else {
 			b.SendError(message.Chat.ID, "Ошибка при добавлении менеджера.")
 		}
@@ -243,8 +227,8 @@ func HandleAddManagerUsername(b *bot.Bot, message *tgbotapi.Message) {
 		"org_name": orgName,
 	})
 
-	b.SendSuccess(message.Chat.ID,
-		fmt.Sprintf("Менеджер @%s добавлен в организацию *%s*", username, bot.EscapeMarkdown(orgName)),
+	b.SendText(message.Chat.ID,
+		bot.NewText(bot.Plain("✅ Менеджер "), bot.Mention(username), bot.Plain(" добавлен в организацию "), bot.Bold(orgName)),
 		bot.GetOrgManageKeyboard())
 }
 
@@ -266,18 +250,17 @@ func HandleListManagers(b *bot.Bot, message *tgbotapi.Message) {
 
 	managers, err := b.DB.GetOrganizationManagers( orgID)
 	if err != nil {
-		log.Printf("Error getting managers (org %d): %v", orgID, err)
+		b.Log(message.From.ID).Error("Error getting managers", "org_id", orgID, "error", err)
 		b.SendError(message.Chat.ID, "Ошибка при получении списка менеджеров.")
 		return
 	}
 
 	if len(managers) == 0 {
-		b.SendMessage(message.Chat.ID, fmt.Sprintf("В организации *%s* пока нет менеджеров.", bot.EscapeMarkdown(orgName)))
+		b.SendText(message.Chat.ID, bot.NewText(bot.Plain("В организации "), bot.Bold(orgName), bot.Plain(" пока нет менеджеров.")), nil)
 		return
 	}
 
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("*Менеджеры организации %s:*\n\n", bot.EscapeMarkdown(orgName)))
+	text := bot.NewText(bot.Bold("Менеджеры организации "+orgName+":"), bot.Plain("\n\n"))
 
 	// Создаём inline-клавиатуру
 	var items []string
@@ -291,10 +274,10 @@ func HandleListManagers(b *bot.Bot, message *tgbotapi.Message) {
 		ids = append(ids, m.ID)
 	}
 
-	sb.WriteString("Выберите менеджера для удаления:")
+	text = text.Add(bot.Plain("Выберите менеджера для удаления:"))
 
 	keyboard := bot.GetInlineListKeyboard(items, ids, "manager")
-	b.SendInlineKeyboard(message.Chat.ID, sb.String(), keyboard)
+	b.SendInlineText(message.Chat.ID, text, keyboard)
 
 	newData := bot.CopyStateData(state.Data)
 	newData["managers"] = managers
@@ -324,7 +307,7 @@ func HandleRemoveManager(b *bot.Bot, message *tgbotapi.Message, idx int) {
 	manager := managers[idx-1]
 
 	if err := b.DB.RemoveManager( orgID, manager.Username); err != nil {
-		log.Printf("Error removing manager @%s: %v", manager.Username, err)
+		b.Log(message.From.ID).Error("Error removing manager", "manager", manager.Username, "error", err)
 		b.SendError(message.Chat.ID, "Ошибка при удалении менеджера.")
 		return
 	}
@@ -334,7 +317,7 @@ func HandleRemoveManager(b *bot.Bot, message *tgbotapi.Message, idx int) {
 		"org_name": orgName,
 	})
 
-	b.SendSuccess(message.Chat.ID,
-		fmt.Sprintf("Менеджер @%s удалён из организации *%s*", manager.Username, bot.EscapeMarkdown(orgName)),
+	b.SendText(message.Chat.ID,
+		bot.NewText(bot.Plain("✅ Менеджер "), bot.Mention(manager.Username), bot.Plain(" удалён из организации "), bot.Bold(orgName)),
 		bot.GetOrgManageKeyboard())
 }
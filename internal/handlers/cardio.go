@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"fitness-bot/internal/bot"
+	"fitness-bot/internal/charts"
+	"fitness-bot/internal/models"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// CardioInputHelp - подсказка по формату ввода кардио (Markdown)
+const CardioInputHelp = "Кардио - название, дистанция и время, по упражнению в строке:\n" +
+	"```\nБег 5км 28:30\nВелосипед 20км 45 мин пульс 140/165\nХодьба в гору 3км 40 мин набор 250м\n```\n" +
+	"Время - 28:30, 1:05:00 или 45 мин; пульс - средний/максимальный. Темп и скорость посчитаются сами."
+
+// exerciseInputHelp - подсказка для тренировки: кардио показываем, если среди групп мышц есть «Кардио»
+func exerciseInputHelp(groups []models.MuscleGroupRecord) string {
+	for _, g := range groups {
+		if g.Name == models.MuscleCardio {
+			return ExerciseInputHelp + "\n\n" + CardioInputHelp
+		}
+	}
+	return ExerciseInputHelp
+}
+
+// formatExerciseResult - результат упражнения одной строкой: кардио-показатели или подходы.
+// У упражнений со своим весом добавляется свой вес на момент тренировки.
+func formatExerciseResult(ex *models.Exercise, unit models.WeightUnit) string {
+	if ex.Cardio != nil {
+		return formatCardio(ex.Cardio)
+	}
+	s := formatSets(ex.Sets, exerciseFormat(ex, unit))
+	if ex.Bodyweight && ex.BodyweightKg != nil {
+		s += ", свой вес " + formatWeight(*ex.BodyweightKg, unit)
+	}
+	return s
+}
+
+// formatCardio форматирует кардио: «5 км за 28:30, темп 5:42/км, 10.5 км/ч, пульс 150/175, набор 120 м»
+func formatCardio(c *models.CardioEntry) string {
+	var parts []string
+	switch {
+	case c.DistanceKm > 0 && c.DurationSeconds > 0:
+		parts = append(parts, formatDistance(c.DistanceKm)+" за "+formatClock(c.DurationSeconds))
+	case c.DistanceKm > 0:
+		parts = append(parts, formatDistance(c.DistanceKm))
+	case c.DurationSeconds > 0:
+		parts = append(parts, formatClock(c.DurationSeconds))
+	}
+	if pace, ok := c.PaceSecondsPerKm(); ok {
+		parts = append(parts, "темп "+formatPace(pace))
+	}
+	if speed, ok := c.SpeedKmh(); ok {
+		parts = append(parts, strconv.FormatFloat(speed, 'f', 1, 64)+" км/ч")
+	}
+	if c.AvgHeartRate != nil {
+		hr := "пульс " + strconv.Itoa(*c.AvgHeartRate)
+		if c.MaxHeartRate != nil {
+			hr += "/" + strconv.Itoa(*c.MaxHeartRate)
+		}
+		parts = append(parts, hr)
+	}
+	if c.ElevationM != nil {
+		parts = append(parts, "набор "+strconv.Itoa(*c.ElevationM)+" м")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatDistance - «5 км», «5.25 км», «800 м»
+func formatDistance(km float64) string {
+	if km < 1 {
+		return strconv.Itoa(int(km*1000+0.5)) + " м"
+	}
+	return strconv.FormatFloat(km, 'f', -1, 64) + " км"
+}
+
+// formatClock - «28:30», «1:05:00»
+func formatClock(seconds int) string {
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// formatPace - темп «5:42/км»
+func formatPace(secondsPerKm float64) string {
+	return formatClock(int(secondsPerKm+0.5)) + "/км"
+}
+
+// sendCardioCharts отправляет графики дистанции с темпом и пульса
+func sendCardioCharts(b *bot.Bot, chatID, userID int64, exercises []*models.Exercise, exerciseName string, period statsPeriod) {
+	progress, err := charts.GenerateCardioChart(exercises, exerciseName)
+	if err != nil {
+		b.Log(userID).Error("Error generating cardio chart", "error", err)
+	}
+	sendChart(b, chatID, progress, "cardio.png",
+		fmt.Sprintf("📊 Дистанция и темп - «%s» %s", exerciseName, period.label))
+
+	heartRate, err := charts.GenerateHeartRateChart(exercises, exerciseName)
+	if err != nil {
+		b.Log(userID).Error("Error generating heart rate chart", "error", err)
+	}
+	sendChart(b, chatID, heartRate, "heart_rate.png",
+		fmt.Sprintf("❤️ Пульс - «%s» %s", exerciseName, period.label))
+}
+
+// cardioStatsText - последний результат и рекорды кардио за период.
+// exercises - от новых к старым.
+func cardioStatsText(exercises []*models.Exercise, period statsPeriod) bot.Text {
+	latest := exercises[0]
+	text := bot.NewText(bot.Plain("📈 "), bot.Bold("Последний результат:"), bot.Plain("\n"))
+	text = text.Line(bot.Plain(formatCardio(latest.Cardio)))
+	text = text.Line(bot.Plain("Дата: " + latest.CreatedAt.Format("02.01.2006")))
+
+	// Рекорды за период: лучший темп и самая длинная дистанция
+	var bestPace, longest *models.Exercise
+	var bestPaceValue, total float64
+	for _, ex := range exercises {
+		if ex.Cardio == nil {
+			continue
+		}
+		total += ex.Cardio.DistanceKm
+		if pace, ok := ex.Cardio.PaceSecondsPerKm(); ok {
+			if bestPace == nil || pace < bestPaceValue {
+				bestPace, bestPaceValue = ex, pace
+			}
+		}
+		if ex.Cardio.DistanceKm > 0 && (longest == nil || ex.Cardio.DistanceKm > longest.Cardio.DistanceKm) {
+			longest = ex
+		}
+	}
+	text = text.Line()
+	if bestPace != nil {
+		text = text.Line(bot.Plain("🏆 Лучший темп: " + formatPace(bestPaceValue) + " (" + bestPace.CreatedAt.Format("02.01.2006") + ")"))
+	}
+	if longest != nil {
+		text = text.Line(bot.Plain("🏁 Самая длинная дистанция: " + formatDistance(longest.Cardio.DistanceKm) +
+			" (" + longest.CreatedAt.Format("02.01.2006") + ")"))
+	}
+	if total > 0 {
+		text = text.Line(bot.Plain(fmt.Sprintf("Всего %s: %s, тренировок: %d", period.label, formatDistance(total), len(exercises))))
+	}
+	return text
+}
+
+// sendChart отправляет график картинкой; nil - графика нет, ничего не отправляем
+func sendChart(b *bot.Bot, chatID int64, data []byte, name, caption string) {
+	if data == nil {
+		return
+	}
+	photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileBytes{Name: name, Bytes: data})
+	photo.Caption = caption
+	b.Send(photo)
+}
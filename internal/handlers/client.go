@@ -4,7 +4,6 @@ import (
 	"fitness-bot/internal/bot"
 	"fitness-bot/internal/models"
 	"fmt"
-	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -24,14 +23,13 @@ func HandleClientMenu(b *bot.Bot, message *tgbotapi.Message, clientAccess []*mod
 	}
 
 	// Несколько тренеров - показываем выбор
-	var sb strings.Builder
-	sb.WriteString("🏋️ *Выберите тренера:*\n\n")
+	text := bot.NewText(bot.Plain("🏋️ "), bot.Bold("Выберите тренера:"), bot.Plain("\n\n"))
 
 	for i, access := range clientAccess {
-		sb.WriteString(fmt.Sprintf("%d. @%s (%s)\n", i+1, access.TrainerUsername, access.OrganizationName))
+		text = text.Line(bot.Plain(fmt.Sprintf("%d. ", i+1)), bot.Mention(access.TrainerUsername), bot.Plain(" ("+access.OrganizationName+")"))
 	}
 
-	b.SendMessage(message.Chat.ID, sb.String())
+	b.SendText(message.Chat.ID, text, nil)
 	b.SetState(message.From.ID, "client_selecting_trainer", map[string]interface{}{
 		"trainers": clientAccess,
 	})
@@ -45,9 +43,12 @@ func showClientTrainerMenu(b *bot.Bot, message *tgbotapi.Message, access *models
 		"org_id":            access.OrganizationID,
 		"org_name":          access.OrganizationName,
 	})
-	b.SendMessageWithKeyboard(
+	b.SendText(
 		message.Chat.ID,
-		fmt.Sprintf("📝 *Тренировки с @%s*\n_Организация: %s_\n\nВыберите действие:", access.TrainerUsername, access.OrganizationName),
+		bot.NewText(
+			bot.Plain("📝 "), bot.Bold("Тренировки с @"+access.TrainerUsername), bot.Plain("\n"),
+			bot.Italic("Организация: "+access.OrganizationName), bot.Plain("\n\nВыберите действие:"),
+		),
 		bot.GetClientMenuKeyboard(),
 	)
 }
@@ -73,17 +74,16 @@ func HandleArchivedAccess(b *bot.Bot, message *tgbotapi.Message, archivedAccess
 		return
 	}
 
-	var sb strings.Builder
-	sb.WriteString("📚 *Архивные тренировки*\n")
-	sb.WriteString("_Доступ завершён, но история сохранена_\n\n")
+	text := bot.NewText(bot.Plain("📚 "), bot.Bold("Архивные тренировки"), bot.Plain("\n"))
+	text = text.Line(bot.Italic("Доступ завершён, но история сохранена"), bot.Plain("\n"))
 
 	for i, access := range archivedAccess {
-		sb.WriteString(fmt.Sprintf("%d. @%s (%s)\n", i+1, access.TrainerUsername, access.OrganizationName))
+		text = text.Line(bot.Plain(fmt.Sprintf("%d. ", i+1)), bot.Mention(access.TrainerUsername), bot.Plain(" ("+access.OrganizationName+")"))
 	}
 
-	sb.WriteString("\nВыберите номер для просмотра истории:")
+	text = text.Add(bot.Plain("\nВыберите номер для просмотра истории:"))
 
-	b.SendMessage(message.Chat.ID, sb.String())
+	b.SendText(message.Chat.ID, text, nil)
 	b.SetState(message.From.ID, "client_viewing_archive", map[string]interface{}{
 		"archived": archivedAccess,
 	})
@@ -99,10 +99,7 @@ func HandleSelectArchivedTrainer(b *bot.Bot, message *tgbotapi.Message, idx int)
 		return
 	}
 
-	access := archived[idx-1]
-
-	// TODO: Показать историю тренировок с этим тренером
-	b.SendMessage(message.Chat.ID, fmt.Sprintf("📋 История тренировок с @%s будет добавлена позже.", access.TrainerUsername))
+	HandleArchiveHistory(b, message, archived[idx-1].TrainerClientID)
 }
 
 // HandleNoAccess показывает сообщение для пользователя без доступов
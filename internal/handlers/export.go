@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fitness-bot/internal/bot"
+	"fitness-bot/internal/models"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// SendHistoryExport присылает тренировки истории pos по её фильтру CSV-файлом
+func SendHistoryExport(b *bot.Bot, chatID int64, user *tgbotapi.User, pos bot.HistoryPosition) {
+	owner, err := resolveHistory(b, user, pos)
+	if err != nil {
+		historyError(b, chatID, user, err)
+		return
+	}
+	workouts, err := b.DB.GetFullWorkoutHistory(owner.scope, pos.Filter)
+	if err != nil {
+		b.Log(user.ID).Error("Error getting workouts for export", "error", err)
+		b.SendMessage(chatID, "❌ Ошибка при выгрузке тренировок.")
+		return
+	}
+	if len(workouts) == 0 {
+		b.SendMessage(chatID, "Нет тренировок для выгрузки.")
+		return
+	}
+
+	data, err := workoutsCSV(workouts, weightUnit(b, user.ID))
+	if err != nil {
+		b.Log(user.ID).Error("Error writing workouts CSV", "error", err)
+		b.SendMessage(chatID, "❌ Ошибка при выгрузке тренировок.")
+		return
+	}
+	name := "workouts_" + time.Now().Format("20060102") + ".csv"
+	caption := fmt.Sprintf("📤 Тренировок: %d", len(workouts))
+	if owner.client != nil {
+		name = "workouts_" + owner.counterpart() + "_" + time.Now().Format("20060102") + ".csv"
+		caption = fmt.Sprintf("📤 Тренировки с @%s: %d", owner.counterpart(), len(workouts))
+	}
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{Name: name, Bytes: data})
+	doc.Caption = caption
+	b.Send(doc)
+}
+
+// workoutsCSV выгружает тренировки по строке на подход (у кардио - на упражнение), вес - в единицах unit.
+// Разделитель «;» и BOM - чтобы файл сразу открывался в Excel с русской локалью.
+func workoutsCSV(workouts []*models.Workout, unit models.WeightUnit) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("\ufeff")
+	w := csv.NewWriter(&buf)
+	w.Comma = ';'
+
+	w.Write([]string{
+		"Дата", "Группы мышц", "Упражнение", "Подход", "Тип", "Вес, " + unit.Label(), "Повторения", "RPE",
+		"Свой вес, " + unit.Label(), "Дистанция, км", "Время", "Пульс ср.", "Пульс макс.", "Набор высоты, м",
+		"Заметки к упражнению", "Заметки к тренировке",
+	})
+	for _, workout := range workouts {
+		for i := range workout.Exercises {
+			ex := &workout.Exercises[i]
+			// Общие столбцы упражнения; подход, кардио и свой вес заполняются ниже
+			row := func() []string {
+				return []string{
+					workout.Date.Format("02.01.2006 15:04"), workout.MuscleGroupNames(), ex.Name,
+					"", "", "", "", "", "", "", "", "", "", "", ex.Notes, workout.Notes,
+				}
+			}
+
+			if ex.Cardio != nil {
+				r := row()
+				c := ex.Cardio
+				if c.DistanceKm > 0 {
+					r[9] = strconv.FormatFloat(c.DistanceKm, 'f', -1, 64)
+				}
+				if c.DurationSeconds > 0 {
+					r[10] = formatClock(c.DurationSeconds)
+				}
+				r[11], r[12], r[13] = optionalInt(c.AvgHeartRate), optionalInt(c.MaxHeartRate), optionalInt(c.ElevationM)
+				w.Write(r)
+				continue
+			}
+			if len(ex.Sets) == 0 {
+				w.Write(row())
+				continue
+			}
+			for j, set := range ex.Sets {
+				r := row()
+				r[3] = strconv.Itoa(j + 1)
+				r[4] = setTypeLabels[set.SetType]
+				if set.Weight != 0 {
+					r[5] = strings.TrimSuffix(formatWeight(set.Weight, unit), unit.Label())
+				}
+				r[6] = strconv.Itoa(set.Reps)
+				if set.RPE != nil {
+					r[7] = strconv.FormatFloat(*set.RPE, 'f', -1, 64)
+				}
+				if ex.Bodyweight && ex.BodyweightKg != nil {
+					r[8] = strings.TrimSuffix(formatWeight(*ex.BodyweightKg, unit), unit.Label())
+				}
+				w.Write(r)
+			}
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// optionalInt - число или пустая строка, если его нет
+func optionalInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
@@ -4,7 +4,6 @@ import (
 	"fitness-bot/internal/bot"
 	"fitness-bot/internal/models"
 	"fmt"
-	"log"
 	"strconv"
 	"strings"
 	"time"
@@ -39,7 +38,7 @@ func HandleGroupTrainings(b *bot.Bot, message *tgbotapi.Message) {
 
 	trainings, err := b.DB.GetUpcomingGroupTrainings(orgID)
 	if err != nil {
-		log.Printf("Error getting group trainings: %v", err)
+		b.Log(message.From.ID).Error("Error getting group trainings", "error", err)
 		b.SendMessage(message.Chat.ID, "❌ Ошибка при получении тренировок.")
 		return
 	}
@@ -108,7 +107,7 @@ func HandleJoinGroupTraining(b *bot.Bot, message *tgbotapi.Message, trainingIdx
 		if strings.Contains(err.Error(), "duplicate") {
 			b.SendMessage(message.Chat.ID, "Вы уже записаны на эту тренировку.")
 		} else {
-			log.Printf("Error joining training: %v", err)
+			b.Log(message.From.ID).Error("Error joining training", "error", err)
 			b.SendMessage(message.Chat.ID, "❌ Ошибка при записи.")
 		}
 		return
@@ -171,12 +170,6 @@ func HandleCreateGroupTraining(b *bot.Bot, message *tgbotapi.Message) {
 func HandleCreateGroupTrainingData(b *bot.Bot, message *tgbotapi.Message) {
 	state := b.GetState(message.From.ID)
 
-	if message.Text == "❌ Отмена" {
-		b.ClearState(message.From.ID)
-		b.SendMessageWithKeyboard(message.Chat.ID, "Отменено.", bot.GetTrainerMenuKeyboard())
-		return
-	}
-
 	lines := strings.Split(strings.TrimSpace(message.Text), "\n")
 	if len(lines) < 4 {
 		b.SendMessage(message.Chat.ID, "❌ Неверный формат. Проверьте данные.")
@@ -209,7 +202,7 @@ func HandleCreateGroupTrainingData(b *bot.Bot, message *tgbotapi.Message) {
 	}
 
 	if err := b.DB.CreateGroupTraining(training); err != nil {
-		log.Printf("Error creating group training: %v", err)
+		b.Log(message.From.ID).Error("Error creating group training", "error", err)
 		b.SendMessage(message.Chat.ID, "❌ Ошибка при создании тренировки.")
 		return
 	}
@@ -0,0 +1,520 @@
+package handlers
+
+import (
+	"errors"
+	"fitness-bot/internal/bot"
+	"fitness-bot/internal/models"
+	"fitness-bot/internal/parser"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
+)
+
+// HistoryPageSize - тренировок на странице истории
+const HistoryPageSize = 5
+
+// errHistoryAccess - у пользователя нет доступа к истории из позиции
+var errHistoryAccess = errors.New("no access to workout history")
+
+// historyOwner - чья история открыта: своя, клиента тренера или архивная связь с тренером
+type historyOwner struct {
+	scope   models.WorkoutScope
+	client  *models.TrainerClient    // связь тренер-клиент; nil - своя история
+	archive *models.ClientAccessInfo // архивная связь, которую смотрит сам клиент; nil - смотрит тренер
+}
+
+// resolveHistory определяет, чью историю из позиции pos смотрит user, и проверяет доступ.
+// Историю связи тренер-клиент видят её тренер и, если связь в архиве, сам клиент.
+func resolveHistory(b *bot.Bot, user *tgbotapi.User, pos bot.HistoryPosition) (*historyOwner, error) {
+	if pos.Client == 0 {
+		return &historyOwner{scope: models.WorkoutScope{ClientTelegramID: user.ID}}, nil
+	}
+	client, err := b.DB.GetTrainerClientByID(pos.Client)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errHistoryAccess
+	}
+	if err != nil {
+		return nil, err
+	}
+	isTrainer, err := b.DB.IsClientTrainer(client.ID, user.ID, user.UserName)
+	if err != nil {
+		return nil, err
+	}
+	if !isTrainer {
+		return resolveArchive(b, user, client)
+	}
+
+	owner := &historyOwner{scope: models.WorkoutScope{TrainerClientID: client.ID}, client: client}
+	if client.TelegramID != nil {
+		// Тренировки без тренера - только если клиент разрешил их показывать
+		owner.scope.ClientTelegramID = *client.TelegramID
+		owner.scope.Independent = userSettings(b, *client.TelegramID).ShareWorkouts
+	}
+	return owner, nil
+}
+
+// resolveArchive открывает клиенту историю его архивной связи с тренером
+func resolveArchive(b *bot.Bot, user *tgbotapi.User, client *models.TrainerClient) (*historyOwner, error) {
+	archived, err := b.DB.GetClientAccess(user.ID, user.UserName, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, access := range archived {
+		if access.TrainerClientID == client.ID {
+			return &historyOwner{scope: models.WorkoutScope{TrainerClientID: client.ID}, client: client, archive: access}, nil
+		}
+	}
+	return nil, errHistoryAccess
+}
+
+// counterpart - с кем связь: клиент для тренера, тренер для клиента в архиве
+func (o *historyOwner) counterpart() string {
+	if o.archive != nil {
+		return o.archive.TrainerUsername
+	}
+	return o.client.Username
+}
+
+// statsPeriod - за какой период показывать статистику: архив - целиком, иначе последние 3 месяца
+func (o *historyOwner) statsPeriod(now time.Time) statsPeriod {
+	if o.archive != nil {
+		return statsPeriod{to: now, label: "за всё время"}
+	}
+	return recentStats(now)
+}
+
+// title - заголовок страницы истории
+func (o *historyOwner) title() bot.Text {
+	switch {
+	case o.archive != nil:
+		return bot.NewText(bot.Bold("📚 Архив: тренировки с "), bot.Mention(o.archive.TrainerUsername),
+			bot.Plain(" ("+o.archive.OrganizationName+")"))
+	case o.client != nil:
+		return bot.NewText(bot.Bold("📋 История тренировок "), bot.Mention(o.client.Username))
+	default:
+		return bot.NewText(bot.Bold("📝 История тренировок"))
+	}
+}
+
+// contains проверяет, что тренировка входит в открытую историю
+func (o *historyOwner) contains(workout *models.Workout) bool {
+	if workout.Status != models.WorkoutCompleted {
+		return false
+	}
+	if o.client == nil {
+		return workout.ClientTelegramID == o.scope.ClientTelegramID
+	}
+	if workout.TrainerClientID != nil {
+		return *workout.TrainerClientID == o.client.ID
+	}
+	return o.scope.Independent && workout.ClientTelegramID == o.scope.ClientTelegramID
+}
+
+// historyError сообщает об ошибке открытия истории
+func historyError(b *bot.Bot, chatID int64, user *tgbotapi.User, err error) {
+	if errors.Is(err, errHistoryAccess) {
+		b.SendMessage(chatID, "❌ Нет доступа к этой истории тренировок.")
+		return
+	}
+	b.Log(user.ID).Error("Error getting workout history", "error", err)
+	b.SendMessage(chatID, "❌ Ошибка при получении тренировок.")
+}
+
+// HandleMyWorkouts показывает первую страницу истории тренировок
+func HandleMyWorkouts(b *bot.Bot, message *tgbotapi.Message) {
+	sendHistory(b, message, bot.HistoryPosition{}, "У вас пока нет тренировок. Добавьте первую!")
+}
+
+// HandleClientHistory показывает тренеру первую страницу истории тренировок клиента
+func HandleClientHistory(b *bot.Bot, message *tgbotapi.Message, trainerClientID int64) {
+	sendHistory(b, message, bot.HistoryPosition{Client: trainerClientID}, "У клиента пока нет тренировок.")
+}
+
+// HandleArchiveHistory показывает клиенту тренировки архивной связи с тренером - только для просмотра
+func HandleArchiveHistory(b *bot.Bot, message *tgbotapi.Message, trainerClientID int64) {
+	sendHistory(b, message, bot.HistoryPosition{Client: trainerClientID}, "С этим тренером тренировок не записано.")
+}
+
+// sendHistory отправляет страницу истории pos новым сообщением, empty - если тренировок нет
+func sendHistory(b *bot.Bot, message *tgbotapi.Message, pos bot.HistoryPosition, empty string) {
+	text, keyboard, err := historyPage(b, message.From, pos)
+	if err != nil {
+		historyError(b, message.Chat.ID, message.From, err)
+		return
+	}
+	if keyboard == nil {
+		b.SendMessage(message.Chat.ID, empty)
+		return
+	}
+	b.SendInlineText(message.Chat.ID, text, *keyboard)
+}
+
+// ShowWorkoutHistory показывает в сообщении messageID первую страницу истории, в которой
+// была тренировка workout: свою или, для тренера, историю клиента.
+// notice выводится над списком (например, «тренировка удалена»).
+func ShowWorkoutHistory(b *bot.Bot, chatID int64, messageID int, user *tgbotapi.User, workout *models.Workout, notice string) {
+	var pos bot.HistoryPosition
+	if workout.ClientTelegramID != user.ID && workout.TrainerClientID != nil {
+		pos.Client = *workout.TrainerClientID
+	}
+	ShowHistoryPage(b, chatID, messageID, user, pos, notice)
+}
+
+// ShowHistoryPage показывает страницу истории pos в сообщении messageID
+func ShowHistoryPage(b *bot.Bot, chatID int64, messageID int, user *tgbotapi.User, pos bot.HistoryPosition, notice string) {
+	text, keyboard, err := historyPage(b, user, pos)
+	if err != nil {
+		historyError(b, chatID, user, err)
+		return
+	}
+	if notice != "" {
+		text = bot.NewText(bot.Plain(notice)).Line().Line().Add(text...)
+	}
+	b.EditText(chatID, messageID, text, keyboard)
+}
+
+// historyPage загружает страницу истории одним запросом вместе с упражнениями.
+// keyboard - nil, если тренировок нет совсем.
+func historyPage(b *bot.Bot, user *tgbotapi.User, pos bot.HistoryPosition) (bot.Text, *tgbotapi.InlineKeyboardMarkup, error) {
+	owner, err := resolveHistory(b, user, pos)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Берём на одну тренировку больше - так понятно, есть ли следующая страница
+	workouts, err := b.DB.GetWorkoutHistory(owner.scope, pos.Filter, pos.Page*HistoryPageSize, HistoryPageSize+1)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(workouts) == 0 && pos.Page > 0 {
+		// Страница опустела (например, удалили последнюю тренировку на ней) - к началу
+		pos.Page = 0
+		return historyPage(b, user, pos)
+	}
+	if len(workouts) == 0 && pos.Filter.IsEmpty() {
+		return bot.NewText(bot.Plain("Тренировок пока нет.")), nil, nil
+	}
+	hasNext := len(workouts) > HistoryPageSize
+	if hasNext {
+		workouts = workouts[:HistoryPageSize]
+	}
+
+	var trainers []*models.ClientAccessInfo
+	if owner.client == nil {
+		// Фильтр по тренеру - только в своей истории
+		trainers = historyTrainers(b, user)
+	}
+	text := owner.title()
+	unit := weightUnit(b, user.ID)
+	if pos.Page > 0 {
+		text = text.Add(bot.Plain(fmt.Sprintf(" - стр. %d", pos.Page+1)))
+	}
+	text = text.Line()
+	if owner.archive != nil {
+		text = text.Line(bot.Italic("🔒 Доступ завершён - тренировки только для просмотра"))
+	}
+	if !pos.Filter.IsEmpty() {
+		text = text.Line(bot.Plain("🔎 " + historyFilterText(b, pos.Filter, trainers)))
+	}
+	text = text.Line()
+	if len(workouts) == 0 {
+		text = text.Line(bot.Plain("По этому фильтру тренировок нет."))
+	}
+	for _, w := range workouts {
+		title := fmt.Sprintf("📅 %s - %s", formatWorkoutTime(w), w.MuscleGroupNames())
+		if owner.scope.Independent && w.TrainerClientID == nil {
+			title += " (без тренера)"
+		}
+		text = text.Line(bot.Plain(title))
+		for i := range w.Exercises {
+			ex := &w.Exercises[i]
+			text = text.Line(bot.Plain(fmt.Sprintf("  • %s: %s", ex.Name, formatExerciseResult(ex, unit))))
+		}
+		text = text.Line()
+	}
+	if len(workouts) > 0 {
+		text = text.Add(bot.Plain("Нажмите на тренировку, чтобы открыть её."))
+	}
+
+	keyboard := bot.GetInlineHistoryKeyboard(workouts, pos, hasNext, len(trainers) > 1)
+	return text, &keyboard, nil
+}
+
+// historyTrainers - тренеры клиента, текущие и архивные, для фильтра истории
+func historyTrainers(b *bot.Bot, user *tgbotapi.User) []*models.ClientAccessInfo {
+	accessInfo, err := b.DB.GetUserAccessInfo(user.ID, user.UserName)
+	if err != nil {
+		b.Log(user.ID).Error("Error getting access info", "error", err)
+		return nil
+	}
+	return append(append([]*models.ClientAccessInfo{}, accessInfo.ClientAccess...), accessInfo.ArchivedAccess...)
+}
+
+// historyFilterText описывает фильтр: «Грудь · 01.03.2024-31.03.2024 · @trainer»
+func historyFilterText(b *bot.Bot, f models.WorkoutFilter, trainers []*models.ClientAccessInfo) string {
+	var parts []string
+	if f.MuscleGroupID != 0 {
+		name := "группа мышц"
+		if group, err := b.DB.GetMuscleGroupByID(f.MuscleGroupID); err == nil {
+			name = string(group.Name)
+		}
+		parts = append(parts, name)
+	}
+	switch {
+	case !f.From.IsZero() && !f.To.IsZero():
+		parts = append(parts, f.From.Format("02.01.2006")+"-"+f.To.Format("02.01.2006"))
+	case !f.From.IsZero():
+		parts = append(parts, "с "+f.From.Format("02.01.2006"))
+	case !f.To.IsZero():
+		parts = append(parts, "по "+f.To.Format("02.01.2006"))
+	}
+	if f.TrainerClientID != 0 {
+		name := "тренер"
+		for _, t := range trainers {
+			if t.TrainerClientID == f.TrainerClientID {
+				name = "@" + t.TrainerUsername
+			}
+		}
+		parts = append(parts, name)
+	}
+	return strings.Join(parts, " · ")
+}
+
+// ShowHistoryFilter показывает меню фильтра view для страницы истории pos
+func ShowHistoryFilter(b *bot.Bot, chatID int64, messageID int, user *tgbotapi.User, pos bot.HistoryPosition, view string) {
+	owner, err := resolveHistory(b, user, pos)
+	if err != nil {
+		historyError(b, chatID, user, err)
+		return
+	}
+
+	var keyboard tgbotapi.InlineKeyboardMarkup
+	var title string
+	switch {
+	case view == bot.HistoryGroupsView:
+		groups, err := b.DB.GetWorkoutHistoryMuscleGroups(owner.scope)
+		if err != nil {
+			b.Log(user.ID).Error("Error getting history muscle groups", "error", err)
+			b.SendMessage(chatID, "❌ Ошибка. Попробуйте позже.")
+			return
+		}
+		title = "💪 Тренировки с группой мышц:"
+		keyboard = bot.GetInlineHistoryGroupsKeyboard(groups, pos)
+	case view == bot.HistoryPeriodView:
+		title = "📅 Тренировки за период:"
+		keyboard = bot.GetInlineHistoryPeriodKeyboard(pos, time.Now())
+	case view == bot.HistoryTrainerView && owner.client == nil:
+		title = "👤 Тренировки с тренером:"
+		keyboard = bot.GetInlineHistoryTrainersKeyboard(historyTrainers(b, user), pos)
+	default:
+		ShowHistoryPage(b, chatID, messageID, user, pos, "")
+		return
+	}
+	b.EditText(chatID, messageID, bot.NewText(bot.Plain(title)), &keyboard)
+}
+
+// ShowHistoryWorkout показывает тренировку из истории подробно - по подходам,
+// с изменением каждого упражнения относительно прошлого раза
+func ShowHistoryWorkout(b *bot.Bot, chatID int64, messageID int, user *tgbotapi.User, workoutID int64, pos bot.HistoryPosition) {
+	owner, err := resolveHistory(b, user, pos)
+	if err != nil {
+		historyError(b, chatID, user, err)
+		return
+	}
+	workout, err := b.DB.GetWorkoutByID(workoutID)
+	if err != nil || !owner.contains(workout) {
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			b.Log(user.ID).Error("Error getting workout", "workout_id", workoutID, "error", err)
+		}
+		ShowHistoryPage(b, chatID, messageID, user, pos, "❌ Тренировка не найдена или уже удалена.")
+		return
+	}
+	exercises, err := b.DB.GetExercisesByWorkout(workout.ID)
+	if err != nil {
+		b.Log(user.ID).Error("Error getting exercises", "workout_id", workout.ID, "error", err)
+		b.SendMessage(chatID, "❌ Ошибка при получении упражнений.")
+		return
+	}
+
+	// Сравниваем с прошлыми выполнениями в той же истории - тренер не увидит скрытых от него тренировок
+	var catalogIDs []int64
+	for _, ex := range exercises {
+		if ex.CatalogID != nil {
+			catalogIDs = append(catalogIDs, *ex.CatalogID)
+		}
+	}
+	previous, err := b.DB.GetPreviousExercises(owner.scope, catalogIDs, workout.Date, workout.ID)
+	if err != nil {
+		// Без динамики тренировку всё равно показываем
+		b.Log(user.ID).Error("Error getting previous exercises", "workout_id", workout.ID, "error", err)
+	}
+
+	// Править можно то же, что и в редакторе: архивные тренировки - только смотреть
+	editable, err := b.DB.CanEditWorkout(workout, user.ID, user.UserName)
+	if err != nil {
+		b.Log(user.ID).Error("Error checking workout access", "workout_id", workout.ID, "error", err)
+	}
+	keyboard := bot.GetInlineHistoryWorkoutKeyboard(workout.ID, pos, editable)
+	b.EditText(chatID, messageID, workoutDetailsText(workout, exercises, previous, weightUnit(b, user.ID)), &keyboard)
+}
+
+// workoutDetailsText - тренировка с упражнениями по подходам и объёмом, вес - в единицах unit.
+// previous - прошлые выполнения упражнений по catalog_id для динамики (nil - без неё).
+func workoutDetailsText(workout *models.Workout, exercises []*models.Exercise, previous map[int64]*models.Exercise, unit models.WeightUnit) bot.Text {
+	text := bot.NewText(bot.Bold(fmt.Sprintf("📅 %s - %s", formatWorkoutTime(workout), workout.MuscleGroupNames()))).Line()
+	if workout.Notes != "" {
+		text = text.Line(bot.Plain("📝 " + workout.Notes))
+	}
+	text = text.Line()
+	if len(exercises) == 0 {
+		text = text.Line(bot.Plain("Упражнений нет."))
+	}
+
+	var total float64
+	for i, ex := range exercises {
+		title := []bot.Part{bot.Bold(fmt.Sprintf("%d. %s", i+1, ex.Name))}
+		if ex.CatalogID != nil && previous != nil {
+			title = append(title, bot.Plain("  "+exerciseTrend(ex, previous[*ex.CatalogID], unit)))
+		}
+		text = text.Line(title...)
+		if ex.Cardio != nil {
+			text = text.Line(bot.Plain("   " + formatCardio(ex.Cardio)))
+			continue
+		}
+		f := exerciseFormat(ex, unit)
+		for j, set := range ex.Sets {
+			text = text.Line(bot.Plain(fmt.Sprintf("   %d) %s", j+1, formatSet(set, f))))
+		}
+		if ex.Bodyweight && ex.BodyweightKg != nil {
+			text = text.Line(bot.Plain("   свой вес " + formatWeight(*ex.BodyweightKg, unit)))
+		}
+		if volume := ex.Volume(); volume > 0 {
+			text = text.Line(bot.Plain("   объём " + formatWeight(volume, unit)))
+			total += volume
+		}
+	}
+	if total > 0 {
+		text = text.Line().Line(bot.Plain("🏋️ Общий объём: "), bot.Bold(formatWeight(total, unit)))
+	}
+	if previous != nil {
+		text = text.Line().Line(bot.Plain("📈 / 📉 / ➡️ - по сравнению с прошлым разом, 🆕 - впервые"))
+	}
+	return text
+}
+
+// exerciseTrend сравнивает упражнение с прошлым выполнением prev: «📈 +5кг», «📉 -2 повт.», «➡️».
+// Силовые - по самому тяжёлому подходу, затем по объёму; кардио - по темпу, затем по дистанции.
+// «🆕» - если раньше упражнения не было.
+func exerciseTrend(ex, prev *models.Exercise, unit models.WeightUnit) string {
+	if prev == nil {
+		return "🆕"
+	}
+	if ex.Cardio != nil || prev.Cardio != nil {
+		return cardioTrend(ex.Cardio, prev.Cardio)
+	}
+
+	top, prevTop := ex.TopSet(), prev.TopSet()
+	if top == nil || prevTop == nil {
+		return ""
+	}
+	// Разницу показываем со знаком, как отягощение
+	signed := weightFormat{unit: unit, bodyweight: true}
+	if diff := ex.Load(*top) - prev.Load(*prevTop); math.Abs(diff) >= 0.01 {
+		return trendArrow(diff > 0) + " " + signed.weight(diff)
+	}
+	if diff := top.Reps - prevTop.Reps; diff != 0 {
+		return trendArrow(diff > 0) + fmt.Sprintf(" %+d повт.", diff)
+	}
+	if diff := ex.Volume() - prev.Volume(); math.Abs(diff) >= 0.01 {
+		return trendArrow(diff > 0) + " объём " + signed.weight(diff)
+	}
+	return "➡️"
+}
+
+// cardioTrend сравнивает кардио с прошлым разом: быстрее темп или больше дистанция - лучше
+func cardioTrend(cardio, prev *models.CardioEntry) string {
+	if cardio == nil || prev == nil {
+		return ""
+	}
+	pace, ok := cardio.PaceSecondsPerKm()
+	prevPace, prevOK := prev.PaceSecondsPerKm()
+	if ok && prevOK {
+		if diff := pace - prevPace; math.Abs(diff) >= 1 {
+			sign := "+"
+			if diff < 0 {
+				sign = "-"
+			}
+			return trendArrow(diff < 0) + " темп " + sign + formatClock(int(math.Abs(diff)+0.5))
+		}
+	}
+	if diff := cardio.DistanceKm - prev.DistanceKm; math.Abs(diff) >= 0.01 {
+		sign := "+"
+		if diff < 0 {
+			sign = "-"
+		}
+		return trendArrow(diff > 0) + " " + sign + formatDistance(math.Abs(diff))
+	}
+	return "➡️"
+}
+
+// trendArrow - стрелка роста или спада
+func trendArrow(better bool) string {
+	if better {
+		return "📈"
+	}
+	return "📉"
+}
+
+// StartHistoryRangeInput запрашивает свой период для фильтра истории
+func StartHistoryRangeInput(b *bot.Bot, message *tgbotapi.Message, pos bot.HistoryPosition) {
+	data := map[string]interface{}{}
+	returnState := ""
+	if state := b.GetState(message.From.ID); state != nil {
+		returnState = state.State
+		if returnState == "awaiting_history_range" {
+			returnState, _ = bot.GetStateString(state.Data, "return_state")
+		}
+		data = bot.CopyStateData(state.Data)
+	}
+	data["history"] = pos.String()
+	data["return_state"] = returnState
+	b.SetState(message.From.ID, "awaiting_history_range", data)
+
+	b.SendText(message.Chat.ID,
+		bot.NewText(bot.Plain("📆 Введите период, например «01.03-31.03» или «01.12.2023-15.01.2024».\nОдин день - просто дата.")),
+		bot.GetCancelKeyboard())
+}
+
+// HandleHistoryRange применяет введённый период и показывает историю заново
+func HandleHistoryRange(b *bot.Bot, message *tgbotapi.Message, state *models.UserState, accessInfo *models.AccessInfo) {
+	if message.Text == bot.CancelText {
+		returnFromInput(b, message, state, accessInfo, "Возврат в меню.", "history")
+		return
+	}
+
+	from, to, err := parser.ParseDateRange(message.Text, time.Now())
+	if err != nil {
+		b.SendWithCancel(message.Chat.ID, "⚠️ "+err.Error())
+		return
+	}
+	encoded, _ := bot.GetStateString(state.Data, "history")
+	pos, _ := bot.ParseHistoryPosition(encoded)
+	pos.Page = 0
+	pos.Filter.From, pos.Filter.To = from, to
+
+	returnFromInput(b, message, state, accessInfo, "📆 Период: "+from.Format("02.01.2006")+"-"+to.Format("02.01.2006"), "history")
+	text, keyboard, err := historyPage(b, message.From, pos)
+	if err != nil {
+		b.Log(message.From.ID).Error("Error getting workout history", "error", err)
+		b.SendMessage(message.Chat.ID, "❌ Ошибка при получении тренировок.")
+		return
+	}
+	if keyboard == nil {
+		b.SendText(message.Chat.ID, text, nil)
+		return
+	}
+	b.SendInlineText(message.Chat.ID, text, *keyboard)
+}
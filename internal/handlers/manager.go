@@ -5,7 +5,6 @@ import (
 	"fitness-bot/internal/database"
 	"fitness-bot/internal/models"
 	"fmt"
-	"log"
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -25,7 +24,7 @@ func HandleManagerMenu(b *bot.Bot, message *tgbotapi.Message, managerOrgs []*mod
 			b.SendMessage(message.Chat.ID, "❌ Ваш доступ к организации был деактивирован.")
 			return
 		}
-		showManagerOrgMenu(b, message, org.Organization.ID, org.Organization.Name)
+		ShowManagerOrgMenu(b, message, org.Organization.ID, org.Organization.Name)
 		return
 	}
 
@@ -52,7 +51,8 @@ func HandleManagerMenu(b *bot.Bot, message *tgbotapi.Message, managerOrgs []*mod
 	})
 }
 
-func showManagerOrgMenu(b *bot.Bot, message *tgbotapi.Message, orgID int64, orgName string) {
+// ShowManagerOrgMenu показывает панель менеджера организации
+func ShowManagerOrgMenu(b *bot.Bot, message *tgbotapi.Message, orgID int64, orgName string) {
 	// Очищаем старые сообщения
 	b.CleanupMessages(message.Chat.ID, message.From.ID)
 
@@ -62,9 +62,9 @@ func showManagerOrgMenu(b *bot.Bot, message *tgbotapi.Message, orgID int64, orgN
 	})
 
 	breadcrumbs := bot.GetBreadcrumbs("🏠 Главная", "🏢 Менеджер", orgName)
-	text := breadcrumbs + "Как менеджер вы можете добавлять и удалять тренеров."
+	text := bot.NewText(bot.Plain(breadcrumbs + "Как менеджер вы можете добавлять и удалять тренеров."))
 
-	b.SendMessageWithKeyboard(
+	b.SendText(
 		message.Chat.ID,
 		text,
 		bot.GetManagerMenuKeyboard(),
@@ -86,7 +86,7 @@ func HandleManagerSelectOrg(b *bot.Bot, message *tgbotapi.Message, idx int) {
 	}
 
 	org := orgs[idx-1]
-	showManagerOrgMenu(b, message, org.Organization.ID, org.Organization.Name)
+	ShowManagerOrgMenu(b, message, org.Organization.ID, org.Organization.Name)
 }
 
 // HandleAddTrainer начинает добавление тренера
@@ -125,11 +125,6 @@ func HandleAddTrainerUsername(b *bot.Bot, message *tgbotapi.Message) {
 		return
 	}
 
-	if message.Text == "❌ Отмена" {
-		showManagerOrgMenu(b, message, orgID, orgName)
-		return
-	}
-
 	username := database.NormalizeUsername(message.Text)
 	if username == "" {
 		b.SendWithCancel(message.Chat.ID, "❌ Некорректный username. Введите в формате @username:")
@@ -137,20 +132,23 @@ func HandleAddTrainerUsername(b *bot.Bot, message *tgbotapi.Message) {
 	}
 
 	if err := b.DB.AddTrainer( orgID, username); err != nil {
-		log.Printf("Error adding trainer: %v", err)
+		b.Log(message.From.ID).Error("Error adding trainer", "error", err)
 		errStr := err.Error()
 		if strings.Contains(errStr, "duplicate") || strings.Contains(errStr, "unique") {
-			b.SendWithCancel(message.Chat.ID, fmt.Sprintf("⚠️ @%s уже является тренером этой организации.", username))
+			b.SendText(message.Chat.ID,
+				bot.NewText(bot.Plain("⚠️ "), bot.Mention(username), bot.Plain(" уже является тренером этой организации.")),
+				bot.GetCancelKeyboard())
 		} else {
 			b.SendWithCancel(message.Chat.ID, "❌ Ошибка при добавлении тренера.")
 		}
 		return
 	}
 
-	showManagerOrgMenu(b, message, orgID, orgName)
-	b.SendMessageWithKeyboard(
+	ShowManagerOrgMenu(b, message, orgID, orgName)
+	b.SendText(
 		message.Chat.ID,
-		fmt.Sprintf("✅ Тренер @%s добавлен в организацию *%s*\n\nКогда тренер напишет боту, он получит доступ.", username, bot.EscapeMarkdown(orgName)),
+		bot.NewText(bot.Plain("✅ Тренер "), bot.Mention(username), bot.Plain(" добавлен в организацию "), bot.Bold(orgName),
+			bot.Plain("\n\nКогда тренер напишет боту, он получит доступ.")),
 		bot.GetManagerMenuKeyboard(),
 	)
 }
@@ -173,18 +171,17 @@ func HandleListTrainers(b *bot.Bot, message *tgbotapi.Message) {
 
 	trainers, err := b.DB.GetOrganizationTrainers( orgID)
 	if err != nil {
-		log.Printf("Error getting trainers: %v", err)
+		b.Log(message.From.ID).Error("Error getting trainers", "error", err)
 		b.SendMessage(message.Chat.ID, "❌ Ошибка при получении списка тренеров.")
 		return
 	}
 
 	if len(trainers) == 0 {
-		b.SendMessage(message.Chat.ID, fmt.Sprintf("В организации *%s* пока нет тренеров.", orgName))
+		b.SendText(message.Chat.ID, bot.NewText(bot.Plain("В организации "), bot.Bold(orgName), bot.Plain(" пока нет тренеров.")), nil)
 		return
 	}
 
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("🏋️ *Тренеры организации %s:*\n\n", orgName))
+	text := bot.NewText(bot.Plain("🏋️ "), bot.Bold("Тренеры организации "+orgName+":"), bot.Plain("\n\n"))
 
 	// Создаём inline-клавиатуру
 	var items []string
@@ -198,10 +195,10 @@ func HandleListTrainers(b *bot.Bot, message *tgbotapi.Message) {
 		ids = append(ids, t.ID)
 	}
 
-	sb.WriteString("Выберите тренера для удаления:")
+	text = text.Add(bot.Plain("Выберите тренера для удаления:"))
 
 	keyboard := bot.GetInlineListKeyboard(items, ids, "trainer")
-	b.SendInlineKeyboard(message.Chat.ID, sb.String(), keyboard)
+	b.SendInlineText(message.Chat.ID, text, keyboard)
 
 	b.SetState(message.From.ID, "manager_removing_trainer", map[string]interface{}{
 		"org_id":   orgID,
@@ -233,15 +230,16 @@ func HandleRemoveTrainer(b *bot.Bot, message *tgbotapi.Message, idx int) {
 
 	trainer := trainers[idx-1]
 	if err := b.DB.RemoveTrainer( orgID, trainer.Username); err != nil {
-		log.Printf("Error removing trainer: %v", err)
+		b.Log(message.From.ID).Error("Error removing trainer", "error", err)
 		b.SendMessage(message.Chat.ID, "❌ Ошибка при удалении тренера.")
 		return
 	}
 
-	showManagerOrgMenu(b, message, orgID, orgName)
-	b.SendMessageWithKeyboard(
+	ShowManagerOrgMenu(b, message, orgID, orgName)
+	b.SendText(
 		message.Chat.ID,
-		fmt.Sprintf("✅ Тренер @%s удалён из организации *%s*\n\n⚠️ Его клиенты смогут просматривать историю тренировок.", trainer.Username, bot.EscapeMarkdown(orgName)),
+		bot.NewText(bot.Plain("✅ Тренер "), bot.Mention(trainer.Username), bot.Plain(" удалён из организации "), bot.Bold(orgName),
+			bot.Plain("\n\n⚠️ Его клиенты смогут просматривать историю тренировок.")),
 		bot.GetManagerMenuKeyboard(),
 	)
 }
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"fitness-bot/internal/bot"
+	"fitness-bot/internal/catalog"
+	"fitness-bot/internal/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// customMuscleGroupEmoji - эмодзи для групп мышц, добавленных организацией
+const customMuscleGroupEmoji = "🔹"
+
+// HandleMuscleGroups показывает менеджеру группы мышц организации
+func HandleMuscleGroups(b *bot.Bot, message *tgbotapi.Message) {
+	state := b.GetState(message.From.ID)
+	if state == nil || state.State != "manager_managing_org" {
+		b.SendMessage(message.Chat.ID, "❌ Сначала выберите организацию.")
+		return
+	}
+	orgID, okID := bot.GetStateInt64(state.Data, "org_id")
+	orgName, okName := bot.GetStateString(state.Data, "org_name")
+	if !okID || !okName {
+		b.SendMessage(message.Chat.ID, "❌ Сначала выберите организацию.")
+		return
+	}
+
+	groups, err := b.DB.GetMuscleGroups(&orgID)
+	if err != nil {
+		b.Log(message.From.ID).Error("Error getting muscle groups", "org_id", orgID, "error", err)
+		b.SendMessage(message.Chat.ID, "❌ Ошибка при получении групп мышц.")
+		return
+	}
+
+	b.CleanupMessages(message.Chat.ID, message.From.ID)
+	msgID := b.SendInlineText(message.Chat.ID, muscleGroupsText(orgName, groups, ""), bot.GetInlineOrgMuscleGroupsKeyboard(groups))
+	b.StoreMessageID(message.From.ID, msgID)
+}
+
+// ShowMuscleGroups показывает группы мышц организации в сообщении messageID
+func ShowMuscleGroups(b *bot.Bot, chatID int64, messageID int, user *tgbotapi.User, orgID int64, orgName, notice string) {
+	groups, err := b.DB.GetMuscleGroups(&orgID)
+	if err != nil {
+		b.Log(user.ID).Error("Error getting muscle groups", "org_id", orgID, "error", err)
+		b.SendMessage(chatID, "❌ Ошибка при получении групп мышц.")
+		return
+	}
+	keyboard := bot.GetInlineOrgMuscleGroupsKeyboard(groups)
+	b.EditText(chatID, messageID, muscleGroupsText(orgName, groups, notice), &keyboard)
+}
+
+// muscleGroupsText - список встроенных групп мышц и групп организации
+func muscleGroupsText(orgName string, groups []*models.MuscleGroupRecord, notice string) bot.Text {
+	text := bot.NewText()
+	if notice != "" {
+		text = text.Line(bot.Plain(notice)).Line()
+	}
+	text = text.Line(bot.Plain("💪 "), bot.Bold("Группы мышц - "+orgName)).Line()
+
+	var builtIn, custom []*models.MuscleGroupRecord
+	for _, g := range groups {
+		if g.OrganizationID == nil {
+			builtIn = append(builtIn, g)
+		} else {
+			custom = append(custom, g)
+		}
+	}
+
+	text = text.Line(bot.Italic("Встроенные:"))
+	for _, g := range builtIn {
+		text = text.Line(bot.Plain(g.Label()))
+	}
+	text = text.Line().Line(bot.Italic("Группы организации:"))
+	if len(custom) == 0 {
+		text = text.Line(bot.Plain("пока нет"))
+	}
+	for _, g := range custom {
+		text = text.Line(bot.Plain(g.Label()))
+	}
+	return text.Line().Add(bot.Plain("Свои группы появятся у тренеров и клиентов при выборе групп мышц тренировки."))
+}
+
+// ManagedMuscleGroup проверяет, что группа принадлежит организации из состояния менеджера
+// и пользователь - её менеджер
+func ManagedMuscleGroup(b *bot.Bot, chatID int64, user *tgbotapi.User, id int64) (*models.MuscleGroupRecord, bool) {
+	state := b.GetState(user.ID)
+	if state == nil || state.State != "manager_managing_org" {
+		b.SendMessage(chatID, "❌ Откройте организацию и попробуйте снова.")
+		return nil, false
+	}
+	orgID, _ := bot.GetStateInt64(state.Data, "org_id")
+
+	group, err := b.DB.GetMuscleGroupByID(id)
+	if err != nil {
+		b.SendMessage(chatID, "❌ Группа мышц не найдена.")
+		return nil, false
+	}
+	if group.OrganizationID == nil || *group.OrganizationID != orgID {
+		b.SendMessage(chatID, "❌ Встроенные группы мышц удалить нельзя.")
+		return nil, false
+	}
+	isManager, err := b.DB.IsOrganizationManager(orgID, user.ID, user.UserName)
+	if err != nil {
+		b.Log(user.ID).Error("Error checking manager access", "org_id", orgID, "error", err)
+		b.SendMessage(chatID, "❌ Ошибка. Попробуйте позже.")
+		return nil, false
+	}
+	if !isManager {
+		b.SendMessage(chatID, "❌ У вас нет доступа к этой организации.")
+		return nil, false
+	}
+	return group, true
+}
+
+// StartMuscleGroupCreation запрашивает название новой группы мышц
+func StartMuscleGroupCreation(b *bot.Bot, message *tgbotapi.Message) {
+	state := b.GetState(message.From.ID)
+	if state == nil || state.State != "manager_managing_org" {
+		b.SendMessage(message.Chat.ID, "❌ Откройте организацию и попробуйте снова.")
+		return
+	}
+
+	b.CleanupMessages(message.Chat.ID, message.From.ID)
+	b.SetState(message.From.ID, "manager_adding_muscle_group", bot.CopyStateData(state.Data))
+	b.SendWithCancel(message.Chat.ID, "Введите название группы мышц (например: «Ягодицы»):")
+}
+
+// HandleAddMuscleGroupName добавляет группу мышц организации
+func HandleAddMuscleGroupName(b *bot.Bot, message *tgbotapi.Message) {
+	state := b.GetState(message.From.ID)
+
+	orgID, okID := bot.GetStateInt64(state.Data, "org_id")
+	orgName, okName := bot.GetStateString(state.Data, "org_name")
+	if !okID || !okName {
+		b.ClearState(message.From.ID)
+		b.SendMessage(message.Chat.ID, "❌ Ошибка состояния. Попробуйте снова.")
+		return
+	}
+
+	name := catalog.Clean(message.Text)
+	if name == "" || len([]rune(name)) > 50 {
+		b.SendWithCancel(message.Chat.ID, "⚠️ Название должно быть от 1 до 50 символов.")
+		return
+	}
+
+	group := &models.MuscleGroupRecord{
+		OrganizationID: &orgID,
+		Name:           models.MuscleGroup(name),
+		Emoji:          customMuscleGroupEmoji,
+		SortOrder:      100,
+	}
+	created, err := b.DB.CreateMuscleGroup(group)
+	if err != nil {
+		b.Log(message.From.ID).Error("Error creating muscle group", "org_id", orgID, "error", err)
+		b.SendWithCancel(message.Chat.ID, "❌ Ошибка при добавлении группы мышц.")
+		return
+	}
+	if !created {
+		b.SendText(message.Chat.ID,
+			bot.NewText(bot.Plain("⚠️ Группа "), bot.Bold(name), bot.Plain(" уже есть. Введите другое название:")),
+			bot.GetCancelKeyboard())
+		return
+	}
+
+	ShowManagerOrgMenu(b, message, orgID, orgName)
+	b.SendText(message.Chat.ID,
+		bot.NewText(bot.Plain("✅ Группа мышц "), bot.Bold(group.Label()), bot.Plain(" добавлена.")),
+		bot.GetManagerMenuKeyboard())
+}
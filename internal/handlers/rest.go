@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"fitness-bot/internal/bot"
+	"fitness-bot/internal/parser"
+	"fmt"
+	"strconv"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// organizationRestSeconds возвращает отдых по умолчанию организации (0 - без таймера)
+func organizationRestSeconds(b *bot.Bot, telegramID int64, orgID *int64) int {
+	if orgID == nil {
+		return 0
+	}
+	org, err := b.DB.GetOrganizationByID(*orgID)
+	if err != nil {
+		b.Log(telegramID).Error("Error getting organization", "org_id", *orgID, "error", err)
+		return 0
+	}
+	return org.DefaultRestSeconds
+}
+
+// startWorkoutRest запускает отдых после записанных упражнений:
+// отдых последнего упражнения, если он указан, иначе - по умолчанию организации orgID тренировки
+func startWorkoutRest(b *bot.Bot, message *tgbotapi.Message, orgID *int64, exercises []parser.Exercise) {
+	if len(exercises) > 0 {
+		if rest := exercises[len(exercises)-1].RestSeconds; rest > 0 {
+			b.StartRest(message.Chat.ID, message.From.ID, rest)
+			return
+		}
+	}
+	b.StartRest(message.Chat.ID, message.From.ID, organizationRestSeconds(b, message.From.ID, orgID))
+}
+
+// formatRest форматирует отдых: «90 сек», «2 мин», «1:30»
+func formatRest(seconds int) string {
+	switch {
+	case seconds <= 0:
+		return "без таймера"
+	case seconds < 60:
+		return strconv.Itoa(seconds) + " сек"
+	case seconds%60 == 0:
+		return strconv.Itoa(seconds/60) + " мин"
+	default:
+		return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+	}
+}
+
+// HandleRestSettings показывает менеджеру отдых по умолчанию и запрашивает новый
+func HandleRestSettings(b *bot.Bot, message *tgbotapi.Message) {
+	state := b.GetState(message.From.ID)
+	if state == nil || state.State != "manager_managing_org" {
+		b.SendMessage(message.Chat.ID, "❌ Сначала выберите организацию.")
+		return
+	}
+	orgID, ok := bot.GetStateInt64(state.Data, "org_id")
+	if !ok {
+		b.SendMessage(message.Chat.ID, "❌ Сначала выберите организацию.")
+		return
+	}
+
+	current := organizationRestSeconds(b, message.From.ID, &orgID)
+	b.SetState(message.From.ID, "manager_setting_rest", bot.CopyStateData(state.Data))
+	b.SendText(message.Chat.ID,
+		bot.NewText(
+			bot.Plain("⏱ Отдых между подходами по умолчанию: "), bot.Bold(formatRest(current)),
+			bot.Plain("\n\nПосле каждого записанного упражнения бот запускает таймер отдыха. "+
+				"Клиент может указать свой отдых: «Жим лежа 4x10x80 отдых 90».\n\n"+
+				"Введите новое значение (90, 2 мин, 1:30) или 0, чтобы отключить таймер:"),
+		),
+		bot.GetCancelKeyboard())
+}
+
+// HandleSetDefaultRest сохраняет отдых по умолчанию организации
+func HandleSetDefaultRest(b *bot.Bot, message *tgbotapi.Message) {
+	state := b.GetState(message.From.ID)
+
+	orgID, okID := bot.GetStateInt64(state.Data, "org_id")
+	orgName, okName := bot.GetStateString(state.Data, "org_name")
+	if !okID || !okName {
+		b.ClearState(message.From.ID)
+		b.SendMessage(message.Chat.ID, "❌ Ошибка состояния. Попробуйте снова.")
+		return
+	}
+
+	seconds, err := parser.ParseRest(message.Text)
+	if err != nil {
+		b.SendWithCancel(message.Chat.ID, "⚠️ Не понял отдых: "+err.Error())
+		return
+	}
+	if err := b.DB.SetOrganizationRestSeconds(orgID, seconds); err != nil {
+		b.Log(message.From.ID).Error("Error saving default rest", "org_id", orgID, "error", err)
+		b.SendWithCancel(message.Chat.ID, "❌ Ошибка при сохранении. Попробуйте позже.")
+		return
+	}
+
+	ShowManagerOrgMenu(b, message, orgID, orgName)
+	b.SendText(message.Chat.ID,
+		bot.NewText(bot.Plain("✅ Отдых по умолчанию: "), bot.Bold(formatRest(seconds))),
+		bot.GetManagerMenuKeyboard())
+}
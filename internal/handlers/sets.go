@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"fitness-bot/internal/models"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ExerciseInputHelp - подсказка по формату ввода упражнений (Markdown)
+const ExerciseInputHelp = "Отправьте упражнения, по одному в строке:\n" +
+	"```\nЖим лежа 4x10x80\nПрисед 100кг 5x5\nПодтягивания 3x12\n```\n" +
+	"(подходы x повторения x вес, без веса - просто подходы x повторения)\n\n" +
+	"Если подходы разные - название, а под ним подходы `вес x повторения`:\n" +
+	"```\nЖим лежа\n80x10\n85x8 @8\n60x12 дроп\n```\n" +
+	"Отметки после подхода: разминка, дроп, отказ, RPE через @.\n" +
+	"Со своим весом: «Подтягивания +10кг 3x8», с помощью гравитрона - «Подтягивания 3x10 -25».\n" +
+	"Вес - в единицах из ⚙️ Настройки, «кг» или «lb» после веса задают их явно.\n" +
+	"Отдых между подходами: «Жим лежа 4x10x80 отдых 90» - после записи запустится таймер."
+
+// setTypeLabels - подписи типов подходов в истории
+var setTypeLabels = map[models.SetType]string{
+	models.SetWarmup:  "разминка",
+	models.SetDrop:    "дроп",
+	models.SetFailure: "отказ",
+}
+
+// weightFormat - как показывать вес: в единицах пользователя, а у упражнений
+// со своим весом - со знаком (отягощение или помощь)
+type weightFormat struct {
+	unit       models.WeightUnit
+	bodyweight bool
+}
+
+// exerciseFormat - формат веса упражнения для пользователя с единицами unit
+func exerciseFormat(ex *models.Exercise, unit models.WeightUnit) weightFormat {
+	return weightFormat{unit: unit, bodyweight: ex.Bodyweight}
+}
+
+// weight форматирует вес из кг: «80кг», «176.4lb», «+10кг», «-25кг»
+func (f weightFormat) weight(kg float64) string {
+	s := formatWeight(kg, f.unit)
+	if f.bodyweight && kg > 0 {
+		s = "+" + s
+	}
+	return s
+}
+
+// formatWeight переводит вес из кг в единицы unit: «80кг», «176.4lb».
+// Фунты округляются до десятых - в базе вес в кг с двумя знаками.
+func formatWeight(kg float64, unit models.WeightUnit) string {
+	v := unit.FromKg(kg)
+	if unit == models.UnitLb {
+		v = math.Round(v*10) / 10
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64) + unit.Label()
+}
+
+// formatSet форматирует подход: «80кг×10», «×12», «100кг×5 @8 (отказ)», «+10кг×8»
+func formatSet(set models.ExerciseSet, f weightFormat) string {
+	s := "×" + strconv.Itoa(set.Reps)
+	if set.Weight != 0 {
+		s = f.weight(set.Weight) + s
+	}
+	if set.RPE != nil {
+		s += " @" + strconv.FormatFloat(*set.RPE, 'f', -1, 64)
+	}
+	if label, ok := setTypeLabels[set.SetType]; ok {
+		s += " (" + label + ")"
+	}
+	return s
+}
+
+// formatSets форматирует подходы одной строкой, одинаковые подходы подряд сворачиваются
+func formatSets(sets []models.ExerciseSet, f weightFormat) string {
+	if len(sets) == 0 {
+		return "нет подходов"
+	}
+	var parts []string
+	for i := 0; i < len(sets); {
+		j := i + 1
+		for j < len(sets) && sameSet(sets[i], sets[j]) {
+			j++
+		}
+		part := formatSet(sets[i], f)
+		if n := j - i; n > 1 {
+			part += fmt.Sprintf(" (%d подх.)", n)
+		}
+		parts = append(parts, part)
+		i = j
+	}
+	return strings.Join(parts, ", ")
+}
+
+func sameSet(a, b models.ExerciseSet) bool {
+	if a.Reps != b.Reps || a.Weight != b.Weight || a.SetType != b.SetType {
+		return false
+	}
+	if a.RPE == nil || b.RPE == nil {
+		return a.RPE == nil && b.RPE == nil
+	}
+	return *a.RPE == *b.RPE
+}
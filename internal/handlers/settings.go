@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"errors"
+	"fitness-bot/internal/bot"
+	"fitness-bot/internal/models"
+	"fitness-bot/internal/parser"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
+)
+
+// Пределы своего веса, кг
+const (
+	MinBodyweightKg = 20
+	MaxBodyweightKg = 400
+)
+
+// userSettings возвращает пользователя с его настройками веса.
+// Если пользователя ещё нет в базе - настройки по умолчанию (кг, свой вес не указан).
+func userSettings(b *bot.Bot, telegramID int64) *models.User {
+	user, err := b.DB.GetUserByTelegramID(telegramID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			b.Log(telegramID).Error("Error getting user settings", "error", err)
+		}
+		return &models.User{TelegramID: telegramID, WeightUnit: models.UnitKg}
+	}
+	return user
+}
+
+// weightUnit возвращает единицы веса пользователя
+func weightUnit(b *bot.Bot, telegramID int64) models.WeightUnit {
+	return userSettings(b, telegramID).Unit()
+}
+
+// settingsText - сообщение с настройками
+func settingsText(user *models.User, notice string) bot.Text {
+	text := bot.NewText()
+	if notice != "" {
+		text = text.Line(bot.Plain(notice)).Line()
+	}
+	bodyweight := "не указан"
+	if user.BodyweightKg != nil {
+		bodyweight = formatWeight(*user.BodyweightKg, user.Unit())
+	}
+	return text.Line(bot.Bold("⚙️ Настройки")).Line().
+		Line(bot.Plain("Единицы веса: "), bot.Bold(user.Unit().Label())).
+		Line(bot.Plain("Свой вес: "), bot.Bold(bodyweight)).
+		Line(bot.Plain("Тренировки без тренера видны тренерам: "), bot.Bold(yesNo(user.ShareWorkouts))).Line().
+		Line(bot.Plain("Свой вес нужен для подтягиваний, брусьев и других упражнений с собственным весом: " +
+			"нагрузка = свой вес + отягощение (или минус помощь гравитрона).")).Line().
+		Add(bot.Plain("Тренеры всегда видят тренировки, записанные с ними. Тренировки без тренера " +
+			"они увидят в вашей истории, только если вы это разрешите."))
+}
+
+// yesNo - «да» или «нет»
+func yesNo(v bool) string {
+	if v {
+		return "да"
+	}
+	return "нет"
+}
+
+// HandleSettings показывает настройки
+func HandleSettings(b *bot.Bot, message *tgbotapi.Message) {
+	user := userSettings(b, message.From.ID)
+	b.SendInlineText(message.Chat.ID, settingsText(user, ""), bot.GetInlineSettingsKeyboard(user.Unit(), user.ShareWorkouts))
+}
+
+// ShowSettings обновляет сообщение с настройками
+func ShowSettings(b *bot.Bot, chatID int64, messageID int, telegramID int64, notice string) {
+	user := userSettings(b, telegramID)
+	keyboard := bot.GetInlineSettingsKeyboard(user.Unit(), user.ShareWorkouts)
+	b.EditText(chatID, messageID, settingsText(user, notice), &keyboard)
+}
+
+// SetWeightUnit меняет единицы веса и обновляет сообщение с настройками
+func SetWeightUnit(b *bot.Bot, chatID int64, messageID int, telegramID int64, unit models.WeightUnit) {
+	if err := b.DB.SetWeightUnit(telegramID, unit); err != nil {
+		b.Log(telegramID).Error("Error saving weight unit", "unit", unit, "error", err)
+		b.SendMessage(chatID, "❌ Ошибка при сохранении. Попробуйте позже.")
+		return
+	}
+	ShowSettings(b, chatID, messageID, telegramID, "✅ Вес теперь в "+unit.Label())
+}
+
+// ToggleShareWorkouts включает или выключает показ тренерам тренировок без тренера
+func ToggleShareWorkouts(b *bot.Bot, chatID int64, messageID int, telegramID int64) {
+	share := !userSettings(b, telegramID).ShareWorkouts
+	if err := b.DB.SetShareWorkouts(telegramID, share); err != nil {
+		b.Log(telegramID).Error("Error saving share workouts", "share", share, "error", err)
+		b.SendMessage(chatID, "❌ Ошибка при сохранении. Попробуйте позже.")
+		return
+	}
+	notice := "✅ Тренеры видят и тренировки без них."
+	if !share {
+		notice = "✅ Тренеры видят только тренировки с ними."
+	}
+	ShowSettings(b, chatID, messageID, telegramID, notice)
+}
+
+// StartBodyweightInput запрашивает свой вес
+func StartBodyweightInput(b *bot.Bot, message *tgbotapi.Message) {
+	unit := weightUnit(b, message.From.ID)
+	b.SetState(message.From.ID, "awaiting_bodyweight", map[string]interface{}{})
+	b.SendText(message.Chat.ID,
+		bot.NewText(bot.Plain("🧍 Введите свой вес в "+unit.Label()+", например 80:")),
+		bot.GetCancelKeyboard())
+}
+
+// HandleBodyweight сохраняет свой вес
+func HandleBodyweight(b *bot.Bot, message *tgbotapi.Message) {
+	user := userSettings(b, message.From.ID)
+	kg, err := parser.ParseWeight(message.Text, user.Unit())
+	if err != nil {
+		b.SendWithCancel(message.Chat.ID, "⚠️ "+err.Error())
+		return
+	}
+	if kg < MinBodyweightKg || kg > MaxBodyweightKg {
+		b.SendWithCancel(message.Chat.ID, "⚠️ Свой вес должен быть от "+formatWeight(MinBodyweightKg, user.Unit())+
+			" до "+formatWeight(MaxBodyweightKg, user.Unit())+".")
+		return
+	}
+	if err := b.DB.SetBodyweight(message.From.ID, kg); err != nil {
+		b.Log(message.From.ID).Error("Error saving bodyweight", "error", err)
+		b.SendWithCancel(message.Chat.ID, "❌ Ошибка при сохранении. Попробуйте позже.")
+		return
+	}
+
+	b.ClearState(message.From.ID)
+	accessInfo, _ := b.DB.GetUserAccessInfo(message.From.ID, message.From.UserName)
+	b.SendText(message.Chat.ID,
+		bot.NewText(bot.Plain("✅ Свой вес: "), bot.Bold(formatWeight(kg, user.Unit()))),
+		bot.GetStartMenuKeyboard(accessInfo))
+}
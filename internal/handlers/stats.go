@@ -3,8 +3,11 @@ package handlers
 import (
 	"fitness-bot/internal/bot"
 	"fitness-bot/internal/charts"
+	"fitness-bot/internal/models"
 	"fmt"
-	"log"
+	"math"
+	"sort"
+	"strings"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -24,66 +27,311 @@ func HandleStats(b *bot.Bot, message *tgbotapi.Message) {
 func HandleExerciseNameForStats(b *bot.Bot, message *tgbotapi.Message) {
 	state := b.GetState(message.From.ID)
 
-	if message.Text == "❌ Отмена" {
+	telegramID := state.Data["telegram_id"].(int64)
+
+	period := recentStats(time.Now())
+
+	// Ищем по справочнику: «жим лёжа», «Жим лежа » и синонимы - одно упражнение
+	match, catalogIDs, err := b.DB.FindClientCatalogExercises(telegramID, message.Text)
+	if err != nil {
+		b.Log(message.From.ID).Error("Error matching exercise for stats", "error", err)
+		b.SendMessage(message.Chat.ID, "❌ Ошибка при получении статистики.")
+		return
+	}
+	if match == nil {
+		b.SendText(message.Chat.ID, bot.NewText(bot.Plain("Упражнение «"+message.Text+"» не найдено в ваших тренировках.")), nil)
 		b.ClearState(message.From.ID)
-		// Определяем клавиатуру на основе доступов
-		accessInfo, _ := b.DB.GetUserAccessInfo( message.From.ID, message.From.UserName)
-		b.SendMessageWithKeyboard(message.Chat.ID, "Отменено.", bot.GetStartMenuKeyboard(accessInfo))
 		return
 	}
+	exerciseName := match.Entry.Name
 
-	exerciseName := message.Text
-	telegramID := state.Data["telegram_id"].(int64)
-
-	from := time.Now().AddDate(0, -3, 0)
-	to := time.Now()
-
-	exercises, err := b.DB.GetExerciseStats(telegramID, exerciseName, from, to)
+	exercises, err := b.DB.GetExerciseStats(models.WorkoutScope{ClientTelegramID: telegramID}, catalogIDs, period.from, period.to)
 	if err != nil {
-		log.Printf("Error getting exercise stats: %v", err)
+		b.Log(message.From.ID).Error("Error getting exercise stats", "catalog_id", match.Entry.ID, "error", err)
 		b.SendMessage(message.Chat.ID, "❌ Ошибка при получении статистики.")
 		return
 	}
 
 	if len(exercises) == 0 {
-		b.SendMessage(message.Chat.ID, fmt.Sprintf("Упражнение '%s' не найдено в ваших тренировках.", exerciseName))
+		b.SendText(message.Chat.ID, bot.NewText(bot.Plain("Упражнение «"+exerciseName+"» не выполнялось последние 3 месяца.")), nil)
 		b.ClearState(message.From.ID)
 		return
 	}
 
-	chartData, err := charts.GenerateProgressChart(exercises, exerciseName)
+	unit := weightUnit(b, message.From.ID)
+	sendExerciseCharts(b, message.Chat.ID, message.From.ID, exercises, exerciseName, unit, period)
+
+	text := bot.NewText()
+	if match.Fuzzy {
+		text = text.Line(bot.Plain("🔎 Похоже, вы имели в виду «" + exerciseName + "»")).Line()
+	}
+	text = text.Add(exerciseStatsText(exercises, unit, period)...)
+
+	b.ClearState(message.From.ID)
+	accessInfo, _ := b.DB.GetUserAccessInfo(message.From.ID, message.From.UserName)
+	b.SendText(message.Chat.ID, text, bot.GetStartMenuKeyboard(accessInfo))
+}
+
+// statsPeriod - период статистики и подпись к нему
+type statsPeriod struct {
+	from, to time.Time // from нулевой - с самой первой тренировки
+	label    string    // «за последние 3 месяца»
+}
+
+// recentStats - обычный период статистики: последние 3 месяца
+func recentStats(now time.Time) statsPeriod {
+	return statsPeriod{from: now.AddDate(0, -3, 0), to: now, label: "за последние 3 месяца"}
+}
+
+// sendExerciseCharts отправляет графики прогресса упражнения за период - силового или кардио.
+// exercises - выполнения от новых к старым.
+func sendExerciseCharts(b *bot.Bot, chatID, userID int64, exercises []*models.Exercise, exerciseName string, unit models.WeightUnit, period statsPeriod) {
+	if exercises[0].Cardio != nil {
+		sendCardioCharts(b, chatID, userID, exercises, exerciseName, period)
+		return
+	}
+	chartData, err := charts.GenerateProgressChart(exercises, exerciseName, unit)
 	if err != nil {
-		log.Printf("Error generating chart: %v", err)
-		b.SendMessage(message.Chat.ID, "❌ Ошибка при создании графика.")
+		b.Log(userID).Error("Error generating chart", "error", err)
+		b.SendMessage(chatID, "❌ Ошибка при создании графика.")
 		return
 	}
+	sendChart(b, chatID, chartData, "progress.png",
+		fmt.Sprintf("📊 Прогресс по упражнению '%s' %s", exerciseName, period.label))
+}
+
+// exerciseStatsText - последний результат и лучший подход упражнения за период (у кардио - рекорды).
+// exercises - выполнения от новых к старым.
+func exerciseStatsText(exercises []*models.Exercise, unit models.WeightUnit, period statsPeriod) bot.Text {
+	if exercises[0].Cardio != nil {
+		return cardioStatsText(exercises, period)
+	}
 
-	if chartData != nil {
-		photoBytes := tgbotapi.FileBytes{
-			Name:  "progress.png",
-			Bytes: chartData,
+	latest := exercises[0]
+	text := bot.NewText(bot.Plain("📈 "), bot.Bold("Последний результат:"), bot.Plain("\n"))
+	text = text.Line(bot.Plain("Подходы: " + formatExerciseResult(latest, unit)))
+	if volume := latest.Volume(); volume > 0 {
+		text = text.Line(bot.Plain(fmt.Sprintf("Тоннаж: %.0f %s", unit.FromKg(volume), unit.Label())))
+	}
+	text = text.Line(bot.Plain("Дата: " + latest.CreatedAt.Format("02.01.2006")))
+
+	// Лучший подход за период - с наибольшей нагрузкой (со своим весом - с учётом своего веса)
+	best, bestExercise := bestSet(exercises)
+	if best != nil {
+		text = text.Line().Add(bot.Plain("🏆 Лучший подход: " + formatSet(*best, exerciseFormat(bestExercise, unit)) +
+			" (" + bestExercise.CreatedAt.Format("02.01.2006") + ")"))
+	}
+	return text
+}
+
+// bestSet возвращает подход с наибольшей нагрузкой среди выполнений упражнения и само выполнение
+func bestSet(exercises []*models.Exercise) (*models.ExerciseSet, *models.Exercise) {
+	var best *models.ExerciseSet
+	var bestExercise *models.Exercise
+	for _, ex := range exercises {
+		if top := ex.TopSet(); top != nil && (best == nil || ex.Load(*top) > bestExercise.Load(*best)) {
+			best, bestExercise = top, ex
 		}
-		photo := tgbotapi.NewPhoto(message.Chat.ID, photoBytes)
-		photo.Caption = fmt.Sprintf("📊 Прогресс по упражнению '%s' за последние 3 месяца", exerciseName)
-		b.API.Send(photo)
+	}
+	return best, bestExercise
+}
+
+// ClientStatsBestLifts - сколько лучших подходов показывать в сводке по клиенту
+const ClientStatsBestLifts = 5
+
+// HandleClientStats присылает тренеру сводку по клиенту и список его упражнений для графиков
+func HandleClientStats(b *bot.Bot, message *tgbotapi.Message, trainerClientID int64) {
+	text, keyboard, err := clientStats(b, message.From, trainerClientID)
+	if err != nil {
+		historyError(b, message.Chat.ID, message.From, err)
+		return
+	}
+	b.SendInlineText(message.Chat.ID, text, keyboard)
+}
+
+// ShowClientStats показывает сводку по связи тренер-клиент в сообщении messageID:
+// тренеру - по клиенту, клиенту - по тренировкам с архивным тренером
+func ShowClientStats(b *bot.Bot, chatID int64, messageID int, user *tgbotapi.User, trainerClientID int64) {
+	text, keyboard, err := clientStats(b, user, trainerClientID)
+	if err != nil {
+		historyError(b, chatID, user, err)
+		return
+	}
+	b.EditText(chatID, messageID, text, &keyboard)
+}
+
+// clientStats собирает сводку по тренировкам связи тренер-клиент и клавиатуру выбора упражнения.
+// Видно то же, что и в истории этой связи.
+func clientStats(b *bot.Bot, user *tgbotapi.User, trainerClientID int64) (bot.Text, tgbotapi.InlineKeyboardMarkup, error) {
+	owner, err := resolveHistory(b, user, bot.HistoryPosition{Client: trainerClientID})
+	if err != nil {
+		return nil, tgbotapi.InlineKeyboardMarkup{}, err
+	}
+	period := owner.statsPeriod(time.Now())
+	workouts, err := b.DB.GetFullWorkoutHistory(owner.scope, models.WorkoutFilter{From: period.from})
+	if err != nil {
+		return nil, tgbotapi.InlineKeyboardMarkup{}, err
+	}
+	entries, err := b.DB.GetPerformedCatalogExercises(owner.scope)
+	if err != nil {
+		return nil, tgbotapi.InlineKeyboardMarkup{}, err
 	}
 
-	var statsText string
+	title := "📊 Статистика "
+	if owner.archive != nil {
+		title = "📊 Тренировки с "
+	}
+	text := bot.NewText(bot.Bold(title), bot.Mention(owner.counterpart()), bot.Bold(" "+period.label)).Line().Line()
+
+	// Весь архив - считаем недели от первой тренировки до последней
+	weeks := period.to.Sub(period.from).Hours() / (24 * 7)
+	if period.from.IsZero() && len(workouts) > 0 {
+		weeks = workouts[len(workouts)-1].Date.Sub(workouts[0].Date).Hours() / (24 * 7)
+	}
+	text = text.Add(workoutSummaryText(workouts, math.Max(weeks, 1), weightUnit(b, user.ID))...)
+
+	exercises := uniqueCatalogNames(entries)
+	text = text.Line()
 	if len(exercises) > 0 {
-		latest := exercises[0]
-		statsText = fmt.Sprintf("\n📈 *Последний результат:*\n"+
-			"Вес: %.1f кг\n"+
-			"Подходы: %d\n"+
-			"Повторения: %d\n"+
-			"Дата: %s",
-			latest.Weight,
-			latest.Sets,
-			latest.Reps,
-			latest.CreatedAt.Format("02.01.2006"),
-		)
+		text = text.Add(bot.Plain("Выберите упражнение, чтобы посмотреть график прогресса:"))
+	} else {
+		text = text.Add(bot.Plain("Упражнений из справочника в тренировках пока нет - графики строить не по чему."))
 	}
+	return text, bot.GetInlineClientStatsKeyboard(trainerClientID, exercises), nil
+}
 
-	b.ClearState(message.From.ID)
-	accessInfo, _ := b.DB.GetUserAccessInfo( message.From.ID, message.From.UserName)
-	b.SendMessageWithKeyboard(message.Chat.ID, statsText, bot.GetStartMenuKeyboard(accessInfo))
+// uniqueCatalogNames оставляет по одному упражнению на название
+// (одноимённые упражнения разных организаций считаются одним)
+func uniqueCatalogNames(entries []*models.CatalogExercise) []*models.CatalogExercise {
+	seen := make(map[string]bool, len(entries))
+	var unique []*models.CatalogExercise
+	for _, e := range entries {
+		if !seen[e.NormalizedName] {
+			seen[e.NormalizedName] = true
+			unique = append(unique, e)
+		}
+	}
+	return unique
+}
+
+// bestLift - лучший подход в упражнении за период
+type bestLift struct {
+	exercise *models.Exercise
+	set      *models.ExerciseSet
+	date     time.Time
+}
+
+// workoutSummaryText - сводка по тренировкам за weeks недель: сколько тренировок в неделю,
+// общий объём, кардио-дистанция и лучшие подходы
+func workoutSummaryText(workouts []*models.Workout, weeks float64, unit models.WeightUnit) bot.Text {
+	if len(workouts) == 0 {
+		return bot.NewText().Line(bot.Plain("Тренировок за этот период нет."))
+	}
+
+	var volume, distance float64
+	best := make(map[string]*bestLift)
+	for _, w := range workouts {
+		for i := range w.Exercises {
+			ex := &w.Exercises[i]
+			if ex.Cardio != nil {
+				distance += ex.Cardio.DistanceKm
+				continue
+			}
+			volume += ex.Volume()
+			top := ex.TopSet()
+			if top == nil || ex.Load(*top) <= 0 {
+				continue
+			}
+			key := strings.ToLower(ex.Name)
+			if cur, ok := best[key]; !ok || ex.Load(*top) > cur.exercise.Load(*cur.set) {
+				best[key] = &bestLift{exercise: ex, set: top, date: w.Date}
+			}
+		}
+	}
+
+	text := bot.NewText().Line(
+		bot.Plain("🗓 Тренировок: "), bot.Bold(fmt.Sprintf("%d", len(workouts))),
+		bot.Plain(fmt.Sprintf(" (≈%.1f в неделю)", float64(len(workouts))/weeks)),
+	)
+	if volume > 0 {
+		text = text.Line(bot.Plain("🏋️ Общий объём: "), bot.Bold(formatWeight(volume, unit)))
+	}
+	if distance > 0 {
+		text = text.Line(bot.Plain("🏃 Кардио: "), bot.Bold(formatDistance(distance)))
+	}
+	text = text.Line(bot.Plain("Последняя тренировка: " + workouts[len(workouts)-1].Date.Format("02.01.2006")))
+
+	lifts := make([]*bestLift, 0, len(best))
+	for _, lift := range best {
+		lifts = append(lifts, lift)
+	}
+	sort.Slice(lifts, func(i, j int) bool {
+		li, lj := lifts[i].exercise.Load(*lifts[i].set), lifts[j].exercise.Load(*lifts[j].set)
+		if li != lj {
+			return li > lj
+		}
+		return lifts[i].exercise.Name < lifts[j].exercise.Name
+	})
+	if len(lifts) > ClientStatsBestLifts {
+		lifts = lifts[:ClientStatsBestLifts]
+	}
+	if len(lifts) > 0 {
+		text = text.Line().Line(bot.Bold("🏆 Лучшие подходы:"))
+		for _, lift := range lifts {
+			text = text.Line(bot.Plain(fmt.Sprintf("• %s: %s (%s)",
+				lift.exercise.Name, formatSet(*lift.set, exerciseFormat(lift.exercise, unit)), lift.date.Format("02.01"))))
+		}
+	}
+	return text
+}
+
+// ShowClientExerciseStats присылает графики прогресса в упражнении справочника catalogID
+// по тренировкам связи тренер-клиент и последний и лучший результаты за период
+func ShowClientExerciseStats(b *bot.Bot, chatID int64, user *tgbotapi.User, trainerClientID, catalogID int64) {
+	owner, err := resolveHistory(b, user, bot.HistoryPosition{Client: trainerClientID})
+	if err != nil {
+		historyError(b, chatID, user, err)
+		return
+	}
+	entries, err := b.DB.GetPerformedCatalogExercises(owner.scope)
+	if err != nil {
+		b.Log(user.ID).Error("Error getting client exercises", "trainer_client_id", trainerClientID, "error", err)
+		b.SendMessage(chatID, "❌ Ошибка при получении статистики.")
+		return
+	}
+	var entry *models.CatalogExercise
+	for _, e := range entries {
+		if e.ID == catalogID {
+			entry = e
+		}
+	}
+	if entry == nil {
+		b.SendMessage(chatID, "❌ Клиент не выполнял это упражнение.")
+		return
+	}
+	var catalogIDs []int64
+	for _, e := range entries {
+		if e.NormalizedName == entry.NormalizedName {
+			catalogIDs = append(catalogIDs, e.ID)
+		}
+	}
+
+	period := owner.statsPeriod(time.Now())
+	exercises, err := b.DB.GetExerciseStats(owner.scope, catalogIDs, period.from, period.to)
+	if err != nil {
+		b.Log(user.ID).Error("Error getting exercise stats", "catalog_id", catalogID, "error", err)
+		b.SendMessage(chatID, "❌ Ошибка при получении статистики.")
+		return
+	}
+	keyboard := bot.GetInlineClientExerciseStatsKeyboard(trainerClientID)
+	if len(exercises) == 0 {
+		b.SendInlineText(chatID, bot.NewText(bot.Plain("Упражнение «"+entry.Name+"» не выполнялось "+period.label+".")), keyboard)
+		return
+	}
+
+	unit := weightUnit(b, user.ID)
+	sendExerciseCharts(b, chatID, user.ID, exercises, entry.Name, unit, period)
+	text := bot.NewText(bot.Bold(entry.Name), bot.Plain(" - "), bot.Mention(owner.counterpart())).Line().Line().
+		Add(exerciseStatsText(exercises, unit, period)...)
+	b.SendInlineText(chatID, text, keyboard)
 }
@@ -0,0 +1,627 @@
+package handlers
+
+import (
+	"errors"
+	"fitness-bot/internal/bot"
+	"fitness-bot/internal/catalog"
+	"fitness-bot/internal/models"
+	"fitness-bot/internal/parser"
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
+)
+
+// templateInputHelp - подсказка по вводу упражнений шаблона
+func templateInputHelp() bot.Text {
+	return bot.NewText(
+		bot.Plain("Отправьте упражнения с целью, по одному в строке:\n"),
+		bot.Pre("Жим лежа 4x10x80\nПрисед 100кг 5x5\nПодтягивания 3x12"),
+		bot.Plain("\n(подходы x повторения x вес, без веса - просто подходы x повторения)"),
+	)
+}
+
+// ====== ТРЕНЕР: ШАБЛОНЫ ======
+
+// HandleTemplates показывает шаблоны организации, в которой работает тренер
+func HandleTemplates(b *bot.Bot, message *tgbotapi.Message) {
+	state := b.GetState(message.From.ID)
+	if state == nil {
+		b.SendMessage(message.Chat.ID, "❌ Сначала выберите организацию.")
+		return
+	}
+	orgID, okID := bot.GetStateInt64(state.Data, "org_id")
+	orgName, okName := bot.GetStateString(state.Data, "org_name")
+	if !okID || !okName {
+		b.SendMessage(message.Chat.ID, "❌ Сначала выберите организацию.")
+		return
+	}
+
+	templates, err := b.DB.GetOrganizationTemplates(orgID)
+	if err != nil {
+		b.Log(message.From.ID).Error("Error getting templates", "org_id", orgID, "error", err)
+		b.SendMessage(message.Chat.ID, "❌ Ошибка при получении шаблонов.")
+		return
+	}
+
+	b.CleanupMessages(message.Chat.ID, message.From.ID)
+	msgID := b.SendInlineText(message.Chat.ID, templateListText(orgName, templates, ""), bot.GetInlineTemplateListKeyboard(templates))
+	b.StoreMessageID(message.From.ID, msgID)
+}
+
+// ShowTemplateList показывает шаблоны организации в сообщении messageID
+func ShowTemplateList(b *bot.Bot, chatID int64, messageID int, user *tgbotapi.User, orgID int64, notice string) {
+	templates, err := b.DB.GetOrganizationTemplates(orgID)
+	if err != nil {
+		b.Log(user.ID).Error("Error getting templates", "org_id", orgID, "error", err)
+		b.SendMessage(chatID, "❌ Ошибка при получении шаблонов.")
+		return
+	}
+	orgName := ""
+	if org, err := b.DB.GetOrganizationByID(orgID); err == nil {
+		orgName = org.Name
+	}
+	keyboard := bot.GetInlineTemplateListKeyboard(templates)
+	b.EditText(chatID, messageID, templateListText(orgName, templates, notice), &keyboard)
+}
+
+// templateListText - заголовок списка шаблонов
+func templateListText(orgName string, templates []*models.WorkoutTemplate, notice string) bot.Text {
+	text := bot.NewText()
+	if notice != "" {
+		text = text.Line(bot.Plain(notice)).Line()
+	}
+	text = text.Add(bot.Plain("📋 "), bot.Bold("Шаблоны тренировок "+orgName), bot.Plain("\n\n"))
+	if len(templates) == 0 {
+		return text.Add(bot.Plain("Шаблонов пока нет. Создайте первый - затем его можно назначить клиентам."))
+	}
+	return text.Add(bot.Plain("Выберите шаблон или создайте новый:"))
+}
+
+// EditableTemplate загружает шаблон, если пользователь - активный тренер его организации.
+// Возвращает шаблон и ID тренера в организации.
+func EditableTemplate(b *bot.Bot, chatID int64, user *tgbotapi.User, templateID int64) (*models.WorkoutTemplate, int64, bool) {
+	template, err := b.DB.GetTemplateByID(templateID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			b.Log(user.ID).Error("Error getting template", "template_id", templateID, "error", err)
+		}
+		b.SendMessage(chatID, "❌ Шаблон не найден или уже удалён.")
+		return nil, 0, false
+	}
+
+	trainerID, err := b.DB.GetTemplateTrainerID(template, user.ID, user.UserName)
+	if err != nil {
+		b.Log(user.ID).Error("Error checking template access", "template_id", templateID, "error", err)
+		b.SendMessage(chatID, "❌ Ошибка. Попробуйте позже.")
+		return nil, 0, false
+	}
+	if trainerID == 0 {
+		b.SendMessage(chatID, "❌ У вас нет доступа к этому шаблону.")
+		return nil, 0, false
+	}
+	return template, trainerID, true
+}
+
+// templateText - карточка шаблона, вес - в единицах unit
+func templateText(template *models.WorkoutTemplate, unit models.WeightUnit) bot.Text {
+	text := bot.NewText(bot.Bold("📋 "+template.Name), bot.Plain("\n"))
+	text = text.Line(bot.Plain("Группы мышц: " + models.MuscleGroupNames(template.MuscleGroups))).Line()
+	if len(template.Exercises) == 0 {
+		text = text.Line(bot.Plain("Упражнений нет."))
+	}
+	for i, ex := range template.Exercises {
+		text = text.Line(bot.Plain(fmt.Sprintf("%d. %s: %s", i+1, ex.Name, formatSets(ex.TargetSetList(), weightFormat{unit: unit}))))
+	}
+	return text
+}
+
+// ShowTemplateCard показывает шаблон с кнопками назначения и удаления
+func ShowTemplateCard(b *bot.Bot, chatID int64, messageID int, user *tgbotapi.User, template *models.WorkoutTemplate, notice string) {
+	text := bot.NewText()
+	if notice != "" {
+		text = text.Line(bot.Plain(notice)).Line()
+	}
+	keyboard := bot.GetInlineTemplateKeyboard(template.ID)
+	b.EditText(chatID, messageID, text.Add(templateText(template, weightUnit(b, user.ID))...), &keyboard)
+}
+
+// ShowTemplateAssign показывает клиентов тренера с отметкой, кому назначен шаблон
+func ShowTemplateAssign(b *bot.Bot, chatID int64, messageID int, user *tgbotapi.User, template *models.WorkoutTemplate, trainerID int64) {
+	clients, err := b.DB.GetTrainerClients(trainerID)
+	if err != nil {
+		b.Log(user.ID).Error("Error getting clients", "trainer_id", trainerID, "error", err)
+		b.SendMessage(chatID, "❌ Ошибка при получении списка клиентов.")
+		return
+	}
+	assignees, err := b.DB.GetTemplateAssignees(template.ID)
+	if err != nil {
+		b.Log(user.ID).Error("Error getting template assignees", "template_id", template.ID, "error", err)
+		b.SendMessage(chatID, "❌ Ошибка при получении назначений.")
+		return
+	}
+
+	assigned := make(map[int64]bool, len(assignees))
+	for _, id := range assignees {
+		assigned[id] = true
+	}
+	var active []*models.ClientWithInfo
+	for _, c := range clients {
+		if c.Client.IsActive {
+			active = append(active, c)
+		}
+	}
+
+	text := bot.NewText(bot.Plain("👤 "), bot.Bold("Назначение шаблона «"+template.Name+"»"), bot.Plain("\n\n"))
+	if len(active) == 0 {
+		text = text.Add(bot.Plain("У вас пока нет активных клиентов."))
+	} else {
+		text = text.Add(bot.Plain("✅ - шаблон назначен. Нажмите на клиента, чтобы назначить или снять."))
+	}
+	keyboard := bot.GetInlineTemplateAssignKeyboard(template.ID, active, assigned)
+	b.EditText(chatID, messageID, text, &keyboard)
+}
+
+// ToggleTemplateAssignment назначает шаблон клиенту тренера или снимает назначение
+func ToggleTemplateAssignment(b *bot.Bot, chatID int64, messageID int, user *tgbotapi.User, template *models.WorkoutTemplate, trainerID, trainerClientID int64) {
+	client, err := b.DB.GetTrainerClientByID(trainerClientID)
+	if err != nil || client.TrainerID != trainerID || !client.IsActive {
+		b.SendMessage(chatID, "❌ Клиент не найден.")
+		return
+	}
+
+	assigned, err := b.DB.IsTemplateAssigned(template.ID, client.ID)
+	if err == nil {
+		if assigned {
+			err = b.DB.UnassignTemplate(template.ID, client.ID)
+		} else {
+			err = b.DB.AssignTemplate(template.ID, client.ID)
+		}
+	}
+	if err != nil {
+		b.Log(user.ID).Error("Error toggling template assignment", "template_id", template.ID, "trainer_client_id", client.ID, "error", err)
+		b.SendMessage(chatID, "❌ Ошибка при назначении шаблона.")
+		return
+	}
+
+	if !assigned && client.TelegramID != nil {
+		b.SendText(*client.TelegramID,
+			bot.NewText(bot.Plain("📋 Тренер назначил вам программу "), bot.Bold(template.Name),
+				bot.Plain(".\n\nНачать её можно в меню тренировок: «📋 Программы тренера».")),
+			nil)
+	}
+	ShowTemplateAssign(b, chatID, messageID, user, template, trainerID)
+}
+
+// StartTemplateCreation начинает создание шаблона: запрашивает название
+func StartTemplateCreation(b *bot.Bot, message *tgbotapi.Message) {
+	state := b.GetState(message.From.ID)
+	if state == nil || state.State != "trainer_managing_org" {
+		b.SendMessage(message.Chat.ID, "❌ Откройте панель тренера и попробуйте снова.")
+		return
+	}
+
+	b.CleanupMessages(message.Chat.ID, message.From.ID)
+	b.SetState(message.From.ID, "template_creating_name", bot.CopyStateData(state.Data))
+	b.SendMessageWithKeyboard(message.Chat.ID, "📋 *Новый шаблон*\n\nВведите название (например: «Грудь и трицепс - база»):", bot.GetCancelKeyboard())
+}
+
+// HandleTemplateName сохраняет название шаблона и запрашивает группу мышц
+func HandleTemplateName(b *bot.Bot, message *tgbotapi.Message) {
+	state := b.GetState(message.From.ID)
+
+	name := catalog.Clean(message.Text)
+	if name == "" || len([]rune(name)) > 255 {
+		b.SendWithCancel(message.Chat.ID, "⚠️ Название должно быть от 1 до 255 символов.")
+		return
+	}
+
+	groups, err := b.DB.GetMuscleGroups(StateOrgID(state.Data))
+	if err != nil {
+		b.Log(message.From.ID).Error("Error getting muscle groups", "error", err)
+		b.SendMessage(message.Chat.ID, "❌ Ошибка. Попробуйте позже.")
+		return
+	}
+
+	data := bot.CopyStateData(state.Data)
+	data["template_name"] = name
+	data["muscle_group_ids"] = []int64{}
+	b.SetState(message.From.ID, "template_selecting_muscle", data)
+	b.SendText(message.Chat.ID, SelectedMuscleGroupsText(groups, nil), bot.GetMuscleGroupKeyboard(groups, nil))
+}
+
+// HandleTemplateMuscleGroup отмечает группы мышц шаблона, по «Готово» создаёт шаблон
+// и переходит к добавлению упражнений
+func HandleTemplateMuscleGroup(b *bot.Bot, message *tgbotapi.Message) {
+	state := b.GetState(message.From.ID)
+
+	groups, err := b.DB.GetMuscleGroups(StateOrgID(state.Data))
+	if err != nil {
+		b.Log(message.From.ID).Error("Error getting muscle groups", "error", err)
+		b.SendMessage(message.Chat.ID, "❌ Ошибка. Попробуйте позже.")
+		return
+	}
+	selected := bot.GetStateInt64s(state.Data, "muscle_group_ids")
+
+	if message.Text != bot.MuscleGroupsDoneText {
+		group := findMuscleGroupButton(groups, selected, message.Text)
+		if group == nil {
+			b.SendMessageWithKeyboard(message.Chat.ID, "⚠️ Пожалуйста, выберите группу мышц из кнопок:", bot.GetMuscleGroupKeyboard(groups, selected))
+			return
+		}
+		selected = ToggleID(selected, group.ID)
+		data := bot.CopyStateData(state.Data)
+		data["muscle_group_ids"] = selected
+		b.SetState(message.From.ID, state.State, data)
+		b.SendText(message.Chat.ID, SelectedMuscleGroupsText(groups, selected), bot.GetMuscleGroupKeyboard(groups, selected))
+		return
+	}
+
+	trainerID, okT := bot.GetStateInt64(state.Data, "trainer_id")
+	orgID, okID := bot.GetStateInt64(state.Data, "org_id")
+	name, okName := bot.GetStateString(state.Data, "template_name")
+	if !okT || !okID || !okName {
+		b.ClearState(message.From.ID)
+		b.SendMessage(message.Chat.ID, "❌ Ошибка состояния. Попробуйте снова.")
+		return
+	}
+
+	muscleGroups, err := b.DB.GetMuscleGroupsByIDs(selected, &orgID)
+	if err != nil {
+		b.Log(message.From.ID).Error("Error getting muscle groups", "error", err)
+		b.SendMessage(message.Chat.ID, "❌ Ошибка при создании шаблона. Попробуйте позже.")
+		return
+	}
+	if len(muscleGroups) == 0 {
+		b.SendMessage(message.Chat.ID, "⚠️ Выберите хотя бы одну группу мышц.")
+		return
+	}
+
+	template := &models.WorkoutTemplate{
+		OrganizationID: orgID,
+		TrainerID:      &trainerID,
+		Name:           name,
+		MuscleGroups:   muscleGroups,
+	}
+	if err := b.DB.CreateTemplate(template); err != nil {
+		b.Log(message.From.ID).Error("Error creating template", "org_id", orgID, "error", err)
+		b.SendMessage(message.Chat.ID, "❌ Ошибка при создании шаблона. Попробуйте позже.")
+		return
+	}
+
+	data := bot.CopyStateData(state.Data)
+	delete(data, "template_name")
+	delete(data, "muscle_group_ids")
+	data["template_id"] = template.ID
+	b.SetState(message.From.ID, "template_adding_exercises", data)
+	text := bot.NewText(bot.Plain("✅ Шаблон "), bot.Bold(name), bot.Plain(" создан.\n\n")).
+		Add(templateInputHelp()...).
+		Add(bot.Plain("\n\nКогда закончите, нажмите «✅ Сохранить шаблон»."))
+	b.SendText(message.Chat.ID, text, bot.GetTemplateEditKeyboard())
+}
+
+// HandleTemplateExercise добавляет упражнения в создаваемый шаблон
+func HandleTemplateExercise(b *bot.Bot, message *tgbotapi.Message) {
+	state := b.GetState(message.From.ID)
+
+	templateID, okTpl := bot.GetStateInt64(state.Data, "template_id")
+	orgID, okID := bot.GetStateInt64(state.Data, "org_id")
+	if !okTpl || !okID {
+		b.ClearState(message.From.ID)
+		b.SendMessage(message.Chat.ID, "❌ Ошибка состояния. Попробуйте снова.")
+		return
+	}
+
+	switch message.Text {
+	case "✅ Сохранить шаблон":
+		finishTemplateCreation(b, message, state, templateID, false)
+		return
+	case bot.CancelText:
+		finishTemplateCreation(b, message, state, templateID, true)
+		return
+	}
+
+	unit := weightUnit(b, message.From.ID)
+	parsed, err := parser.Parse(message.Text, unit)
+	if err != nil {
+		b.SendText(message.Chat.ID, bot.NewText(bot.Plain("❌ Не удалось разобрать упражнение: "+err.Error()+"\n\n")).Add(templateInputHelp()...), nil)
+		return
+	}
+	// Цель шаблона - одинаковые подходы, проверяем всё до сохранения
+	for _, p := range parsed {
+		if !uniformSets(p.Sets) {
+			b.SendText(message.Chat.ID, bot.NewText(bot.Plain("⚠️ «"+p.Name+"»: в шаблоне все подходы упражнения одинаковые. "+
+				"Запишите цель одной строкой, например «"+p.Name+" 4x10x80».")), nil)
+			return
+		}
+	}
+
+	template, err := b.DB.GetTemplateByID(templateID)
+	if err != nil {
+		b.Log(message.From.ID).Error("Error getting template", "template_id", templateID, "error", err)
+		b.SendMessage(message.Chat.ID, "❌ Шаблон не найден. Начните заново.")
+		return
+	}
+
+	text := bot.NewText()
+	for _, p := range parsed {
+		exercise := &models.TemplateExercise{
+			TemplateID:   templateID,
+			Name:         p.Name,
+			TargetSets:   len(p.Sets),
+			TargetReps:   p.Sets[0].Reps,
+			TargetWeight: p.Sets[0].Weight,
+		}
+		if match, err := b.DB.ResolveCatalogExercise(p.Name, &orgID, primaryMuscleGroup(template.MuscleGroups)); err != nil {
+			b.Log(message.From.ID).Error("Error resolving catalog exercise", "name", p.Name, "error", err)
+		} else {
+			exercise.CatalogID = &match.Entry.ID
+		}
+
+		if err := b.DB.AddTemplateExercise(exercise); err != nil {
+			b.Log(message.From.ID).Error("Error adding template exercise", "template_id", templateID, "error", err)
+			text = text.Line(bot.Plain("❌ "), bot.Bold(p.Name), bot.Plain(": ошибка при сохранении"))
+			continue
+		}
+		text = text.Line(bot.Plain("✅ "), bot.Bold(p.Name), bot.Plain(": "+formatSets(p.Sets, weightFormat{unit: unit, bodyweight: p.Bodyweight})))
+	}
+	b.SendText(message.Chat.ID, text.Add(bot.Plain("\nДобавьте ещё или нажмите «✅ Сохранить шаблон».")), nil)
+}
+
+// finishTemplateCreation завершает создание шаблона и возвращает в панель тренера.
+// Отменённый или пустой шаблон удаляется.
+func finishTemplateCreation(b *bot.Bot, message *tgbotapi.Message, state *models.UserState, templateID int64, cancelled bool) {
+	template, err := b.DB.GetTemplateByID(templateID)
+	if err != nil {
+		b.Log(message.From.ID).Error("Error getting template", "template_id", templateID, "error", err)
+	}
+
+	notice := "✅ Шаблон сохранён. Назначить его клиентам можно в «📋 Шаблоны тренировок»."
+	if template != nil && (cancelled || len(template.Exercises) == 0) {
+		if err := b.DB.DeleteTemplate(templateID); err != nil {
+			b.Log(message.From.ID).Error("Error deleting template", "template_id", templateID, "error", err)
+		}
+		notice = "Шаблон без упражнений не сохранён."
+		if cancelled {
+			notice = "❌ Создание шаблона отменено."
+		}
+	}
+
+	trainerID, _ := bot.GetStateInt64(state.Data, "trainer_id")
+	orgID, _ := bot.GetStateInt64(state.Data, "org_id")
+	orgName, _ := bot.GetStateString(state.Data, "org_name")
+	ShowTrainerOrgMenu(b, message, trainerID, orgID, orgName)
+	b.SendMessageWithKeyboard(message.Chat.ID, notice, bot.GetTrainerMenuKeyboard())
+}
+
+// uniformSets проверяет, что все подходы одинаковые рабочие
+func uniformSets(sets []models.ExerciseSet) bool {
+	for _, set := range sets {
+		if set.SetType != models.SetWorking || set.RPE != nil || set.Reps != sets[0].Reps || set.Weight != sets[0].Weight {
+			return false
+		}
+	}
+	return len(sets) > 0
+}
+
+// ====== КЛИЕНТ: ТРЕНИРОВКА ПО ШАБЛОНУ ======
+
+// HandleAssignedTemplates показывает программы, назначенные клиенту текущим тренером
+func HandleAssignedTemplates(b *bot.Bot, message *tgbotapi.Message) {
+	state := b.GetState(message.From.ID)
+	if state == nil {
+		b.SendMessage(message.Chat.ID, "❌ Сначала выберите тренера.")
+		return
+	}
+	trainerClientID, ok := bot.GetStateInt64(state.Data, "trainer_client_id")
+	if !ok {
+		b.SendMessage(message.Chat.ID, "❌ Сначала выберите тренера.")
+		return
+	}
+
+	templates, err := b.DB.GetAssignedTemplates(trainerClientID)
+	if err != nil {
+		b.Log(message.From.ID).Error("Error getting assigned templates", "trainer_client_id", trainerClientID, "error", err)
+		b.SendMessage(message.Chat.ID, "❌ Ошибка при получении программ.")
+		return
+	}
+	if len(templates) == 0 {
+		b.SendMessage(message.Chat.ID, "📋 Тренер пока не назначил вам программ.")
+		return
+	}
+
+	unit := weightUnit(b, message.From.ID)
+	text := bot.NewText(bot.Plain("📋 "), bot.Bold("Программы тренера"), bot.Plain("\n\n"))
+	for _, t := range templates {
+		text = text.Add(templateText(t, unit)...).Line()
+	}
+	text = text.Add(bot.Plain("Выберите программу, чтобы начать тренировку:"))
+	b.SendInlineText(message.Chat.ID, text, bot.GetInlineAssignedTemplatesKeyboard(templates))
+}
+
+// StartTemplateWorkout создаёт тренировку по назначенному шаблону и показывает первое упражнение
+func StartTemplateWorkout(b *bot.Bot, message *tgbotapi.Message, templateID int64) {
+	state := b.GetState(message.From.ID)
+	if state == nil || state.State != "client_with_trainer" {
+		b.SendMessage(message.Chat.ID, "❌ Откройте меню тренировок с тренером и попробуйте снова.")
+		return
+	}
+	trainerClientID, ok := bot.GetStateInt64(state.Data, "trainer_client_id")
+	if !ok {
+		b.SendMessage(message.Chat.ID, "❌ Сначала выберите тренера.")
+		return
+	}
+
+	assigned, err := b.DB.IsTemplateAssigned(templateID, trainerClientID)
+	if err != nil {
+		b.Log(message.From.ID).Error("Error checking template assignment", "template_id", templateID, "error", err)
+		b.SendMessage(message.Chat.ID, "❌ Ошибка. Попробуйте позже.")
+		return
+	}
+	if !assigned {
+		b.SendMessage(message.Chat.ID, "❌ Эта программа вам больше не назначена.")
+		return
+	}
+	template, err := b.DB.GetTemplateByID(templateID)
+	if err != nil || len(template.Exercises) == 0 {
+		if err != nil {
+			b.Log(message.From.ID).Error("Error getting template", "template_id", templateID, "error", err)
+		}
+		b.SendMessage(message.Chat.ID, "❌ Программа не найдена или пуста.")
+		return
+	}
+
+	now := time.Now()
+	workout := &models.Workout{
+		TrainerClientID:  &trainerClientID,
+		ClientTelegramID: message.From.ID,
+		Date:             now,
+		StartedAt:        &now,
+		MuscleGroups:     template.MuscleGroups,
+		TemplateID:       &template.ID,
+	}
+	if err := b.DB.CreateWorkout(workout); err != nil {
+		b.Log(message.From.ID).Error("Error creating workout", "template_id", templateID, "error", err)
+		b.SendMessage(message.Chat.ID, "❌ Ошибка при создании тренировки. Попробуйте позже.")
+		return
+	}
+
+	data := bot.CopyStateData(state.Data)
+	data["workout_id"] = workout.ID
+	data["template_id"] = template.ID
+	data["step"] = 0
+	data["done"] = 0
+	b.SetState(message.From.ID, "template_workout", data)
+
+	b.SendText(message.Chat.ID, bot.NewText(bot.Plain("🏋️ Тренировка по программе "), bot.Bold(template.Name), bot.Plain(" начата!")), nil)
+	showTemplateStep(b, message.Chat.ID, template, 0, weightUnit(b, message.From.ID))
+}
+
+// showTemplateStep показывает упражнение шаблона с целью, вес - в единицах unit
+func showTemplateStep(b *bot.Bot, chatID int64, template *models.WorkoutTemplate, step int, unit models.WeightUnit) {
+	ex := template.Exercises[step]
+	text := bot.NewText(
+		bot.Plain(fmt.Sprintf("%d/%d ", step+1, len(template.Exercises))), bot.Bold(ex.Name),
+		bot.Plain("\nПлан: "+formatSets(ex.TargetSetList(), weightFormat{unit: unit})+"\n\n"),
+		bot.Plain("Нажмите «✅ По плану» или отправьте, как получилось, - по подходу в строке «вес x повторения»:\n80x10\n80x8"),
+	)
+	b.SendText(chatID, text, bot.GetTemplateWorkoutKeyboard())
+}
+
+// HandleTemplateWorkout записывает выполнение очередного упражнения шаблона
+func HandleTemplateWorkout(b *bot.Bot, message *tgbotapi.Message) {
+	state := b.GetState(message.From.ID)
+
+	workoutID, okW := bot.GetStateInt64(state.Data, "workout_id")
+	templateID, okT := bot.GetStateInt64(state.Data, "template_id")
+	step, _ := state.Data["step"].(int)
+	done, _ := state.Data["done"].(int)
+	if !okW || !okT {
+		finishTemplateWorkout(b, message, state, "❌ Ошибка состояния. Тренировка сохранена как есть.")
+		return
+	}
+
+	template, err := b.DB.GetTemplateByID(templateID)
+	if err != nil || step >= len(template.Exercises) {
+		if err != nil {
+			b.Log(message.From.ID).Error("Error getting template", "template_id", templateID, "error", err)
+		}
+		finishTemplateWorkout(b, message, state, fmt.Sprintf("✅ Тренировка сохранена! Выполнено упражнений: %d 💪", done))
+		return
+	}
+
+	if message.Text == bot.CancelText {
+		CancelWorkout(b, message.From.ID, workoutID)
+		leaveTemplateWorkout(b, message, state, "❌ Тренировка отменена.")
+		return
+	}
+	if message.Text == "🏁 Завершить тренировку" {
+		finishTemplateWorkout(b, message, state, fmt.Sprintf("✅ Тренировка сохранена! Выполнено упражнений: %d из %d 💪", done, len(template.Exercises)))
+		return
+	}
+
+	settings := userSettings(b, message.From.ID)
+	target := template.Exercises[step]
+	if message.Text != "⏭ Пропустить" {
+		if _, ok := workoutOpen(b, message, workoutID); !ok {
+			return
+		}
+		sets := target.TargetSetList()
+		signed := false
+		if message.Text != "✅ По плану" {
+			if sets, signed, err = parser.ParseSets(strings.TrimSpace(message.Text), settings.Unit()); err != nil {
+				b.SendMessage(message.Chat.ID, "⚠️ Не удалось разобрать подходы: "+err.Error()+"\n\nИли нажмите «✅ По плану».")
+				return
+			}
+		}
+		exercise := &models.Exercise{
+			WorkoutID: workoutID,
+			CatalogID: target.CatalogID,
+			Name:      target.Name,
+			Sets:      sets,
+			Order:     done + 1,
+		}
+		if signed || catalogBodyweight(b, message.From.ID, target.CatalogID) {
+			exercise.Bodyweight = true
+			exercise.BodyweightKg = settings.BodyweightKg
+		}
+		if err := b.DB.CreateExercise(exercise); err != nil {
+			b.Log(message.From.ID).Error("Error creating exercise", "workout_id", workoutID, "error", err)
+			b.SendMessage(message.Chat.ID, "❌ Ошибка при сохранении упражнения. Попробуйте ещё раз.")
+			return
+		}
+		done++
+		b.SendText(message.Chat.ID, bot.NewText(bot.Plain("✅ "), bot.Bold(target.Name), bot.Plain(": "+formatExerciseResult(exercise, settings.Unit()))), nil)
+	}
+
+	step++
+	if step >= len(template.Exercises) {
+		finishTemplateWorkout(b, message, state, fmt.Sprintf("🏁 Программа пройдена! Выполнено упражнений: %d из %d 💪", done, len(template.Exercises)))
+		return
+	}
+
+	data := bot.CopyStateData(state.Data)
+	data["step"] = step
+	data["done"] = done
+	b.SetState(message.From.ID, "template_workout", data)
+	showTemplateStep(b, message.Chat.ID, template, step, settings.Unit())
+	if message.Text != "⏭ Пропустить" {
+		b.StartRest(message.Chat.ID, message.From.ID, organizationRestSeconds(b, message.From.ID, &template.OrganizationID))
+	}
+}
+
+// catalogBodyweight - упражнение справочника выполняется со своим весом
+func catalogBodyweight(b *bot.Bot, telegramID int64, catalogID *int64) bool {
+	if catalogID == nil {
+		return false
+	}
+	entry, err := b.DB.GetCatalogExerciseByID(*catalogID)
+	if err != nil {
+		b.Log(telegramID).Error("Error getting catalog exercise", "catalog_id", *catalogID, "error", err)
+		return false
+	}
+	return entry.IsBodyweight()
+}
+
+// finishTemplateWorkout завершает тренировку по шаблону и возвращает клиента в меню тренировок с тренером.
+// text показывается, если тренировка сохранена.
+func finishTemplateWorkout(b *bot.Bot, message *tgbotapi.Message, state *models.UserState, text string) {
+	if workoutID, ok := bot.GetStateInt64(state.Data, "workout_id"); ok {
+		if status := FinishWorkout(b, message.From.ID, workoutID); status != models.WorkoutCompleted {
+			text = FinishedWorkoutText(status)
+		}
+	}
+	leaveTemplateWorkout(b, message, state, text)
+}
+
+// leaveTemplateWorkout возвращает клиента в меню тренировок с тренером
+func leaveTemplateWorkout(b *bot.Bot, message *tgbotapi.Message, state *models.UserState, text string) {
+	data := bot.CopyStateData(state.Data)
+	for _, key := range []string{"workout_id", "template_id", "step", "done"} {
+		delete(data, key)
+	}
+	b.SetState(message.From.ID, "client_with_trainer", data)
+	b.SendMessageWithKeyboard(message.Chat.ID, text, bot.GetClientMenuKeyboard())
+}
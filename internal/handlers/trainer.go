@@ -5,7 +5,6 @@ import (
 	"fitness-bot/internal/database"
 	"fitness-bot/internal/models"
 	"fmt"
-	"log"
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -34,7 +33,7 @@ func HandleTrainerMenu(b *bot.Bot, message *tgbotapi.Message, trainerOrgs []*mod
 	// Если одна организация - сразу показываем управление
 	if len(activeOrgs) == 1 {
 		org := activeOrgs[0]
-		showTrainerOrgMenu(b, message, org.TrainerID, org.Organization.ID, org.Organization.Name)
+		ShowTrainerOrgMenu(b, message, org.TrainerID, org.Organization.ID, org.Organization.Name)
 		return
 	}
 
@@ -52,7 +51,8 @@ func HandleTrainerMenu(b *bot.Bot, message *tgbotapi.Message, trainerOrgs []*mod
 	})
 }
 
-func showTrainerOrgMenu(b *bot.Bot, message *tgbotapi.Message, trainerID, orgID int64, orgName string) {
+// ShowTrainerOrgMenu показывает панель тренера в организации
+func ShowTrainerOrgMenu(b *bot.Bot, message *tgbotapi.Message, trainerID, orgID int64, orgName string) {
 	// Очищаем старые сообщения
 	b.CleanupMessages(message.Chat.ID, message.From.ID)
 
@@ -63,9 +63,9 @@ func showTrainerOrgMenu(b *bot.Bot, message *tgbotapi.Message, trainerID, orgID
 	})
 
 	breadcrumbs := bot.GetBreadcrumbs("🏠 Главная", "🏋️ Тренер", orgName)
-	text := breadcrumbs + "Выберите действие:"
+	text := bot.NewText(bot.Plain(breadcrumbs + "Выберите действие:"))
 
-	b.SendMessageWithKeyboard(
+	b.SendText(
 		message.Chat.ID,
 		text,
 		bot.GetTrainerMenuKeyboard(),
@@ -87,7 +87,7 @@ func HandleTrainerSelectOrg(b *bot.Bot, message *tgbotapi.Message, idx int) {
 	}
 
 	org := orgs[idx-1]
-	showTrainerOrgMenu(b, message, org.TrainerID, org.Organization.ID, org.Organization.Name)
+	ShowTrainerOrgMenu(b, message, org.TrainerID, org.Organization.ID, org.Organization.Name)
 }
 
 // HandleAddClient начинает добавление клиента
@@ -128,11 +128,6 @@ func HandleAddClientUsername(b *bot.Bot, message *tgbotapi.Message) {
 		return
 	}
 
-	if message.Text == "❌ Отмена" {
-		showTrainerOrgMenu(b, message, trainerID, orgID, orgName)
-		return
-	}
-
 	username := database.NormalizeUsername(message.Text)
 	if username == "" {
 		b.SendWithCancel(message.Chat.ID, "❌ Некорректный username. Введите в формате @username:")
@@ -140,20 +135,22 @@ func HandleAddClientUsername(b *bot.Bot, message *tgbotapi.Message) {
 	}
 
 	if err := b.DB.AddClient( trainerID, username); err != nil {
-		log.Printf("Error adding client: %v", err)
+		b.Log(message.From.ID).Error("Error adding client", "error", err)
 		errStr := err.Error()
 		if strings.Contains(errStr, "duplicate") || strings.Contains(errStr, "unique") {
-			b.SendWithCancel(message.Chat.ID, fmt.Sprintf("⚠️ @%s уже ваш клиент.", username))
+			b.SendText(message.Chat.ID,
+				bot.NewText(bot.Plain("⚠️ "), bot.Mention(username), bot.Plain(" уже ваш клиент.")),
+				bot.GetCancelKeyboard())
 		} else {
 			b.SendWithCancel(message.Chat.ID, "❌ Ошибка при добавлении клиента.")
 		}
 		return
 	}
 
-	showTrainerOrgMenu(b, message, trainerID, orgID, orgName)
-	b.SendMessageWithKeyboard(
+	ShowTrainerOrgMenu(b, message, trainerID, orgID, orgName)
+	b.SendText(
 		message.Chat.ID,
-		fmt.Sprintf("✅ Клиент @%s добавлен.\n\nКогда клиент напишет боту, он получит доступ к тренировкам.", username),
+		bot.NewText(bot.Plain("✅ Клиент "), bot.Mention(username), bot.Plain(" добавлен.\n\nКогда клиент напишет боту, он получит доступ к тренировкам.")),
 		bot.GetTrainerMenuKeyboard(),
 	)
 }
@@ -176,18 +173,17 @@ func HandleListClients(b *bot.Bot, message *tgbotapi.Message) {
 
 	clients, err := b.DB.GetTrainerClients( trainerID)
 	if err != nil {
-		log.Printf("Error getting clients: %v", err)
+		b.Log(message.From.ID).Error("Error getting clients", "error", err)
 		b.SendMessage(message.Chat.ID, "❌ Ошибка при получении списка клиентов.")
 		return
 	}
 
 	if len(clients) == 0 {
-		b.SendMessage(message.Chat.ID, fmt.Sprintf("У вас пока нет клиентов в организации *%s*.", orgName))
+		b.SendText(message.Chat.ID, bot.NewText(bot.Plain("У вас пока нет клиентов в организации "), bot.Bold(orgName), bot.Plain(".")), nil)
 		return
 	}
 
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("👥 *Ваши клиенты в %s:*\n\n", orgName))
+	text := bot.NewText(bot.Plain("👥 "), bot.Bold("Ваши клиенты в "+orgName+":"), bot.Plain("\n\n"))
 
 	// Создаем inline-кнопки для каждого клиента
 	var items []string
@@ -212,10 +208,10 @@ func HandleListClients(b *bot.Bot, message *tgbotapi.Message) {
 		ids = append(ids, c.Client.ID)
 	}
 
-	sb.WriteString("Выберите клиента для просмотра:")
+	text = text.Add(bot.Plain("Выберите клиента для просмотра:"))
 
 	keyboard := bot.GetInlineListKeyboard(items, ids, "client")
-	b.SendInlineKeyboard(message.Chat.ID, sb.String(), keyboard)
+	b.SendInlineText(message.Chat.ID, text, keyboard)
 
 	b.SetState(message.From.ID, "trainer_viewing_clients", map[string]interface{}{
 		"trainer_id": state.Data["trainer_id"],
@@ -244,7 +240,6 @@ func HandleSelectClient(b *bot.Bot, message *tgbotapi.Message, idx int) {
 	// Очищаем старые сообщения
 	b.CleanupMessages(message.Chat.ID, message.From.ID)
 
-	var sb strings.Builder
 	name := client.Client.Username
 	if client.FullName != "" {
 		name = client.FullName
@@ -252,30 +247,29 @@ func HandleSelectClient(b *bot.Bot, message *tgbotapi.Message, idx int) {
 
 	// Breadcrumbs
 	orgName, _ := bot.GetStateString(state.Data, "org_name")
-	breadcrumbs := bot.GetBreadcrumbs("🏠 Главная", "🏋️ Тренер", orgName, "👥 Клиенты", name)
-	sb.WriteString(breadcrumbs)
+	text := bot.NewText(bot.Plain(bot.GetBreadcrumbs("🏠 Главная", "🏋️ Тренер", orgName, "👥 Клиенты", name)))
 
-	sb.WriteString(fmt.Sprintf("Username: @%s\n", client.Client.Username))
-	sb.WriteString(fmt.Sprintf("Тренировок: %d\n", client.WorkoutCount))
+	text = text.Line(bot.Plain("Username: "), bot.Mention(client.Client.Username))
+	text = text.Line(bot.Plain(fmt.Sprintf("Тренировок: %d", client.WorkoutCount)))
 	if client.LastWorkout != nil {
-		sb.WriteString(fmt.Sprintf("Последняя: %s\n", client.LastWorkout.Format("02.01.2006")))
+		text = text.Line(bot.Plain("Последняя: " + client.LastWorkout.Format("02.01.2006")))
 	}
 
 	status := "Активен ✅"
 	if !client.Client.IsActive {
 		status = "Деактивирован ❌"
 	}
-	sb.WriteString(fmt.Sprintf("Статус: %s\n", status))
+	text = text.Line(bot.Plain("Статус: " + status))
 
-	sb.WriteString("\n*Действия:*\n")
-	sb.WriteString("1. 📊 Статистика клиента\n")
-	sb.WriteString("2. ➕ Создать тренировку\n")
-	sb.WriteString("3. 📋 История тренировок\n")
+	text = text.Line(bot.Plain("\n"), bot.Bold("Действия:"))
+	text = text.Line(bot.Plain("1. 📊 Статистика клиента"))
+	text = text.Line(bot.Plain("2. ➕ Создать тренировку"))
+	text = text.Line(bot.Plain("3. 📋 История тренировок"))
 	if client.Client.IsActive {
-		sb.WriteString("4. ❌ Удалить клиента\n")
+		text = text.Line(bot.Plain("4. ❌ Удалить клиента"))
 	}
 
-	b.SendMessage(message.Chat.ID, sb.String())
+	b.SendText(message.Chat.ID, text, nil)
 	b.SetState(message.From.ID, "trainer_client_action", map[string]interface{}{
 		"trainer_id": state.Data["trainer_id"],
 		"org_id":     state.Data["org_id"],
@@ -308,15 +302,15 @@ func HandleRemoveClientByIndex(b *bot.Bot, message *tgbotapi.Message, idx int) {
 
 	client := clients[idx-1]
 	if err := b.DB.RemoveClient( trainerID, client.Client.Username); err != nil {
-		log.Printf("Error removing client: %v", err)
+		b.Log(message.From.ID).Error("Error removing client", "error", err)
 		b.SendMessage(message.Chat.ID, "❌ Ошибка при удалении клиента.")
 		return
 	}
 
-	showTrainerOrgMenu(b, message, trainerID, orgID, orgName)
-	b.SendMessageWithKeyboard(
+	ShowTrainerOrgMenu(b, message, trainerID, orgID, orgName)
+	b.SendText(
 		message.Chat.ID,
-		fmt.Sprintf("✅ Клиент @%s удалён.\n\n⚠️ Клиент сможет просматривать историю тренировок.", client.Client.Username),
+		bot.NewText(bot.Plain("✅ Клиент "), bot.Mention(client.Client.Username), bot.Plain(" удалён.\n\n⚠️ Клиент сможет просматривать историю тренировок.")),
 		bot.GetTrainerMenuKeyboard(),
 	)
 }
@@ -345,26 +339,33 @@ func HandleClientAction(b *bot.Bot, message *tgbotapi.Message, action int) {
 
 	switch action {
 	case 1: // Статистика
-		b.SendMessage(message.Chat.ID, fmt.Sprintf("📊 Статистика клиента @%s будет добавлена позже.", client.Client.Username))
+		HandleClientStats(b, message, client.Client.ID)
 
 	case 2: // Создать тренировку
+		groups, err := b.DB.GetMuscleGroups(&orgID)
+		if err != nil {
+			b.Log(message.From.ID).Error("Error getting muscle groups", "error", err)
+			b.SendMessage(message.Chat.ID, "❌ Ошибка. Попробуйте позже.")
+			return
+		}
 		b.SetState(message.From.ID, "awaiting_muscle_group", map[string]interface{}{
 			"trainer_id":        trainerID,
 			"org_id":            orgID,
 			"org_name":          orgName,
 			"client":            client,
 			"trainer_client_id": client.Client.ID,
+			"muscle_group_ids":  []int64{},
 		})
-		keyboard := bot.GetInlineMuscleGroupKeyboard()
-		msgID := b.SendInlineKeyboard(
+		keyboard := bot.GetInlineMuscleGroupKeyboard(groups, nil)
+		msgID := b.SendInlineText(
 			message.Chat.ID,
-			fmt.Sprintf("➕ *Создание тренировки для @%s*\n\nВыберите группу мышц:", client.Client.Username),
+			bot.NewText(bot.Plain("➕ "), bot.Bold("Создание тренировки для @"+client.Client.Username), bot.Plain("\n\nВыберите группы мышц:")),
 			keyboard,
 		)
 		b.StoreMessageID(message.From.ID, msgID)
 
 	case 3: // История тренировок
-		b.SendMessage(message.Chat.ID, fmt.Sprintf("📋 История тренировок @%s будет добавлена позже.", client.Client.Username))
+		HandleClientHistory(b, message, client.Client.ID)
 
 	case 4: // Удалить клиента
 		if !client.Client.IsActive {
@@ -372,14 +373,14 @@ func HandleClientAction(b *bot.Bot, message *tgbotapi.Message, action int) {
 			return
 		}
 		if err := b.DB.RemoveClient( trainerID, client.Client.Username); err != nil {
-			log.Printf("Error removing client: %v", err)
+			b.Log(message.From.ID).Error("Error removing client", "error", err)
 			b.SendMessage(message.Chat.ID, "❌ Ошибка при удалении клиента.")
 			return
 		}
-		showTrainerOrgMenu(b, message, trainerID, orgID, orgName)
-		b.SendMessageWithKeyboard(
+		ShowTrainerOrgMenu(b, message, trainerID, orgID, orgName)
+		b.SendText(
 			message.Chat.ID,
-			fmt.Sprintf("✅ Клиент @%s удалён.", client.Client.Username),
+			bot.NewText(bot.Plain("✅ Клиент "), bot.Mention(client.Client.Username), bot.Plain(" удалён.")),
 			bot.GetTrainerMenuKeyboard(),
 		)
 
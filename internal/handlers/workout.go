@@ -1,91 +1,125 @@
 package handlers
 
 import (
+	"errors"
 	"fitness-bot/internal/bot"
 	"fitness-bot/internal/models"
-	"fmt"
-	"log"
+	"fitness-bot/internal/parser"
 	"strconv"
 	"strings"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
 )
 
 func HandleAddWorkout(b *bot.Bot, message *tgbotapi.Message) {
 	// Получаем текущее состояние - там может быть trainer_client_id
 	state := b.GetState(message.From.ID)
 	var trainerClientID int64
+	var data map[string]interface{}
 
 	if state != nil && state.Data != nil {
+		data = state.Data
 		if tcID, ok := bot.GetStateInt64(state.Data, "trainer_client_id"); ok {
 			trainerClientID = tcID
 		}
 	}
 
+	groups, err := b.DB.GetMuscleGroups(StateOrgID(data))
+	if err != nil {
+		b.Log(message.From.ID).Error("Error getting muscle groups", "error", err)
+		b.SendMessage(message.Chat.ID, "❌ Ошибка. Попробуйте позже.")
+		return
+	}
+
 	b.SendMessageWithKeyboard(
 		message.Chat.ID,
-		"🏋️ *Новая тренировка*\n\nВыберите группу мышц:",
-		bot.GetMuscleGroupKeyboard(),
+		"🏋️ *Новая тренировка*\n\nВыберите группы мышц и нажмите «"+bot.MuscleGroupsDoneText+"»:",
+		bot.GetMuscleGroupKeyboard(groups, nil),
 	)
-	b.SetState(message.From.ID, "awaiting_muscle_group", map[string]interface{}{
+	newData := map[string]interface{}{
 		"telegram_id":       message.From.ID,
 		"trainer_client_id": trainerClientID,
-	})
+		"muscle_group_ids":  []int64{},
+	}
+	if orgID := StateOrgID(data); orgID != nil {
+		newData["org_id"] = *orgID
+	}
+	b.SetState(message.From.ID, "awaiting_muscle_group", newData)
 }
 
 func HandleMuscleGroupSelection(b *bot.Bot, message *tgbotapi.Message) {
-
-	if message.Text == "❌ Отмена" {
-		b.CleanupMessages(message.Chat.ID, message.From.ID)
-		b.ClearState(message.From.ID)
-		accessInfo, _ := b.DB.GetUserAccessInfo( message.From.ID, message.From.UserName)
-		b.SendMessageWithKeyboard(message.Chat.ID, "Отменено.", bot.GetStartMenuKeyboard(accessInfo))
+	state := b.GetState(message.From.ID)
+	if state == nil {
 		return
 	}
 
-	state := b.GetState(message.From.ID)
+	groups, err := b.DB.GetMuscleGroups(StateOrgID(state.Data))
+	if err != nil {
+		b.Log(message.From.ID).Error("Error getting muscle groups", "error", err)
+		b.SendMessage(message.Chat.ID, "❌ Ошибка. Попробуйте позже.")
+		return
+	}
+	selected := bot.GetStateInt64s(state.Data, "muscle_group_ids")
 
-	muscleGroupMap := map[string]models.MuscleGroup{
-		"💪 Грудь":   models.MuscleChest,
-		"🦾 Спина":   models.MuscleBack,
-		"🦵 Ноги":    models.MuscleLegs,
-		"🏋️ Плечи":  models.MuscleShoulders,
-		"💪 Бицепс":  models.MuscleBiceps,
-		"💪 Трицепс": models.MuscleTriceps,
-		"🎯 Пресс":   models.MuscleAbs,
-		"🏃 Кардио":  models.MuscleCardio,
+	if message.Text == bot.MuscleGroupsDoneText {
+		if len(selected) == 0 {
+			b.SendMessage(message.Chat.ID, "⚠️ Выберите хотя бы одну группу мышц.")
+			return
+		}
+		b.SetState(message.From.ID, "awaiting_workout_date", bot.CopyStateData(state.Data))
+		b.SendMessageWithKeyboard(message.Chat.ID, WorkoutDatePrompt, bot.GetWorkoutDateKeyboard())
+		return
 	}
 
-	muscleGroup, ok := muscleGroupMap[message.Text]
-	if !ok {
-		b.SendMessageWithKeyboard(message.Chat.ID, "⚠️ Пожалуйста, выберите группу мышц из кнопок:", bot.GetMuscleGroupKeyboard())
+	group := findMuscleGroupButton(groups, selected, message.Text)
+	if group == nil {
+		b.SendMessageWithKeyboard(message.Chat.ID, "⚠️ Пожалуйста, выберите группу мышц из кнопок:", bot.GetMuscleGroupKeyboard(groups, selected))
 		return
 	}
 
-	// Безопасное извлечение trainer_client_id (может быть int64 или *int64)
-	var trainerClientID *int64
-	if state != nil && state.Data != nil {
-		// Пробуем как int64
-		if tcID, ok := bot.GetStateInt64(state.Data, "trainer_client_id"); ok && tcID > 0 {
-			trainerClientID = &tcID
-		}
-		// Пробуем как *int64
-		if tcID, ok := state.Data["trainer_client_id"].(*int64); ok && tcID != nil {
-			trainerClientID = tcID
-		}
+	selected = ToggleID(selected, group.ID)
+	data := bot.CopyStateData(state.Data)
+	data["muscle_group_ids"] = selected
+	b.SetState(message.From.ID, state.State, data)
+	b.SendText(message.Chat.ID, SelectedMuscleGroupsText(groups, selected), bot.GetMuscleGroupKeyboard(groups, selected))
+}
+
+// WorkoutDatePrompt - вопрос о дате новой тренировки
+const WorkoutDatePrompt = "Когда тренировка? Нажмите «" + bot.WorkoutNowText + "» или запишите задним числом: " +
+	"«вчера 18:30», «15.03 18:30-19:45» (начало и конец) или просто дату."
+
+// HandleWorkoutDate принимает дату тренировки и создаёт её
+func HandleWorkoutDate(b *bot.Bot, message *tgbotapi.Message) {
+	state := b.GetState(message.From.ID)
+	if state == nil {
+		return
 	}
 
-	workout := &models.Workout{
-		TrainerClientID:  trainerClientID,
-		ClientTelegramID: message.From.ID,
-		Date:             time.Now(),
-		MuscleGroup:      muscleGroup,
+	now := time.Now()
+	var when parser.WorkoutTime
+	switch message.Text {
+	case bot.WorkoutNowText:
+		when = parser.Now(now)
+	case bot.WorkoutYesterdayText:
+		when, _ = parser.ParseWorkoutTime("вчера", now)
+	default:
+		var err error
+		when, err = parser.ParseWorkoutTime(message.Text, now)
+		if err != nil {
+			b.SendMessage(message.Chat.ID, "⚠️ "+err.Error())
+			return
+		}
 	}
+	BeginWorkout(b, message, state, when)
+}
 
-	if err := b.DB.CreateWorkout(workout); err != nil {
-		log.Printf("Error creating workout (trainer_client_id=%v, telegram_id=%d): %v", trainerClientID, message.From.ID, err)
-		b.SendMessage(message.Chat.ID, "❌ Ошибка при создании тренировки. Попробуйте позже.")
+// BeginWorkout создаёт тренировку из состояния выбора групп мышц и переходит к добавлению упражнений.
+// inline в данных состояния - тренировку создают из inline-меню, ответ тоже inline.
+func BeginWorkout(b *bot.Bot, message *tgbotapi.Message, state *models.UserState, when parser.WorkoutTime) {
+	workout, ok := CreateWorkoutFromState(b, message.Chat.ID, message.From.ID, state.Data, when)
+	if !ok {
 		return
 	}
 
@@ -95,28 +129,231 @@ func HandleMuscleGroupSelection(b *bot.Bot, message *tgbotapi.Message) {
 		"order":       1,
 	})
 
+	var backdated string
+	if when.Start == nil || time.Since(*when.Start) > time.Minute {
+		backdated = "📅 Тренировка за " + formatWorkoutTime(workout) + "\n\n"
+	}
+
+	if inline, _ := state.Data["inline"].(bool); inline {
+		// Очищаем предыдущие сообщения
+		b.CleanupMessages(message.Chat.ID, message.From.ID)
+
+		msgID := b.SendInlineKeyboard(
+			message.Chat.ID,
+			"✅ Тренировка создана!\n\n"+backdated+"*Добавьте упражнение:*\n"+exerciseInputHelp(workout.MuscleGroups),
+			bot.GetInlineFinishKeyboard(),
+		)
+		// Сохраняем ID нового сообщения
+		b.StoreMessageID(message.From.ID, msgID)
+		return
+	}
+
 	breadcrumbs := bot.GetBreadcrumbs("🏠 Главная", "🏋️ Тренировки", "➕ Новая тренировка")
-	text := breadcrumbs + "Добавьте упражнение в формате:\n"+
-		"```\nНазвание\nПодходы\nПовторения\nВес (кг)\n```\n\n"+
-		"Например:\n"+
-		"```\nЖим лежа\n4\n10\n80\n```\n\n"+
-		"Отправьте '✅ Завершить' когда закончите."
+	text := breadcrumbs + backdated + exerciseInputHelp(workout.MuscleGroups) + "\n\n" +
+		"Отправьте «" + bot.FinishWorkoutText + "», когда закончите, или «" + bot.CancelText + "», чтобы не сохранять тренировку."
 
 	b.SendMessageWithKeyboard(
 		message.Chat.ID,
 		text,
-		bot.GetCancelKeyboard(),
+		bot.GetWorkoutKeyboard(),
 	)
 }
 
+// FinishWorkout завершает черновик тренировки и записывает время окончания, если она идёт сейчас.
+// У тренировок, записанных задним числом без времени, окончание остаётся неизвестным.
+// Тренировка без упражнений не сохраняется. Возвращает итоговый статус (при ошибке - черновик).
+func FinishWorkout(b *bot.Bot, telegramID, workoutID int64) models.WorkoutStatus {
+	b.StopRest(telegramID)
+	workout, err := b.DB.GetWorkoutByID(workoutID)
+	if err != nil {
+		b.Log(telegramID).Error("Error getting workout", "workout_id", workoutID, "error", err)
+		return models.WorkoutDraft
+	}
+	var finishedAt *time.Time
+	if now := time.Now(); workout.CanFinishAt(now) {
+		finishedAt = &now
+	}
+	status, err := b.DB.FinishWorkout(workoutID, finishedAt)
+	if err != nil {
+		b.Log(telegramID).Error("Error finishing workout", "workout_id", workoutID, "error", err)
+		return models.WorkoutDraft
+	}
+	return status
+}
+
+// CancelWorkout отменяет черновик тренировки вместе с добавленными упражнениями
+func CancelWorkout(b *bot.Bot, telegramID, workoutID int64) {
+	b.StopRest(telegramID)
+	if err := b.DB.CancelWorkout(workoutID); err != nil {
+		b.Log(telegramID).Error("Error cancelling workout", "workout_id", workoutID, "error", err)
+	}
+}
+
+// FinishedWorkoutText - ответ на завершение тренировки с итоговым статусом status
+func FinishedWorkoutText(status models.WorkoutStatus) string {
+	switch status {
+	case models.WorkoutCompleted:
+		return "✅ Тренировка сохранена! 💪"
+	case models.WorkoutCancelled:
+		return "🗑 В тренировке нет упражнений - она не сохранена."
+	default:
+		return "❌ Не удалось завершить тренировку. Упражнения записаны, тренировка закроется автоматически."
+	}
+}
+
+// formatWorkoutTime - дата тренировки и, если известны, время и длительность:
+// «15.03.2024 18:30-19:45 (1 ч 15 мин)»
+func formatWorkoutTime(w *models.Workout) string {
+	s := w.Date.Format("02.01.2006")
+	if w.StartedAt == nil {
+		return s
+	}
+	s += " " + w.StartedAt.Format("15:04")
+	if w.FinishedAt != nil {
+		s += "-" + w.FinishedAt.Format("15:04")
+	}
+	if d, ok := w.Duration(); ok {
+		s += " (" + formatDuration(d) + ")"
+	}
+	return s
+}
+
+// formatDuration форматирует длительность: «45 мин», «1 ч 15 мин»
+func formatDuration(d time.Duration) string {
+	minutes := int(d.Round(time.Minute) / time.Minute)
+	if minutes < 60 {
+		return strconv.Itoa(minutes) + " мин"
+	}
+	s := strconv.Itoa(minutes/60) + " ч"
+	if minutes%60 > 0 {
+		s += " " + strconv.Itoa(minutes%60) + " мин"
+	}
+	return s
+}
+
+// CreateWorkoutFromState создаёт тренировку с группами мышц, выбранными в состоянии
+// (muscle_group_ids). Об ошибках сообщает пользователю сам.
+func CreateWorkoutFromState(b *bot.Bot, chatID, telegramID int64, data map[string]interface{}, when parser.WorkoutTime) (*models.Workout, bool) {
+	orgID := StateOrgID(data)
+	groups, err := b.DB.GetMuscleGroupsByIDs(bot.GetStateInt64s(data, "muscle_group_ids"), orgID)
+	if err != nil {
+		b.Log(telegramID).Error("Error getting muscle groups", "error", err)
+		b.SendMessage(chatID, "❌ Ошибка при создании тренировки. Попробуйте позже.")
+		return nil, false
+	}
+	if len(groups) == 0 {
+		b.SendMessage(chatID, "⚠️ Выберите хотя бы одну группу мышц.")
+		return nil, false
+	}
+
+	// Безопасное извлечение trainer_client_id (может быть int64 или *int64)
+	var trainerClientID *int64
+	if tcID, ok := bot.GetStateInt64(data, "trainer_client_id"); ok && tcID > 0 {
+		trainerClientID = &tcID
+	}
+	if tcID, ok := data["trainer_client_id"].(*int64); ok && tcID != nil {
+		trainerClientID = tcID
+	}
+
+	workout := &models.Workout{
+		TrainerClientID:  trainerClientID,
+		ClientTelegramID: telegramID,
+		Date:             when.Date,
+		StartedAt:        when.Start,
+		FinishedAt:       when.End,
+		MuscleGroups:     groups,
+	}
+
+	if err := b.DB.CreateWorkout(workout); err != nil {
+		b.Log(telegramID).Error("Error creating workout", "trainer_client_id", trainerClientID, "error", err)
+		b.SendMessage(chatID, "❌ Ошибка при создании тренировки. Попробуйте позже.")
+		return nil, false
+	}
+	return workout, true
+}
+
+// StateOrgID возвращает организацию из данных состояния - от неё зависит список групп мышц
+func StateOrgID(data map[string]interface{}) *int64 {
+	if orgID, ok := bot.GetStateInt64(data, "org_id"); ok && orgID > 0 {
+		return &orgID
+	}
+	return nil
+}
+
+// findMuscleGroupButton находит группу мышц по тексту кнопки
+func findMuscleGroupButton(groups []*models.MuscleGroupRecord, selected []int64, text string) *models.MuscleGroupRecord {
+	for _, g := range groups {
+		if text == bot.MuscleGroupButton(g, containsID(selected, g.ID)) {
+			return g
+		}
+	}
+	return nil
+}
+
+// SelectedMuscleGroupsText - подсказка с уже выбранными группами мышц
+// (названия задают организации, поэтому текст собирается из частей)
+func SelectedMuscleGroupsText(groups []*models.MuscleGroupRecord, selected []int64) bot.Text {
+	var names []string
+	for _, g := range groups {
+		if containsID(selected, g.ID) {
+			names = append(names, string(g.Name))
+		}
+	}
+	if len(names) == 0 {
+		return bot.NewText(bot.Plain("Выберите группы мышц и нажмите «" + bot.MuscleGroupsDoneText + "»:"))
+	}
+	return bot.NewText(bot.Plain("Выбрано: " + strings.Join(names, ", ") + "\nДобавьте ещё или нажмите «" + bot.MuscleGroupsDoneText + "»."))
+}
+
+// ToggleID добавляет id в список или убирает, если он уже там
+func ToggleID(ids []int64, id int64) []int64 {
+	result := make([]int64, 0, len(ids)+1)
+	for _, v := range ids {
+		if v != id {
+			result = append(result, v)
+		}
+	}
+	if len(result) == len(ids) {
+		result = append(result, id)
+	}
+	return result
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// primaryMuscleGroup - первая группа мышц, по ней новое упражнение попадает в каталог
+func primaryMuscleGroup(groups []models.MuscleGroupRecord) models.MuscleGroup {
+	if len(groups) == 0 {
+		return ""
+	}
+	return groups[0].Name
+}
+
 func HandleAddExercise(b *bot.Bot, message *tgbotapi.Message) {
 	state := b.GetState(message.From.ID)
 
-	if message.Text == "❌ Отмена" || message.Text == "✅ Завершить" {
+	if message.Text == bot.CancelText || message.Text == bot.FinishWorkoutText {
+		text := "❌ Тренировка отменена."
+		if state != nil {
+			if workoutID, ok := bot.GetStateInt64(state.Data, "workout_id"); ok {
+				if message.Text == bot.FinishWorkoutText {
+					text = FinishedWorkoutText(FinishWorkout(b, message.From.ID, workoutID))
+				} else {
+					CancelWorkout(b, message.From.ID, workoutID)
+				}
+			}
+		}
 		b.CleanupMessages(message.Chat.ID, message.From.ID)
 		b.ClearState(message.From.ID)
 		accessInfo, _ := b.DB.GetUserAccessInfo( message.From.ID, message.From.UserName)
-		b.SendMessageWithKeyboard(message.Chat.ID, "✅ Тренировка сохранена! 💪", bot.GetStartMenuKeyboard(accessInfo))
+		b.SendMessageWithKeyboard(message.Chat.ID, text, bot.GetStartMenuKeyboard(accessInfo))
 		return
 	}
 
@@ -128,24 +365,26 @@ func HandleAddExercise(b *bot.Bot, message *tgbotapi.Message) {
 	if len(message.Photo) > 0 {
 		photos := message.Photo
 		photoFileID := photos[len(photos)-1].FileID
-		state.Data["photo_file_id"] = photoFileID
+		data := bot.CopyStateData(state.Data)
+		data["photo_file_id"] = photoFileID
+		b.SetState(message.From.ID, state.State, data)
 		b.SendMessage(message.Chat.ID, "📷 Фото сохранено! Теперь отправьте данные упражнения.")
 		return
 	}
 
-	lines := strings.Split(strings.TrimSpace(message.Text), "\n")
-	if len(lines) < 4 {
-		b.SendMessage(message.Chat.ID, "❌ Неверный формат. Укажите:\n\nНазвание\nПодходы\nПовторения\nВес (кг)")
-		return
+	// Кардио записывают дистанцией и временем вместо подходов
+	settings := userSettings(b, message.From.ID)
+	var parsed []parser.Exercise
+	var err error
+	help := ExerciseInputHelp
+	if parser.LooksLikeCardio(message.Text) {
+		parsed, err = parser.ParseCardio(message.Text)
+		help = CardioInputHelp
+	} else {
+		parsed, err = parser.Parse(message.Text, settings.Unit())
 	}
-
-	name := strings.TrimSpace(lines[0])
-	sets, err1 := strconv.Atoi(strings.TrimSpace(lines[1]))
-	reps, err2 := strconv.Atoi(strings.TrimSpace(lines[2]))
-	weight, err3 := strconv.ParseFloat(strings.TrimSpace(lines[3]), 64)
-
-	if err1 != nil || err2 != nil || err3 != nil {
-		b.SendMessage(message.Chat.ID, "❌ Ошибка в числовых значениях. Проверьте формат:\n\nНазвание\n4\n10\n80")
+	if err != nil {
+		b.SendMessage(message.Chat.ID, "❌ Не удалось разобрать упражнение: "+err.Error()+"\n\n"+help)
 		return
 	}
 
@@ -154,66 +393,121 @@ func HandleAddExercise(b *bot.Bot, message *tgbotapi.Message) {
 		b.SendMessage(message.Chat.ID, "❌ Ошибка. Начните тренировку заново.")
 		return
 	}
+	// Тренировку и её организацию загружаем один раз на сообщение
+	workout, ok := workoutOpen(b, message, workoutID)
+	if !ok {
+		return
+	}
+	orgID := workoutOrganizationID(b, message.From.ID, workout)
 
 	order := 1
 	if o, ok := state.Data["order"].(int); ok {
 		order = o
 	}
 
-	photoFileID := ""
-	if photo, ok := state.Data["photo_file_id"].(string); ok {
-		photoFileID = photo
-		delete(state.Data, "photo_file_id")
-	}
+	photoFileID, _ := bot.GetStateString(state.Data, "photo_file_id")
+
+	text := bot.NewText()
+	added := 0
+	needBodyweight := false
+	for _, p := range parsed {
+		entry, matchedName := resolveCatalogExercise(b, message.From.ID, workout, orgID, p.Name)
+
+		exercise := &models.Exercise{
+			WorkoutID:   workoutID,
+			Name:        p.Name,
+			Sets:        p.Sets,
+			Cardio:      p.Cardio,
+			RestSeconds: p.RestSeconds,
+			Order:       order,
+		}
+		if entry != nil {
+			exercise.CatalogID = &entry.ID
+		}
+		// Со своим весом - если вес записан со знаком или так отмечено в справочнике
+		if p.Cardio == nil && (p.Bodyweight || (entry != nil && entry.IsBodyweight())) {
+			exercise.Bodyweight = true
+			exercise.BodyweightKg = settings.BodyweightKg
+			needBodyweight = needBodyweight || settings.BodyweightKg == nil
+		}
+		// Фото относится к первому упражнению сообщения
+		if added == 0 {
+			exercise.PhotoFileID = photoFileID
+		}
+
+		if err := b.DB.CreateExercise(exercise); err != nil {
+			b.Log(message.From.ID).Error("Error creating exercise", "name", p.Name, "error", err)
+			text = text.Line(bot.Plain("❌ "), bot.Bold(p.Name), bot.Plain(": ошибка при сохранении"))
+			continue
+		}
+		added++
+		order++
 
-	exercise := &models.Exercise{
-		WorkoutID:   workoutID,
-		Name:        name,
-		Sets:        sets,
-		Reps:        reps,
-		Weight:      weight,
-		PhotoFileID: photoFileID,
-		Order:       order,
+		text = text.Line(bot.Plain("✅ "), bot.Bold(p.Name), bot.Plain(": "+formatExerciseResult(exercise, settings.Unit())))
+		if matchedName != "" {
+			text = text.Line(bot.Plain("   🔎 Записано как «" + matchedName + "»"))
+		}
+	}
+	if needBodyweight && added > 0 {
+		text = text.Line().Line(bot.Plain("ℹ️ Укажите свой вес в «" + bot.SettingsText + "», чтобы прогресс в упражнениях со своим весом считался по полной нагрузке."))
 	}
 
-	if err := b.DB.CreateExercise(exercise); err != nil {
-		log.Printf("Error creating exercise: %v", err)
-		b.SendMessage(message.Chat.ID, "❌ Ошибка при сохранении упражнения.")
-		return
+	if added > 0 {
+		data := bot.CopyStateData(state.Data)
+		delete(data, "photo_file_id")
+		data["order"] = order
+		b.SetState(message.From.ID, state.State, data)
+	}
+	b.SendText(message.Chat.ID, text.Add(bot.Plain("\nДобавьте ещё или отправьте «"+bot.FinishWorkoutText+"»")), nil)
+	if added > 0 {
+		startWorkoutRest(b, message, orgID, parsed)
 	}
+}
 
-	state.Data["order"] = order + 1
-	b.SendMessage(message.Chat.ID, fmt.Sprintf("✅ Упражнение '%s' добавлено!\n\nДобавьте ещё одно или отправьте '✅ Завершить'", name))
+// workoutOpen загружает тренировку и проверяет, что в неё ещё можно добавлять упражнения:
+// брошенную пустую тренировку могли отменить автоматически. Об этом сообщает сам.
+func workoutOpen(b *bot.Bot, message *tgbotapi.Message, workoutID int64) (*models.Workout, bool) {
+	workout, err := b.DB.GetWorkoutByID(workoutID)
+	if err == nil {
+		return workout, true
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		b.Log(message.From.ID).Error("Error getting workout", "workout_id", workoutID, "error", err)
+		b.SendMessage(message.Chat.ID, "❌ Ошибка. Попробуйте ещё раз.")
+		return nil, false
+	}
+	b.ClearState(message.From.ID)
+	accessInfo, _ := b.DB.GetUserAccessInfo(message.From.ID, message.From.UserName)
+	b.SendMessageWithKeyboard(message.Chat.ID, "⌛ Тренировка была пустой слишком долго и закрыта. Начните новую.", bot.GetStartMenuKeyboard(accessInfo))
+	return nil, false
 }
 
-func HandleMyWorkouts(b *bot.Bot, message *tgbotapi.Message) {
-	workouts, err := b.DB.GetWorkoutsByClientTelegramID(message.From.ID, 10)
+// workoutOrganizationID возвращает организацию тренировки. nil - без тренера
+// или организацию не удалось узнать (ошибка пишется в лог).
+func workoutOrganizationID(b *bot.Bot, telegramID int64, workout *models.Workout) *int64 {
+	orgID, err := b.DB.GetWorkoutOrganizationID(workout)
 	if err != nil {
-		log.Printf("Error getting workouts: %v", err)
-		b.SendMessage(message.Chat.ID, "❌ Ошибка при получении тренировок.")
-		return
+		b.Log(telegramID).Error("Error getting workout organization", "workout_id", workout.ID, "error", err)
+		return nil
 	}
+	return orgID
+}
 
-	if len(workouts) == 0 {
-		b.SendMessage(message.Chat.ID, "У вас пока нет тренировок. Добавьте первую!")
-		return
+// resolveCatalogExercise привязывает название к справочнику упражнений организации orgID
+// тренировки workout. Возвращает упражнение справочника и его название, если оно найдено
+// по похожему написанию. Ошибка справочника или новое название в тренировке без организации
+// не мешают сохранить упражнение - оно просто останется без привязки (nil).
+func resolveCatalogExercise(b *bot.Bot, telegramID int64, workout *models.Workout, orgID *int64, name string) (*models.CatalogExercise, string) {
+	match, err := b.DB.ResolveCatalogExercise(name, orgID, primaryMuscleGroup(workout.MuscleGroups))
+	if err != nil {
+		b.Log(telegramID).Error("Error resolving catalog exercise", "name", name, "error", err)
+		return nil, ""
 	}
-
-	var response strings.Builder
-	response.WriteString("📝 *Ваши последние тренировки:*\n\n")
-
-	for _, w := range workouts {
-		exercises, _ := b.DB.GetExercisesByWorkout(w.ID)
-		response.WriteString(fmt.Sprintf("📅 %s - %s\n", w.Date.Format("02.01.2006"), w.MuscleGroup))
-
-		if len(exercises) > 0 {
-			for _, ex := range exercises {
-				response.WriteString(fmt.Sprintf("  • %s: %d x %d (%.1f кг)\n",
-					ex.Name, ex.Sets, ex.Reps, ex.Weight))
-			}
-		}
-		response.WriteString("\n")
+	if match == nil {
+		return nil, ""
 	}
-
-	b.SendMessage(message.Chat.ID, response.String())
+	if match.Fuzzy {
+		return match.Entry, match.Entry.Name
+	}
+	return match.Entry, ""
 }
@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"errors"
+	"fitness-bot/internal/bot"
+	"fitness-bot/internal/catalog"
+	"fitness-bot/internal/models"
+	"fitness-bot/internal/parser"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
+)
+
+// editReturnKeyboards - панели, в которые пользователь возвращается после правки упражнения
+// или другого ввода, начатого из них. Из записи тренировки возвращаемся к вводу упражнений
+// с тем же черновиком (workout_id остаётся в данных состояния).
+// Из остальных состояний возвращаемся в главное меню.
+var editReturnKeyboards = map[string]func() tgbotapi.ReplyKeyboardMarkup{
+	"client_with_trainer":  bot.GetClientMenuKeyboard,
+	"trainer_managing_org": bot.GetTrainerMenuKeyboard,
+	"adding_exercises":     bot.GetWorkoutKeyboard,
+}
+
+// exerciseFields - поля упражнения, которые можно исправить, и подсказки для ввода
+var exerciseFields = map[string]string{
+	"name":  "Введите новое название упражнения:",
+	"sets":  "Отправьте все подходы заново, по одному в строке - «вес x повторения», например:\n80x10\n85x8\n90x6\n\nСо своим весом - со знаком: +10x8 (отягощение), -20x10 (помощь).",
+	"order": "Введите новый номер упражнения в тренировке:",
+	"cardio": "Отправьте показатели заново - дистанцию, время и, если есть, пульс и набор высоты, например:\n" +
+		"5км 28:30 пульс 150/175",
+}
+
+// EditableWorkout загружает тренировку, если пользователь может её менять
+// (владелец или тренер клиента). Иначе сообщает об ошибке и возвращает false.
+func EditableWorkout(b *bot.Bot, chatID int64, user *tgbotapi.User, workoutID int64) (*models.Workout, bool) {
+	workout, err := b.DB.GetWorkoutByID(workoutID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			b.Log(user.ID).Error("Error getting workout", "workout_id", workoutID, "error", err)
+		}
+		b.SendMessage(chatID, "❌ Тренировка не найдена или уже удалена.")
+		return nil, false
+	}
+
+	allowed, err := b.DB.CanEditWorkout(workout, user.ID, user.UserName)
+	if err != nil {
+		b.Log(user.ID).Error("Error checking workout access", "workout_id", workoutID, "error", err)
+		b.SendMessage(chatID, "❌ Ошибка. Попробуйте позже.")
+		return nil, false
+	}
+	if !allowed {
+		if workout.ClientTelegramID == user.ID {
+			// Своя тренировка, но с архивным тренером
+			b.SendMessage(chatID, "🔒 Тренировка с архивным тренером доступна только для просмотра.")
+			return nil, false
+		}
+		b.SendMessage(chatID, "❌ У вас нет доступа к этой тренировке.")
+		return nil, false
+	}
+	return workout, true
+}
+
+// EditableExercise загружает упражнение и его тренировку, если пользователь может их менять
+func EditableExercise(b *bot.Bot, chatID int64, user *tgbotapi.User, exerciseID int64) (*models.Exercise, *models.Workout, bool) {
+	exercise, err := b.DB.GetExerciseByID(exerciseID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			b.Log(user.ID).Error("Error getting exercise", "exercise_id", exerciseID, "error", err)
+		}
+		b.SendMessage(chatID, "❌ Упражнение не найдено или уже удалено.")
+		return nil, nil, false
+	}
+	workout, ok := EditableWorkout(b, chatID, user, exercise.WorkoutID)
+	if !ok {
+		return nil, nil, false
+	}
+	return exercise, workout, true
+}
+
+// ShowWorkoutEditor показывает тренировку с кнопками правки её упражнений
+func ShowWorkoutEditor(b *bot.Bot, chatID int64, messageID int, user *tgbotapi.User, workout *models.Workout, notice string) {
+	exercises, err := b.DB.GetExercisesByWorkout(workout.ID)
+	if err != nil {
+		b.Log(user.ID).Error("Error getting exercises", "workout_id", workout.ID, "error", err)
+		b.SendMessage(chatID, "❌ Ошибка при получении упражнений.")
+		return
+	}
+
+	unit := weightUnit(b, user.ID)
+	text := bot.NewText()
+	if notice != "" {
+		text = text.Line(bot.Plain(notice)).Line()
+	}
+	text = text.Line(bot.Bold(fmt.Sprintf("📅 Тренировка %s - %s", formatWorkoutTime(workout), workout.MuscleGroupNames()))).Line()
+	if len(exercises) == 0 {
+		text = text.Line(bot.Plain("Упражнений нет."))
+	}
+	for i, ex := range exercises {
+		text = text.Line(bot.Plain(fmt.Sprintf("%d. %s: %s", i+1, ex.Name, formatExerciseResult(ex, unit))))
+	}
+	text = text.Line().Add(bot.Plain("Выберите упражнение для правки:"))
+
+	keyboard := bot.GetInlineWorkoutEditKeyboard(workout.ID, exercises)
+	b.EditText(chatID, messageID, text, &keyboard)
+}
+
+// exerciseCardText - карточка упражнения, вес - в единицах unit
+func exerciseCardText(exercise *models.Exercise, unit models.WeightUnit) bot.Text {
+	if exercise.Cardio != nil {
+		return bot.NewText(bot.Bold("🏃 "+exercise.Name), bot.Plain("\n\n"+formatCardio(exercise.Cardio)+
+			fmt.Sprintf("\n\nПорядок в тренировке: %d\n\nЧто исправить?", exercise.Order)))
+	}
+	text := bot.NewText(bot.Bold("🏋️ "+exercise.Name), bot.Plain("\n\n"))
+	for i, set := range exercise.Sets {
+		text = text.Line(bot.Plain(fmt.Sprintf("%d. %s", i+1, formatSet(set, exerciseFormat(exercise, unit)))))
+	}
+	if len(exercise.Sets) == 0 {
+		text = text.Line(bot.Plain("Подходов нет."))
+	}
+	if exercise.Bodyweight {
+		bodyweight := "не указан"
+		if exercise.BodyweightKg != nil {
+			bodyweight = formatWeight(*exercise.BodyweightKg, unit)
+		}
+		text = text.Line().Line(bot.Plain("Со своим весом, свой вес: " + bodyweight))
+	}
+	return text.Add(bot.Plain(fmt.Sprintf("\nПорядок в тренировке: %d\n\nЧто исправить?", exercise.Order)))
+}
+
+// ShowExerciseEditor показывает упражнение с кнопками правки полей
+func ShowExerciseEditor(b *bot.Bot, chatID int64, messageID int, user *tgbotapi.User, exercise *models.Exercise) {
+	keyboard := bot.GetInlineExerciseEditKeyboard(exercise)
+	b.EditText(chatID, messageID, exerciseCardText(exercise, weightUnit(b, user.ID)), &keyboard)
+}
+
+// StartExerciseEdit запрашивает новое значение поля упражнения.
+// Текущее состояние запоминается, чтобы вернуться в него после правки.
+func StartExerciseEdit(b *bot.Bot, message *tgbotapi.Message, exercise *models.Exercise, field string) {
+	prompt, ok := exerciseFields[field]
+	if !ok {
+		return
+	}
+	// Подходы - только у силовых, показатели - только у кардио (кнопка из старого сообщения)
+	if (field == "sets" && exercise.Cardio != nil) || (field == "cardio" && exercise.Cardio == nil) {
+		return
+	}
+
+	data := map[string]interface{}{}
+	returnState := ""
+	if state := b.GetState(message.From.ID); state != nil {
+		returnState = state.State
+		if returnState == "editing_exercise" {
+			// Повторное нажатие кнопки: возвращаемся туда же, куда и раньше
+			returnState, _ = bot.GetStateString(state.Data, "return_state")
+		}
+		data = bot.CopyStateData(state.Data)
+	}
+	data["exercise_id"] = exercise.ID
+	data["edit_field"] = field
+	data["return_state"] = returnState
+	b.SetState(message.From.ID, "editing_exercise", data)
+
+	b.SendText(message.Chat.ID,
+		bot.NewText(bot.Plain("✏️ "), bot.Bold(exercise.Name), bot.Plain("\n\n"+prompt)),
+		bot.GetCancelKeyboard(),
+	)
+}
+
+// HandleEditExerciseValue сохраняет новое значение поля упражнения
+func HandleEditExerciseValue(b *bot.Bot, message *tgbotapi.Message, state *models.UserState, accessInfo *models.AccessInfo) {
+	if message.Text == bot.CancelText {
+		finishExerciseEdit(b, message, state, accessInfo, "Правка отменена.")
+		return
+	}
+
+	exerciseID, okID := bot.GetStateInt64(state.Data, "exercise_id")
+	field, okF := bot.GetStateString(state.Data, "edit_field")
+	if !okID || !okF {
+		finishExerciseEdit(b, message, state, accessInfo, "❌ Ошибка. Откройте упражнение заново.")
+		return
+	}
+
+	exercise, workout, ok := EditableExercise(b, message.Chat.ID, message.From, exerciseID)
+	if !ok {
+		finishExerciseEdit(b, message, state, accessInfo, "")
+		return
+	}
+
+	value := strings.TrimSpace(message.Text)
+	var err error
+	switch field {
+	case "name":
+		value = catalog.Clean(value)
+		if value == "" || len([]rune(value)) > 255 {
+			b.SendMessage(message.Chat.ID, "⚠️ Название должно быть от 1 до 255 символов.")
+			return
+		}
+		exercise.Name = value
+		exercise.CatalogID = nil
+		entry, _ := resolveCatalogExercise(b, message.From.ID, workout, workoutOrganizationID(b, message.From.ID, workout), value)
+		if entry != nil {
+			exercise.CatalogID = &entry.ID
+			markBodyweight(b, exercise, workout, entry.IsBodyweight())
+		}
+		err = b.DB.UpdateExercise(exercise)
+	case "sets":
+		sets, signed, parseErr := parser.ParseSets(value, weightUnit(b, message.From.ID))
+		if parseErr != nil {
+			b.SendMessage(message.Chat.ID, "⚠️ Не удалось разобрать подходы: "+parseErr.Error())
+			return
+		}
+		if err = b.DB.ReplaceExerciseSets(exercise.ID, sets); err == nil && markBodyweight(b, exercise, workout, signed) {
+			err = b.DB.UpdateExercise(exercise)
+		}
+	case "cardio":
+		cardio, parseErr := parser.ParseCardioValues(value)
+		if parseErr != nil {
+			b.SendMessage(message.Chat.ID, "⚠️ Не удалось разобрать показатели: "+parseErr.Error())
+			return
+		}
+		err = b.DB.UpdateCardio(exercise.ID, &cardio)
+	case "order":
+		n, convErr := strconv.Atoi(value)
+		if convErr != nil || n <= 0 {
+			b.SendMessage(message.Chat.ID, "⚠️ Введите номер упражнения (1, 2, 3...).")
+			return
+		}
+		err = b.DB.MoveExercise(exercise.ID, n)
+	}
+	if err != nil {
+		b.Log(message.From.ID).Error("Error updating exercise", "exercise_id", exercise.ID, "field", field, "error", err)
+		b.SendMessage(message.Chat.ID, "❌ Ошибка при сохранении. Попробуйте позже.")
+		return
+	}
+
+	finishExerciseEdit(b, message, state, accessInfo, "✅ Сохранено!")
+
+	if updated, err := b.DB.GetExerciseByID(exercise.ID); err == nil {
+		keyboard := bot.GetInlineExerciseEditKeyboard(updated)
+		b.SendInlineText(message.Chat.ID, exerciseCardText(updated, weightUnit(b, message.From.ID)), keyboard)
+	}
+}
+
+// markBodyweight отмечает упражнение как упражнение со своим весом, если bodyweight,
+// и запоминает свой вес клиента. Возвращает true, если упражнение изменилось.
+func markBodyweight(b *bot.Bot, exercise *models.Exercise, workout *models.Workout, bodyweight bool) bool {
+	if !bodyweight || exercise.Bodyweight {
+		return false
+	}
+	exercise.Bodyweight = true
+	exercise.BodyweightKg = userSettings(b, workout.ClientTelegramID).BodyweightKg
+	return true
+}
+
+// finishExerciseEdit возвращает пользователя в состояние, из которого он начал правку
+func finishExerciseEdit(b *bot.Bot, message *tgbotapi.Message, state *models.UserState, accessInfo *models.AccessInfo, text string) {
+	if text == "" {
+		text = "Возврат в меню."
+	}
+	returnFromInput(b, message, state, accessInfo, text, "exercise_id", "edit_field")
+}
+
+// returnFromInput возвращает пользователя в состояние return_state, из которого он начал ввод,
+// и убирает из данных состояния ключи ввода keys. Из остальных состояний - в главное меню.
+func returnFromInput(b *bot.Bot, message *tgbotapi.Message, state *models.UserState, accessInfo *models.AccessInfo, text string, keys ...string) {
+	returnState, _ := bot.GetStateString(state.Data, "return_state")
+	keyboard, ok := editReturnKeyboards[returnState]
+	if ok {
+		data := bot.CopyStateData(state.Data)
+		for _, key := range append(keys, "return_state") {
+			delete(data, key)
+		}
+		b.SetState(message.From.ID, returnState, data)
+	} else {
+		b.ClearState(message.From.ID)
+		keyboard = func() tgbotapi.ReplyKeyboardMarkup { return bot.GetStartMenuKeyboard(accessInfo) }
+	}
+	b.SendMessageWithKeyboard(message.Chat.ID, text, keyboard())
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -8,19 +9,65 @@ import (
 
 // User - базовая информация о пользователе Telegram
 type User struct {
-	ID         int64          `gorm:"primaryKey;autoIncrement" json:"id"`
-	TelegramID int64          `gorm:"uniqueIndex;not null" json:"telegram_id"`
-	Username   string         `gorm:"uniqueIndex;type:varchar(255)" json:"username"`
-	FullName   string         `gorm:"type:varchar(255)" json:"full_name"`
-	CreatedAt  time.Time      `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt  time.Time      `gorm:"autoUpdateTime" json:"-"`
-	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+	ID            int64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	TelegramID    int64          `gorm:"uniqueIndex;not null" json:"telegram_id"`
+	Username      string         `gorm:"uniqueIndex;type:varchar(255)" json:"username"`
+	FullName      string         `gorm:"type:varchar(255)" json:"full_name"`
+	WeightUnit    WeightUnit     `gorm:"type:varchar(2);not null;default:kg" json:"weight_unit"`
+	BodyweightKg  *float64       `gorm:"type:decimal(5,2)" json:"bodyweight_kg,omitempty"` // свой вес, nil - не указан
+	ShareWorkouts bool           `gorm:"not null;default:false" json:"share_workouts"`     // тренеры видят тренировки без тренера
+	CreatedAt     time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time      `gorm:"autoUpdateTime" json:"-"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 func (User) TableName() string {
 	return "users"
 }
 
+// Unit возвращает единицы веса пользователя (по умолчанию кг)
+func (u *User) Unit() WeightUnit {
+	if u.WeightUnit == UnitLb {
+		return UnitLb
+	}
+	return UnitKg
+}
+
+// WeightUnit - единицы, в которых пользователь вводит и видит вес. В базе вес всегда в кг.
+type WeightUnit string
+
+const (
+	UnitKg WeightUnit = "kg"
+	UnitLb WeightUnit = "lb"
+)
+
+// KgPerLb - килограммов в фунте
+const KgPerLb = 0.45359237
+
+// ToKg переводит вес из этих единиц в кг
+func (u WeightUnit) ToKg(v float64) float64 {
+	if u == UnitLb {
+		return v * KgPerLb
+	}
+	return v
+}
+
+// FromKg переводит вес из кг в эти единицы
+func (u WeightUnit) FromKg(kg float64) float64 {
+	if u == UnitLb {
+		return kg / KgPerLb
+	}
+	return kg
+}
+
+// Label - подпись единиц: «кг» или «lb»
+func (u WeightUnit) Label() string {
+	if u == UnitLb {
+		return "lb"
+	}
+	return "кг"
+}
+
 // Organization - фитнес организация
 type Organization struct {
 	ID        int64          `gorm:"primaryKey;autoIncrement" json:"id"`
@@ -30,6 +77,8 @@ type Organization struct {
 	CreatedAt time.Time      `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"-"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	DefaultRestSeconds int `gorm:"not null;default:0" json:"default_rest_seconds"` // отдых между подходами, 0 - без таймера
 }
 
 func (Organization) TableName() string {
@@ -95,7 +144,8 @@ func (TrainerClient) TableName() string {
 	return "trainer_clients"
 }
 
-// MuscleGroup - группа мышц
+// MuscleGroup - название группы мышц. Константы ниже - встроенные группы,
+// организации могут добавлять свои (MuscleGroupRecord)
 type MuscleGroup string
 
 const (
@@ -109,6 +159,37 @@ const (
 	MuscleCardio    MuscleGroup = "Кардио"
 )
 
+// MuscleGroupRecord - группа мышц в справочнике.
+// Без OrganizationID - встроенная, иначе - добавлена организацией.
+type MuscleGroupRecord struct {
+	ID             int64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	OrganizationID *int64         `gorm:"index" json:"organization_id"`
+	Name           MuscleGroup    `gorm:"type:varchar(50);not null" json:"name"`
+	Emoji          string         `gorm:"type:varchar(16);not null" json:"emoji"`
+	SortOrder      int            `gorm:"not null;default:100" json:"sort_order"`
+	CreatedAt      time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time      `gorm:"autoUpdateTime" json:"-"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (MuscleGroupRecord) TableName() string {
+	return "muscle_groups"
+}
+
+// Label возвращает название группы с эмодзи для кнопок
+func (g *MuscleGroupRecord) Label() string {
+	return g.Emoji + " " + string(g.Name)
+}
+
+// MuscleGroupNames перечисляет названия групп через запятую
+func MuscleGroupNames(groups []MuscleGroupRecord) string {
+	names := make([]string, len(groups))
+	for i, g := range groups {
+		names[i] = string(g.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
 // Workout - тренировка
 type Workout struct {
 	ID               int64          `gorm:"primaryKey;autoIncrement" json:"id"`
@@ -116,42 +197,315 @@ type Workout struct {
 	ClientTelegramID int64          `gorm:"not null;index" json:"client_telegram_id"`
 	Date             time.Time      `gorm:"not null;index" json:"date"`
 	Notes            string         `gorm:"type:text" json:"notes"`
-	MuscleGroup      MuscleGroup    `gorm:"type:varchar(50);not null" json:"muscle_group"`
+	TemplateID       *int64         `gorm:"index" json:"template_id"` // шаблон, по которому начата тренировка
+	StartedAt        *time.Time     `json:"started_at"`               // nil - время начала неизвестно
+	FinishedAt       *time.Time     `json:"finished_at"`              // nil - не завершена или время неизвестно
+	Status           WorkoutStatus  `gorm:"type:varchar(10);not null;default:draft;index" json:"status"`
 	CreatedAt        time.Time      `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt        time.Time      `gorm:"autoUpdateTime" json:"-"`
 	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// Relations
-	TrainerClient *TrainerClient `gorm:"foreignKey:TrainerClientID" json:"-"`
-	Exercises     []Exercise     `gorm:"foreignKey:WorkoutID" json:"-"`
+	TrainerClient *TrainerClient      `gorm:"foreignKey:TrainerClientID" json:"-"`
+	Exercises     []Exercise          `gorm:"foreignKey:WorkoutID" json:"-"`
+	MuscleGroups  []MuscleGroupRecord `gorm:"many2many:workout_muscle_groups;joinForeignKey:WorkoutID;joinReferences:MuscleGroupID" json:"muscle_groups"`
+}
+
+// WorkoutStatus - статус тренировки
+type WorkoutStatus string
+
+const (
+	// WorkoutDraft - тренировка идёт, упражнения ещё добавляются
+	WorkoutDraft WorkoutStatus = "draft"
+	// WorkoutCompleted - тренировка завершена; только такие попадают в историю, статистику и счётчики
+	WorkoutCompleted WorkoutStatus = "completed"
+	// WorkoutCancelled - тренировку отменили или бросили пустой, она удалена
+	WorkoutCancelled WorkoutStatus = "cancelled"
+)
+
+// MuscleGroupNames возвращает группы мышц тренировки через запятую
+func (w *Workout) MuscleGroupNames() string {
+	return MuscleGroupNames(w.MuscleGroups)
+}
+
+// MaxWorkoutDuration - дольше тренировка не длится. Если «Завершить» нажали позже,
+// время окончания неизвестно и не записывается.
+// Черновик, в который столько же не добавляли упражнений, считается брошенным.
+const MaxWorkoutDuration = 6 * time.Hour
+
+// Duration возвращает длительность тренировки, если известны начало и конец
+func (w *Workout) Duration() (time.Duration, bool) {
+	if w.StartedAt == nil || w.FinishedAt == nil {
+		return 0, false
+	}
+	return w.FinishedAt.Sub(*w.StartedAt), true
+}
+
+// CanFinishAt проверяет, можно ли считать at временем окончания тренировки
+func (w *Workout) CanFinishAt(at time.Time) bool {
+	if w.StartedAt == nil || w.FinishedAt != nil {
+		return false
+	}
+	return at.After(*w.StartedAt) && at.Sub(*w.StartedAt) <= MaxWorkoutDuration
 }
 
 func (Workout) TableName() string {
 	return "workouts"
 }
 
+// WorkoutFilter - фильтр истории тренировок. Нулевые поля не фильтруют.
+type WorkoutFilter struct {
+	MuscleGroupID   int64     // тренировки с группой мышц с таким же названием, как у этой
+	From            time.Time // с этого дня
+	To              time.Time // по этот день включительно
+	TrainerClientID int64     // тренировки с этим тренером
+}
+
+// IsEmpty проверяет, что фильтр ничего не ограничивает
+func (f WorkoutFilter) IsEmpty() bool {
+	return f == WorkoutFilter{}
+}
+
+// WorkoutScope - чьи завершённые тренировки выбираются: все тренировки клиента
+// или тренировки с одним тренером
+type WorkoutScope struct {
+	ClientTelegramID int64 // все тренировки клиента, если TrainerClientID не задан
+	TrainerClientID  int64 // только тренировки с этим тренером
+	Independent      bool  // вместе с TrainerClientID: и тренировки клиента ClientTelegramID без тренера
+}
+
 // Exercise - упражнение в тренировке
 type Exercise struct {
-	ID          int64     `gorm:"primaryKey;autoIncrement" json:"id"`
-	WorkoutID   int64     `gorm:"not null;index" json:"workout_id"`
-	Name        string    `gorm:"type:varchar(255);not null" json:"name"`
-	Sets        int       `gorm:"not null" json:"sets"`
-	Reps        int       `gorm:"not null" json:"reps"`
-	Weight      float64   `gorm:"type:decimal(10,2)" json:"weight"`
-	RestSeconds int       `json:"rest_seconds"`
-	PhotoFileID string    `gorm:"type:varchar(255)" json:"photo_file_id"`
-	Notes       string    `gorm:"type:text" json:"notes"`
-	Order       int       `gorm:"not null" json:"order"`
-	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+	ID           int64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	WorkoutID    int64          `gorm:"not null;index" json:"workout_id"`
+	CatalogID    *int64         `gorm:"index" json:"catalog_id"` // упражнение справочника
+	Name         string         `gorm:"type:varchar(255);not null" json:"name"`
+	RestSeconds  int            `json:"rest_seconds"`
+	PhotoFileID  string         `gorm:"type:varchar(255)" json:"photo_file_id"`
+	Notes        string         `gorm:"type:text" json:"notes"`
+	Order        int            `gorm:"not null" json:"order"`
+	Bodyweight   bool           `gorm:"not null;default:false" json:"bodyweight"`         // со своим весом: вес подходов - отягощение (+) или помощь (−)
+	BodyweightKg *float64       `gorm:"type:decimal(5,2)" json:"bodyweight_kg,omitempty"` // свой вес клиента на момент тренировки
+	CreatedAt    time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt    time.Time      `gorm:"autoUpdateTime" json:"-"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// Relations
-	Workout Workout `gorm:"foreignKey:WorkoutID" json:"-"`
+	Workout Workout          `gorm:"foreignKey:WorkoutID" json:"-"`
+	Catalog *CatalogExercise `gorm:"foreignKey:CatalogID" json:"-"`
+	Sets    []ExerciseSet    `gorm:"foreignKey:ExerciseID" json:"sets"`
+	Cardio  *CardioEntry     `gorm:"foreignKey:ExerciseID" json:"cardio,omitempty"` // у кардио вместо подходов
 }
 
 func (Exercise) TableName() string {
 	return "exercises"
 }
 
+// TopSet возвращает самый тяжёлый рабочий подход (при равном весе - с большим числом повторений).
+// Разминочные подходы не учитываются; nil, если рабочих подходов нет.
+func (e *Exercise) TopSet() *ExerciseSet {
+	var top *ExerciseSet
+	for i := range e.Sets {
+		set := &e.Sets[i]
+		if set.SetType == SetWarmup {
+			continue
+		}
+		if top == nil || set.Weight > top.Weight || (set.Weight == top.Weight && set.Reps > top.Reps) {
+			top = set
+		}
+	}
+	return top
+}
+
+// Load возвращает нагрузку подхода в кг. У упражнений со своим весом это свой вес
+// плюс отягощение (минус помощь); если свой вес не указан - только отягощение.
+func (e *Exercise) Load(set ExerciseSet) float64 {
+	if e.Bodyweight && e.BodyweightKg != nil {
+		return *e.BodyweightKg + set.Weight
+	}
+	return set.Weight
+}
+
+// Volume возвращает тоннаж упражнения (повторения × нагрузка) без разминочных подходов
+func (e *Exercise) Volume() float64 {
+	var volume float64
+	for _, set := range e.Sets {
+		if load := e.Load(set); set.SetType != SetWarmup && load > 0 {
+			volume += float64(set.Reps) * load
+		}
+	}
+	return volume
+}
+
+// CatalogExercise - упражнение справочника.
+// Без OrganizationID - общее для всех, иначе - упражнение организации.
+type CatalogExercise struct {
+	ID             int64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	OrganizationID *int64         `gorm:"index" json:"organization_id"`
+	Name           string         `gorm:"type:varchar(255);not null" json:"name"`
+	NormalizedName string         `gorm:"type:varchar(255);not null;index" json:"-"`
+	MuscleGroup    MuscleGroup    `gorm:"type:varchar(50)" json:"muscle_group"`
+	Equipment      string         `gorm:"type:varchar(100)" json:"equipment"`
+	CreatedAt      time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time      `gorm:"autoUpdateTime" json:"-"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Relations
+	Aliases []CatalogAlias `gorm:"foreignKey:CatalogID" json:"aliases"`
+}
+
+func (CatalogExercise) TableName() string {
+	return "exercise_catalog"
+}
+
+// EquipmentBodyweight - оборудование упражнений с собственным весом в справочнике
+const EquipmentBodyweight = "Собственный вес"
+
+// IsBodyweight - упражнение выполняется с собственным весом (подтягивания, брусья, отжимания)
+func (e *CatalogExercise) IsBodyweight() bool {
+	return e.Equipment == EquipmentBodyweight
+}
+
+// CatalogAlias - другое название упражнения справочника («жим штанги лёжа» для «Жим лёжа»)
+type CatalogAlias struct {
+	ID              int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	CatalogID       int64     `gorm:"not null;index" json:"catalog_id"`
+	Alias           string    `gorm:"type:varchar(255);not null" json:"alias"`
+	NormalizedAlias string    `gorm:"type:varchar(255);not null;index" json:"-"`
+	CreatedAt       time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (CatalogAlias) TableName() string {
+	return "exercise_catalog_aliases"
+}
+
+// SetType - тип подхода
+type SetType string
+
+const (
+	SetWorking SetType = "working"
+	SetWarmup  SetType = "warmup"
+	SetDrop    SetType = "drop"
+	SetFailure SetType = "failure"
+)
+
+// ExerciseSet - один подход упражнения
+type ExerciseSet struct {
+	ID         int64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	ExerciseID int64          `gorm:"not null;index" json:"exercise_id"`
+	SetIndex   int            `gorm:"not null" json:"set_index"`
+	Reps       int            `gorm:"not null" json:"reps"`
+	Weight     float64        `gorm:"type:decimal(10,2);not null" json:"weight"`
+	SetType    SetType        `gorm:"type:varchar(20);not null;default:working" json:"set_type"`
+	RPE        *float64       `gorm:"column:rpe;type:decimal(3,1)" json:"rpe,omitempty"`
+	CreatedAt  time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt  time.Time      `gorm:"autoUpdateTime" json:"-"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (ExerciseSet) TableName() string {
+	return "exercise_sets"
+}
+
+// CardioEntry - показатели кардио-упражнения: дистанция, время, пульс, набор высоты.
+// Темп и скорость не хранятся - считаются из дистанции и времени.
+type CardioEntry struct {
+	ID              int64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	ExerciseID      int64          `gorm:"not null;uniqueIndex" json:"exercise_id"`
+	DistanceKm      float64        `gorm:"type:decimal(8,3);not null;default:0" json:"distance_km"` // 0 - не указана
+	DurationSeconds int            `gorm:"not null;default:0" json:"duration_seconds"`              // 0 - не указано
+	AvgHeartRate    *int           `json:"avg_heart_rate,omitempty"`
+	MaxHeartRate    *int           `json:"max_heart_rate,omitempty"`
+	ElevationM      *int           `json:"elevation_m,omitempty"`
+	CreatedAt       time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time      `gorm:"autoUpdateTime" json:"-"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (CardioEntry) TableName() string {
+	return "cardio_entries"
+}
+
+// PaceSecondsPerKm возвращает темп (секунд на километр), если известны дистанция и время
+func (c *CardioEntry) PaceSecondsPerKm() (float64, bool) {
+	if c.DistanceKm <= 0 || c.DurationSeconds <= 0 {
+		return 0, false
+	}
+	return float64(c.DurationSeconds) / c.DistanceKm, true
+}
+
+// SpeedKmh возвращает среднюю скорость (км/ч), если известны дистанция и время
+func (c *CardioEntry) SpeedKmh() (float64, bool) {
+	if c.DistanceKm <= 0 || c.DurationSeconds <= 0 {
+		return 0, false
+	}
+	return c.DistanceKm / (float64(c.DurationSeconds) / 3600), true
+}
+
+// WorkoutTemplate - шаблон тренировки организации: упражнения с целевыми подходами
+type WorkoutTemplate struct {
+	ID             int64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	OrganizationID int64          `gorm:"not null;index" json:"organization_id"`
+	TrainerID      *int64         `json:"trainer_id"` // автор, ссылка на organization_trainers.id
+	Name           string         `gorm:"type:varchar(255);not null" json:"name"`
+	Notes          string         `gorm:"type:text" json:"notes"`
+	CreatedAt      time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time      `gorm:"autoUpdateTime" json:"-"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Relations
+	Exercises    []TemplateExercise  `gorm:"foreignKey:TemplateID" json:"exercises"`
+	MuscleGroups []MuscleGroupRecord `gorm:"many2many:workout_template_muscle_groups;joinForeignKey:TemplateID;joinReferences:MuscleGroupID" json:"muscle_groups"`
+}
+
+func (WorkoutTemplate) TableName() string {
+	return "workout_templates"
+}
+
+// TemplateExercise - упражнение шаблона с целью «подходы × повторения × вес»
+type TemplateExercise struct {
+	ID           int64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	TemplateID   int64          `gorm:"not null;index" json:"template_id"`
+	CatalogID    *int64         `json:"catalog_id"`
+	Name         string         `gorm:"type:varchar(255);not null" json:"name"`
+	Order        int            `gorm:"not null" json:"order"`
+	TargetSets   int            `gorm:"not null" json:"target_sets"`
+	TargetReps   int            `gorm:"not null" json:"target_reps"`
+	TargetWeight float64        `gorm:"type:decimal(10,2);not null" json:"target_weight"`
+	CreatedAt    time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt    time.Time      `gorm:"autoUpdateTime" json:"-"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (TemplateExercise) TableName() string {
+	return "workout_template_exercises"
+}
+
+// TargetSetList возвращает целевые подходы в виде подходов упражнения
+func (e *TemplateExercise) TargetSetList() []ExerciseSet {
+	sets := make([]ExerciseSet, e.TargetSets)
+	for i := range sets {
+		sets[i] = ExerciseSet{SetIndex: i + 1, Reps: e.TargetReps, Weight: e.TargetWeight, SetType: SetWorking}
+	}
+	return sets
+}
+
+// TemplateAssignment - шаблон, назначенный клиенту тренера
+type TemplateAssignment struct {
+	ID              int64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	TemplateID      int64          `gorm:"not null;index" json:"template_id"`
+	TrainerClientID int64          `gorm:"not null;index" json:"trainer_client_id"`
+	CreatedAt       time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Relations
+	Template WorkoutTemplate `gorm:"foreignKey:TemplateID" json:"-"`
+}
+
+func (TemplateAssignment) TableName() string {
+	return "workout_template_assignments"
+}
+
 // GroupTraining - групповая тренировка
 type GroupTraining struct {
 	ID              int64          `gorm:"primaryKey;autoIncrement" json:"id"`
@@ -210,6 +564,18 @@ type UserState struct {
 	Data       map[string]interface{}
 }
 
+// UserStateRecord - сохранённое в БД состояние диалога (Data сериализована)
+type UserStateRecord struct {
+	TelegramID int64     `gorm:"primaryKey;autoIncrement:false" json:"telegram_id"`
+	State      string    `gorm:"type:varchar(100);not null" json:"state"`
+	Data       []byte    `gorm:"type:jsonb;not null" json:"data"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (UserStateRecord) TableName() string {
+	return "user_states"
+}
+
 // AccessInfo - информация о доступах пользователя
 type AccessInfo struct {
 	IsAdmin        bool
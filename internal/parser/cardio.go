@@ -0,0 +1,184 @@
+package parser
+
+import (
+	"fitness-bot/internal/models"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Пределы, за которыми скорее опечатка, чем реальная тренировка
+const (
+	MaxCardioDistanceKm = 1000
+	MaxCardioSeconds    = 24 * 60 * 60
+	MinHeartRate        = 30
+	MaxHeartRate        = 250
+)
+
+var (
+	// Дистанция: «5км», «5.2 km», «800м», «800 m»
+	distancePattern = regexp.MustCompile(`(?i)(?:^|\s)` + num + `\s*(км|km|м|m)(?:\s|$)`)
+	// Время: «28:30», «1:05:00»
+	clockDurationPattern = regexp.MustCompile(`(?:^|\s)(\d{1,2}):(\d{2})(?::(\d{2}))?(?:\s|$)`)
+	// Время: «45 мин», «1ч 20мин», «1 h 20 min»
+	unitDurationPattern = regexp.MustCompile(`(?i)(?:^|\s)(?:(\d+)\s*(?:ч|h)\s*)?(\d+)\s*(?:мин|min)(?:\s|$)|(?:^|\s)(\d+)\s*(?:ч|h)(?:\s|$)`)
+	// Пульс: «пульс 150», «пульс 150/175», «hr 150/175»
+	heartRatePattern = regexp.MustCompile(`(?i)(?:^|\s)(?:пульс|чсс|hr)\s*(\d+)(?:\s*/\s*(\d+))?(?:\s|$)`)
+	// Набор высоты: «набор 120», «подъём 120м», «elev 120 m»
+	elevationPattern = regexp.MustCompile(`(?i)(?:^|\s)(?:набор|подъ[её]м|elev)\s*(\d+)\s*(?:м|m)?(?:\s|$)`)
+	// Подходы «4x10» - значит, это силовое упражнение
+	setsPattern = regexp.MustCompile(`(?i)\d` + x + `\d`)
+)
+
+// LooksLikeCardio проверяет, записано ли сообщение в формате кардио:
+// в первой строке есть дистанция или время и нет подходов «x».
+func LooksLikeCardio(text string) bool {
+	for _, raw := range strings.Split(text, "\n") {
+		s := strings.TrimSpace(raw)
+		if s == "" {
+			continue
+		}
+		if setsPattern.MatchString(s) {
+			return false
+		}
+		return distancePattern.MatchString(s) || clockDurationPattern.MatchString(s) || unitDurationPattern.MatchString(s)
+	}
+	return false
+}
+
+// ParseCardio разбирает кардио-упражнения, по одному в строке:
+// название, затем дистанция и/или время, пульс «средний/максимальный», набор высоты.
+//
+//	Бег 5км 28:30
+//	Велосипед 20 км 45 мин пульс 140/165
+//	Ходьба в гору 3км 1:05:00 набор 250м
+func ParseCardio(text string) ([]Exercise, error) {
+	var result []Exercise
+	for i, raw := range strings.Split(text, "\n") {
+		l := line{n: i + 1, text: strings.TrimSpace(raw)}
+		if l.text == "" {
+			continue
+		}
+		rest, err := splitRest(&l)
+		if err != nil {
+			return nil, err
+		}
+		if rest > 0 && l.text == "" {
+			if len(result) == 0 {
+				return nil, l.errorf("сначала укажите упражнение, потом отдых")
+			}
+			result[len(result)-1].RestSeconds = rest
+			continue
+		}
+		name, cardio, err := parseCardioLine(l)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, Exercise{Line: l.n, Name: name, Cardio: &cardio, RestSeconds: rest})
+	}
+	if len(result) == 0 {
+		return nil, &ParseError{Line: 1, Msg: "упражнение не найдено"}
+	}
+	return result, nil
+}
+
+// ParseCardioValues разбирает показатели без названия: «5км 28:30 пульс 150»
+func ParseCardioValues(text string) (models.CardioEntry, error) {
+	_, cardio, err := parseCardioLine(line{n: 1, text: "кардио " + strings.TrimSpace(text)})
+	return cardio, err
+}
+
+// parseCardioLine разбирает строку «Название показатели...».
+// Название - всё до первого показателя.
+func parseCardioLine(l line) (string, models.CardioEntry, error) {
+	var c models.CardioEntry
+	rest := " " + l.text + " "
+	nameEnd := len(rest)
+	cut := func(loc []int) {
+		if loc[0] < nameEnd {
+			nameEnd = loc[0]
+		}
+		rest = rest[:loc[0]] + " " + strings.Repeat(" ", loc[1]-loc[0]-1) + rest[loc[1]:]
+	}
+
+	if m := heartRatePattern.FindStringSubmatchIndex(rest); m != nil {
+		avg, _ := strconv.Atoi(rest[m[2]:m[3]])
+		c.AvgHeartRate = &avg
+		if m[4] >= 0 {
+			peak, _ := strconv.Atoi(rest[m[4]:m[5]])
+			c.MaxHeartRate = &peak
+		}
+		cut(m)
+	}
+	if m := elevationPattern.FindStringSubmatchIndex(rest); m != nil {
+		elev, _ := strconv.Atoi(rest[m[2]:m[3]])
+		c.ElevationM = &elev
+		cut(m)
+	}
+	if m := distancePattern.FindStringSubmatchIndex(rest); m != nil {
+		value, err := strconv.ParseFloat(strings.Replace(rest[m[2]:m[3]], ",", ".", 1), 64)
+		if err != nil {
+			return "", c, l.errorf("не понял дистанцию")
+		}
+		if unit := strings.ToLower(rest[m[4]:m[5]]); unit == "м" || unit == "m" {
+			value /= 1000
+		}
+		c.DistanceKm = value
+		cut(m)
+	}
+	if m := clockDurationPattern.FindStringSubmatchIndex(rest); m != nil {
+		a, _ := strconv.Atoi(rest[m[2]:m[3]])
+		b, _ := strconv.Atoi(rest[m[4]:m[5]])
+		if m[6] >= 0 {
+			s, _ := strconv.Atoi(rest[m[6]:m[7]])
+			if b > 59 || s > 59 {
+				return "", c, l.errorf("не понял время - напишите например 1:05:00")
+			}
+			c.DurationSeconds = a*3600 + b*60 + s
+		} else {
+			if b > 59 {
+				return "", c, l.errorf("не понял время - напишите например 28:30")
+			}
+			c.DurationSeconds = a*60 + b
+		}
+		cut(m)
+	} else if m := unitDurationPattern.FindStringSubmatchIndex(rest); m != nil {
+		var hours, minutes int
+		if m[2] >= 0 {
+			hours, _ = strconv.Atoi(rest[m[2]:m[3]])
+		}
+		if m[4] >= 0 {
+			minutes, _ = strconv.Atoi(rest[m[4]:m[5]])
+		}
+		if m[6] >= 0 {
+			hours, _ = strconv.Atoi(rest[m[6]:m[7]])
+		}
+		c.DurationSeconds = hours*3600 + minutes*60
+		cut(m)
+	}
+
+	name := cleanName(rest[:nameEnd])
+	if left := strings.TrimSpace(rest[nameEnd:]); left != "" {
+		return "", c, l.errorf("не понял «" + left + "» - напишите например «Бег 5км 28:30 пульс 150/175»")
+	}
+	return name, c, validateCardio(l, name, c)
+}
+
+func validateCardio(l line, name string, e models.CardioEntry) error {
+	switch {
+	case name == "":
+		return l.errorf("укажите название, например «Бег 5км 28:30»")
+	case e.DistanceKm <= 0 && e.DurationSeconds <= 0:
+		return l.errorf("укажите дистанцию или время, например «Бег 5км 28:30»")
+	case e.DistanceKm > MaxCardioDistanceKm:
+		return l.errorf("слишком большая дистанция")
+	case e.DurationSeconds > MaxCardioSeconds:
+		return l.errorf("слишком долго - больше суток")
+	case e.AvgHeartRate != nil && (*e.AvgHeartRate < MinHeartRate || *e.AvgHeartRate > MaxHeartRate),
+		e.MaxHeartRate != nil && (*e.MaxHeartRate < MinHeartRate || *e.MaxHeartRate > MaxHeartRate):
+		return l.errorf("пульс должен быть от 30 до 250")
+	case e.AvgHeartRate != nil && e.MaxHeartRate != nil && *e.MaxHeartRate < *e.AvgHeartRate:
+		return l.errorf("максимальный пульс меньше среднего - сначала средний, потом максимальный: 150/175")
+	}
+	return nil
+}
@@ -0,0 +1,478 @@
+// Package parser разбирает упражнения, которые клиент присылает текстом.
+//
+// Поддерживаемые записи (можно смешивать в одном сообщении, по упражнению в строке):
+//
+//	Жим лежа 4x10x80          - подходы × повторения × вес
+//	Присед 100кг 5×5          - вес, затем подходы × повторения
+//	Присед 5x5 100кг          - то же в другом порядке
+//	Подтягивания 3x12         - без веса
+//	Подтягивания +10кг 3x8    - со своим весом: отягощение со знаком «+»
+//	Гравитрон 3x10 -25        - со своим весом: помощь со знаком «−»
+//
+// и блоком - название, затем подходы по одному в строке «вес x повторения»:
+//
+//	Жим лежа
+//	80x10
+//	85x8 @8
+//	60x12 дроп
+//
+// Старый формат из четырёх строк (название, подходы, повторения, вес) тоже принимается.
+//
+// Вес без единиц - в единицах пользователя (см. Parse), «кг» и «lb» после веса задают их явно.
+//
+// Кардио записывается дистанцией и временем - см. ParseCardio:
+//
+//	Бег 5км 28:30 пульс 150/175
+package parser
+
+import (
+	"fitness-bot/internal/models"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MaxSets - больше подходов в одной записи не бывает; «Жим 80x10» скорее всего вес × повторения
+const MaxSets = 20
+
+// MaxRestSeconds - дольше отдых между подходами не бывает
+const MaxRestSeconds = 15 * 60
+
+// Exercise - разобранное упражнение
+type Exercise struct {
+	Line        int // номер строки с названием (с 1)
+	Name        string
+	Sets        []models.ExerciseSet
+	RestSeconds int                 // отдых между подходами, 0 - не указан
+	Bodyweight  bool                // вес записан со знаком: отягощение (+) или помощь (−) к своему весу
+	Cardio      *models.CardioEntry // у кардио вместо подходов, см. ParseCardio
+}
+
+// ParseError - ошибка разбора с указанием строки сообщения
+type ParseError struct {
+	Line int    // номер строки (с 1)
+	Text string // текст строки
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	if e.Text == "" {
+		return e.Msg
+	}
+	return fmt.Sprintf("строка %d «%s»: %s", e.Line, e.Text, e.Msg)
+}
+
+const (
+	num    = `(\d+(?:[.,]\d+)?)`
+	signed = `([+-]\d+(?:[.,]\d+)?)` // отягощение или помощь в упражнениях со своим весом
+	weight = `([+-]?\d+(?:[.,]\d+)?)`
+	kg     = `\s*(?:кг|kg|lbs?|фунт(?:а|ов)?)` // любые единицы веса, см. lineUnit
+	x      = `\s*[xх×*]\s*`
+)
+
+var (
+	// Подход отдельной строкой: «80x10», «80 кг x 10», «x12»
+	setLinePattern = regexp.MustCompile(`(?i)^(?:` + weight + `(?:` + kg + `)?)?` + x + `(\d+)$`)
+
+	// Упражнение одной строкой - см. описание пакета
+	inlinePatterns = []struct {
+		re                 *regexp.Regexp
+		sets, reps, weight int // номера групп, 0 - нет
+	}{
+		{regexp.MustCompile(`(?i)^(.+?)\s+(\d+)` + x + `(\d+)` + x + weight + `(?:` + kg + `)?$`), 2, 3, 4},
+		{regexp.MustCompile(`(?i)^(.+?)\s+` + weight + kg + `\s+(\d+)` + x + `(\d+)$`), 3, 4, 2},
+		{regexp.MustCompile(`(?i)^(.+?)\s+(\d+)` + x + `(\d+)\s+` + weight + kg + `$`), 2, 3, 4},
+		{regexp.MustCompile(`(?i)^(.+?)\s+` + signed + `\s+(\d+)` + x + `(\d+)$`), 3, 4, 2},
+		{regexp.MustCompile(`(?i)^(.+?)\s+(\d+)` + x + `(\d+)\s+` + signed + `$`), 2, 3, 4},
+		{regexp.MustCompile(`(?i)^(.+?)\s+(\d+)` + x + `(\d+)$`), 2, 3, 0},
+	}
+
+	bareNumberPattern = regexp.MustCompile(`^` + num + `$`)
+	weightOnlyPattern = regexp.MustCompile(`(?i)^` + num + `(?:` + kg + `)?$`)
+
+	// Явные единицы веса в строке
+	lbPattern = regexp.MustCompile(`(?i)\d\s*(?:lbs?|фунт)`)
+	kgPattern = regexp.MustCompile(`(?i)\d\s*(?:кг|kg)`)
+
+	// Отдых в конце строки: «отдых 90», «отдых 90с», «отдых 2 мин», «отдых 1:30», «rest 90s»
+	restPattern = regexp.MustCompile(`(?i)(?:^|\s+)(?:отдых|rest)\s*(\d+)(?::(\d{2}))?\s*(мин|min|m|сек|с|sec|s)?\.?$`)
+)
+
+// setTypeWords - отметки типа подхода (по началу слова)
+var setTypeWords = []struct {
+	prefix  string
+	setType models.SetType
+}{
+	{"разм", models.SetWarmup},
+	{"warm", models.SetWarmup},
+	{"дроп", models.SetDrop},
+	{"drop", models.SetDrop},
+	{"отказ", models.SetFailure},
+	{"fail", models.SetFailure},
+}
+
+// block - упражнение, записанное блоком: название и подходы в следующих строках
+type block struct {
+	exercise Exercise
+	numbers  []line // строки с одними числами (старый формат)
+}
+
+type line struct {
+	n    int
+	text string
+	unit models.WeightUnit // в чём записан вес в этой строке
+}
+
+// newLine - строка сообщения; вес без явных единиц считается в единицах unit
+func newLine(n int, raw string, unit models.WeightUnit) line {
+	text := strings.TrimSpace(raw)
+	switch {
+	case lbPattern.MatchString(text):
+		unit = models.UnitLb
+	case kgPattern.MatchString(text):
+		unit = models.UnitKg
+	}
+	return line{n: n, text: text, unit: unit}
+}
+
+// Parse разбирает сообщение с одним или несколькими упражнениями.
+// Вес без единиц - в единицах unit, в результате вес всегда в кг.
+func Parse(text string, unit models.WeightUnit) ([]Exercise, error) {
+	var exercises []Exercise
+	var current *block
+
+	closeBlock := func() error {
+		if current == nil {
+			return nil
+		}
+		b := current
+		current = nil
+		ex, err := b.finish()
+		if err != nil {
+			return err
+		}
+		exercises = append(exercises, ex)
+		return nil
+	}
+
+	for i, raw := range strings.Split(text, "\n") {
+		l := newLine(i+1, raw, unit)
+		if l.text == "" {
+			continue
+		}
+
+		rest, err := splitRest(&l)
+		if err != nil {
+			return nil, err
+		}
+		// «отдых 90» отдельной строкой относится к упражнению выше
+		if rest > 0 && l.text == "" {
+			switch {
+			case current != nil:
+				current.exercise.RestSeconds = rest
+			case len(exercises) > 0:
+				exercises[len(exercises)-1].RestSeconds = rest
+			default:
+				return nil, l.errorf("сначала укажите упражнение, потом отдых")
+			}
+			continue
+		}
+
+		if bareNumberPattern.MatchString(l.text) {
+			if current == nil {
+				return nil, l.errorf("сначала укажите название упражнения")
+			}
+			current.numbers = append(current.numbers, l)
+			continue
+		}
+
+		body, setType, rpe, err := splitTags(l)
+		if err != nil {
+			return nil, err
+		}
+
+		if setLinePattern.MatchString(body) {
+			if rest > 0 {
+				return nil, l.errorf("отдых указывается после названия упражнения или отдельной строкой")
+			}
+			if current == nil {
+				return nil, l.errorf("подход без названия упражнения - напишите название строкой выше")
+			}
+			set, signed, err := parseSetLine(l, body)
+			if err != nil {
+				return nil, err
+			}
+			if signed {
+				current.exercise.Bodyweight = true
+			}
+			set.SetType, set.RPE = setType, rpe
+			set.SetIndex = len(current.exercise.Sets) + 1
+			current.exercise.Sets = append(current.exercise.Sets, set)
+			continue
+		}
+
+		if err := closeBlock(); err != nil {
+			return nil, err
+		}
+
+		if ex, ok, err := parseInline(l, body, setType, rpe); err != nil {
+			return nil, err
+		} else if ok {
+			ex.RestSeconds = rest
+			exercises = append(exercises, ex)
+			continue
+		}
+
+		if setType != models.SetWorking || rpe != nil {
+			return nil, l.errorf("отметки подхода ставятся после «вес x повторения»")
+		}
+		current = &block{exercise: Exercise{Line: l.n, Name: cleanName(l.text), RestSeconds: rest}}
+	}
+
+	if err := closeBlock(); err != nil {
+		return nil, err
+	}
+	if len(exercises) == 0 {
+		return nil, &ParseError{Line: 1, Msg: "упражнение не найдено"}
+	}
+	return exercises, nil
+}
+
+// ParseSets разбирает только подходы, по одному в строке «вес x повторения».
+// Вес без единиц - в единицах unit, в результате - в кг.
+// bodyweight - вес записан со знаком (отягощение или помощь к своему весу).
+func ParseSets(text string, unit models.WeightUnit) (sets []models.ExerciseSet, bodyweight bool, err error) {
+	for i, raw := range strings.Split(text, "\n") {
+		l := newLine(i+1, raw, unit)
+		if l.text == "" {
+			continue
+		}
+		body, setType, rpe, err := splitTags(l)
+		if err != nil {
+			return nil, false, err
+		}
+		if !setLinePattern.MatchString(body) {
+			return nil, false, l.errorf("ожидается «вес x повторения», например 80x10")
+		}
+		set, signed, err := parseSetLine(l, body)
+		if err != nil {
+			return nil, false, err
+		}
+		bodyweight = bodyweight || signed
+		set.SetType, set.RPE = setType, rpe
+		set.SetIndex = len(sets) + 1
+		sets = append(sets, set)
+	}
+	if len(sets) == 0 {
+		return nil, false, &ParseError{Line: 1, Msg: "добавьте хотя бы один подход, например 80x10"}
+	}
+	return sets, bodyweight, nil
+}
+
+// splitRest убирает из строки отметку отдыха и возвращает его в секундах (0 - отметки нет).
+// Число без единиц - секунды, «1:30» - минуты и секунды.
+func splitRest(l *line) (int, error) {
+	m := restPattern.FindStringSubmatchIndex(l.text)
+	if m == nil {
+		return 0, nil
+	}
+	first, _ := strconv.Atoi(l.text[m[2]:m[3]])
+	seconds := first
+	switch {
+	case m[4] >= 0:
+		sec, _ := strconv.Atoi(l.text[m[4]:m[5]])
+		seconds = first*60 + sec
+	case m[6] >= 0 && isMinutes(l.text[m[6]:m[7]]):
+		seconds = first * 60
+	}
+	if seconds <= 0 || seconds > MaxRestSeconds {
+		return 0, l.errorf(fmt.Sprintf("отдых должен быть от 1 секунды до %d минут", MaxRestSeconds/60))
+	}
+	l.text = strings.TrimSpace(l.text[:m[0]])
+	return seconds, nil
+}
+
+func isMinutes(unit string) bool {
+	unit = strings.ToLower(unit)
+	return strings.HasPrefix(unit, "м") || strings.HasPrefix(unit, "m")
+}
+
+// finish проверяет упражнение, записанное блоком
+func (b *block) finish() (Exercise, error) {
+	ex := b.exercise
+	switch {
+	case len(b.numbers) == 0 && len(ex.Sets) == 0:
+		return ex, &ParseError{Line: ex.Line, Text: ex.Name, Msg: "нет подходов - добавьте строки вида 80x10 или запишите «" + ex.Name + " 4x10x80»"}
+	case len(b.numbers) > 0 && len(ex.Sets) > 0:
+		return ex, b.numbers[0].errorf("не смешивайте подходы «вес x повторения» с отдельными числами")
+	case len(b.numbers) > 0:
+		if len(b.numbers) != 3 {
+			return ex, b.numbers[0].errorf("отдельными числами укажите три строки: подходы, повторения, вес")
+		}
+		count, err := parseCount(b.numbers[0], b.numbers[0].text, "подходов")
+		if err != nil {
+			return ex, err
+		}
+		reps, err := parseCount(b.numbers[1], b.numbers[1].text, "повторений")
+		if err != nil {
+			return ex, err
+		}
+		weight, _, err := parseWeight(b.numbers[2], b.numbers[2].text)
+		if err != nil {
+			return ex, err
+		}
+		ex.Sets = repeatSet(count, reps, weight, models.SetWorking, nil)
+	}
+	return ex, nil
+}
+
+// parseInline разбирает упражнение, записанное одной строкой
+func parseInline(l line, body string, setType models.SetType, rpe *float64) (Exercise, bool, error) {
+	for _, p := range inlinePatterns {
+		m := p.re.FindStringSubmatch(body)
+		if m == nil {
+			continue
+		}
+		count, err := parseCount(l, m[p.sets], "подходов")
+		if err != nil {
+			return Exercise{}, false, err
+		}
+		if count > MaxSets {
+			return Exercise{}, false, l.errorf(fmt.Sprintf("%d подходов - слишком много. Если это вес x повторения, запишите название, а подходы - отдельными строками", count))
+		}
+		reps, err := parseCount(l, m[p.reps], "повторений")
+		if err != nil {
+			return Exercise{}, false, err
+		}
+		var weight float64
+		var signed bool
+		if p.weight != 0 {
+			if weight, signed, err = parseWeight(l, m[p.weight]); err != nil {
+				return Exercise{}, false, err
+			}
+		}
+		return Exercise{
+			Line:       l.n,
+			Name:       cleanName(m[1]),
+			Sets:       repeatSet(count, reps, weight, setType, rpe),
+			Bodyweight: signed,
+		}, true, nil
+	}
+	return Exercise{}, false, nil
+}
+
+// parseSetLine разбирает подход «вес x повторения» (body уже без отметок).
+// signed - вес записан со знаком.
+func parseSetLine(l line, body string) (set models.ExerciseSet, signed bool, err error) {
+	m := setLinePattern.FindStringSubmatch(body)
+	set = models.ExerciseSet{SetType: models.SetWorking}
+	if m[1] != "" {
+		if set.Weight, signed, err = parseWeight(l, m[1]); err != nil {
+			return set, false, err
+		}
+	}
+	if set.Reps, err = parseCount(l, m[2], "повторений"); err != nil {
+		return set, false, err
+	}
+	return set, signed, nil
+}
+
+// splitTags отделяет от строки отметки в конце: тип подхода и RPE («@8»)
+func splitTags(l line) (string, models.SetType, *float64, error) {
+	fields := strings.Fields(l.text)
+	setType := models.SetWorking
+	var rpe *float64
+
+	for len(fields) > 1 {
+		word := strings.ToLower(fields[len(fields)-1])
+		if strings.HasPrefix(word, "@") {
+			value, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimPrefix(word, "@"), ",", "."), 64)
+			if err != nil || value < 1 || value > 10 {
+				return "", "", nil, l.errorf("RPE должен быть от 1 до 10")
+			}
+			rpe = &value
+			fields = fields[:len(fields)-1]
+			continue
+		}
+		known := false
+		for _, t := range setTypeWords {
+			if strings.HasPrefix(word, t.prefix) {
+				setType, known = t.setType, true
+				break
+			}
+		}
+		if !known {
+			break
+		}
+		fields = fields[:len(fields)-1]
+	}
+	return strings.Join(fields, " "), setType, rpe, nil
+}
+
+func repeatSet(count, reps int, weight float64, setType models.SetType, rpe *float64) []models.ExerciseSet {
+	sets := make([]models.ExerciseSet, count)
+	for i := range sets {
+		sets[i] = models.ExerciseSet{SetIndex: i + 1, Reps: reps, Weight: weight, SetType: setType, RPE: rpe}
+	}
+	return sets
+}
+
+func parseCount(l line, s, what string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return 0, l.errorf("число " + what + " должно быть целым и больше нуля")
+	}
+	return n, nil
+}
+
+// parseWeight разбирает вес в единицах строки и возвращает его в кг.
+// signed - вес со знаком: «+10» - отягощение, «-20» - помощь в упражнении со своим весом.
+func parseWeight(l line, s string) (kg float64, signed bool, err error) {
+	weight, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", "."), 64)
+	if err != nil {
+		return 0, false, l.errorf("вес должен быть числом, например 80 или 72.5")
+	}
+	signed = strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-")
+	return l.unit.ToKg(weight), signed, nil
+}
+
+// cleanName убирает лишние пробелы и двоеточие/тире между названием и подходами
+func cleanName(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	return strings.TrimRight(name, " :-–—")
+}
+
+func (l line) errorf(msg string) *ParseError {
+	return &ParseError{Line: l.n, Text: l.text, Msg: msg}
+}
+
+// ParseRest разбирает отдых без слова «отдых»: «90», «90с», «2 мин», «1:30».
+// «0», «нет» и «выкл» - отдых не нужен (0).
+func ParseRest(text string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(text)) {
+	case "0", "нет", "выкл", "off":
+		return 0, nil
+	}
+	l := line{n: 1, text: "отдых " + strings.TrimSpace(text)}
+	rest, err := splitRest(&l)
+	if err != nil {
+		return 0, err
+	}
+	if rest == 0 || l.text != "" {
+		return 0, &ParseError{Line: 1, Msg: "укажите отдых, например 90, 2 мин или 1:30"}
+	}
+	return rest, nil
+}
+
+// ParseWeight разбирает один вес: «80», «80.5 кг», «176 lb».
+// Без единиц - в единицах unit, результат в кг.
+func ParseWeight(text string, unit models.WeightUnit) (float64, error) {
+	l := newLine(1, text, unit)
+	m := weightOnlyPattern.FindStringSubmatch(l.text)
+	if m == nil {
+		return 0, &ParseError{Line: 1, Msg: "укажите вес числом, например 80 или 72.5"}
+	}
+	kg, _, err := parseWeight(l, m[1])
+	return kg, err
+}
@@ -0,0 +1,413 @@
+package parser
+
+import (
+	"errors"
+	"fitness-bot/internal/models"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// set - подход в сравнимом виде: вес округлён до граммов, RPE 0 - не указан
+type set struct {
+	reps   int
+	weight float64
+	typ    models.SetType
+	rpe    float64
+}
+
+type exercise struct {
+	line       int
+	name       string
+	sets       []set
+	rest       int
+	bodyweight bool
+}
+
+func simplifySets(sets []models.ExerciseSet) []set {
+	result := make([]set, len(sets))
+	for i, s := range sets {
+		result[i] = set{reps: s.Reps, weight: round(s.Weight), typ: s.SetType}
+		if s.RPE != nil {
+			result[i].rpe = *s.RPE
+		}
+	}
+	return result
+}
+
+func simplify(exercises []Exercise) []exercise {
+	result := make([]exercise, len(exercises))
+	for i, ex := range exercises {
+		result[i] = exercise{
+			line:       ex.Line,
+			name:       ex.Name,
+			sets:       simplifySets(ex.Sets),
+			rest:       ex.RestSeconds,
+			bodyweight: ex.Bodyweight,
+		}
+	}
+	return result
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// working - count одинаковых рабочих подходов
+func working(count, reps int, weight float64) []set {
+	sets := make([]set, count)
+	for i := range sets {
+		sets[i] = set{reps: reps, weight: weight, typ: models.SetWorking}
+	}
+	return sets
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		unit models.WeightUnit
+		want []exercise
+	}{
+		{
+			name: "подходы x повторения x вес",
+			text: "Жим лежа 4x10x80",
+			want: []exercise{{line: 1, name: "Жим лежа", sets: working(4, 10, 80)}},
+		},
+		{
+			name: "вес перед подходами",
+			text: "Присед 100кг 5×5",
+			want: []exercise{{line: 1, name: "Присед", sets: working(5, 5, 100)}},
+		},
+		{
+			name: "вес после подходов",
+			text: "Присед 5x5 100кг",
+			want: []exercise{{line: 1, name: "Присед", sets: working(5, 5, 100)}},
+		},
+		{
+			name: "без веса",
+			text: "Подтягивания 3x12",
+			want: []exercise{{line: 1, name: "Подтягивания", sets: working(3, 12, 0)}},
+		},
+		{
+			name: "русская х и дробный вес через запятую",
+			text: "Жим гантелей 3х12х22,5",
+			want: []exercise{{line: 1, name: "Жим гантелей", sets: working(3, 12, 22.5)}},
+		},
+		{
+			name: "лишние пробелы и двоеточие в названии",
+			text: "  Жим   лежа:  4x10x80  ",
+			want: []exercise{{line: 1, name: "Жим лежа", sets: working(4, 10, 80)}},
+		},
+		{
+			name: "отягощение",
+			text: "Подтягивания +10кг 3x8",
+			want: []exercise{{line: 1, name: "Подтягивания", sets: working(3, 8, 10), bodyweight: true}},
+		},
+		{
+			name: "помощь",
+			text: "Гравитрон 3x10 -25",
+			want: []exercise{{line: 1, name: "Гравитрон", sets: working(3, 10, -25), bodyweight: true}},
+		},
+		{
+			name: "фунты явно",
+			text: "Жим 3x10x100 lb",
+			want: []exercise{{line: 1, name: "Жим", sets: working(3, 10, 45.36)}},
+		},
+		{
+			name: "фунты по умолчанию",
+			text: "Жим 3x10x100",
+			unit: models.UnitLb,
+			want: []exercise{{line: 1, name: "Жим", sets: working(3, 10, 45.36)}},
+		},
+		{
+			name: "кг явно при фунтах по умолчанию",
+			text: "Жим 3x10x80кг",
+			unit: models.UnitLb,
+			want: []exercise{{line: 1, name: "Жим", sets: working(3, 10, 80)}},
+		},
+		{
+			name: "RPE и тип подхода в строке",
+			text: "Жим 3x10x80 @8,5\nПрисед 2x10x40 разминка",
+			want: []exercise{
+				{line: 1, name: "Жим", sets: []set{
+					{reps: 10, weight: 80, typ: models.SetWorking, rpe: 8.5},
+					{reps: 10, weight: 80, typ: models.SetWorking, rpe: 8.5},
+					{reps: 10, weight: 80, typ: models.SetWorking, rpe: 8.5},
+				}},
+				{line: 2, name: "Присед", sets: []set{
+					{reps: 10, weight: 40, typ: models.SetWarmup},
+					{reps: 10, weight: 40, typ: models.SetWarmup},
+				}},
+			},
+		},
+		{
+			name: "блок с отметками подходов",
+			text: "Жим лежа\n80x10\n85 кг x 8 @8\n60x12 дроп\n90x3 отказ\n40x15 warmup",
+			want: []exercise{{line: 1, name: "Жим лежа", sets: []set{
+				{reps: 10, weight: 80, typ: models.SetWorking},
+				{reps: 8, weight: 85, typ: models.SetWorking, rpe: 8},
+				{reps: 12, weight: 60, typ: models.SetDrop},
+				{reps: 3, weight: 90, typ: models.SetFailure},
+				{reps: 15, weight: 40, typ: models.SetWarmup},
+			}}},
+		},
+		{
+			name: "блок со своим весом",
+			text: "Подтягивания\nx12\n+10x8",
+			want: []exercise{{line: 1, name: "Подтягивания", sets: []set{
+				{reps: 12, typ: models.SetWorking},
+				{reps: 8, weight: 10, typ: models.SetWorking},
+			}, bodyweight: true}},
+		},
+		{
+			name: "старый формат из четырёх строк",
+			text: "Жим лежа\n4\n10\n80",
+			want: []exercise{{line: 1, name: "Жим лежа", sets: working(4, 10, 80)}},
+		},
+		{
+			name: "несколько упражнений разными записями",
+			text: "Жим лежа 4x10x80\n\nПрисед\n100x5\n110x3\nТяга 3x12x60\nЖим стоя\n3\n10\n40",
+			want: []exercise{
+				{line: 1, name: "Жим лежа", sets: working(4, 10, 80)},
+				{line: 3, name: "Присед", sets: []set{
+					{reps: 5, weight: 100, typ: models.SetWorking},
+					{reps: 3, weight: 110, typ: models.SetWorking},
+				}},
+				{line: 6, name: "Тяга", sets: working(3, 12, 60)},
+				{line: 7, name: "Жим стоя", sets: working(3, 10, 40)},
+			},
+		},
+		{
+			name: "отдых в строке упражнения",
+			text: "Жим лежа 4x10x80 отдых 90\nПрисед 5x5x100 rest 2 min\nТяга отдых 1:30\n60x12",
+			want: []exercise{
+				{line: 1, name: "Жим лежа", sets: working(4, 10, 80), rest: 90},
+				{line: 2, name: "Присед", sets: working(5, 5, 100), rest: 120},
+				{line: 3, name: "Тяга", sets: working(1, 12, 60), rest: 90},
+			},
+		},
+		{
+			name: "отдых отдельной строкой",
+			text: "Жим 3x10x80\nотдых 90с\nПрисед\n100x5\nотдых 3 мин",
+			want: []exercise{
+				{line: 1, name: "Жим", sets: working(3, 10, 80), rest: 90},
+				{line: 3, name: "Присед", sets: working(1, 5, 100), rest: 180},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unit := tt.unit
+			if unit == "" {
+				unit = models.UnitKg
+			}
+			got, err := Parse(tt.text, unit)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.text, err)
+			}
+			if simple := simplify(got); !reflect.DeepEqual(simple, tt.want) {
+				t.Errorf("Parse(%q)\n got %+v\nwant %+v", tt.text, simple, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSetIndexes(t *testing.T) {
+	got, err := Parse("Жим 3x10x80\nПрисед\n100x5\n110x3", models.UnitKg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ex := range got {
+		for i, s := range ex.Sets {
+			if s.SetIndex != i+1 {
+				t.Errorf("%s: подход %d имеет SetIndex %d", ex.Name, i+1, s.SetIndex)
+			}
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		line int
+		msg  string // часть текста ошибки
+	}{
+		{"пустое сообщение", "", 1, "упражнение не найдено"},
+		{"подход без названия", "80x10", 1, "подход без названия"},
+		{"число без названия", "5\nЖим 3x10x80", 1, "сначала укажите название"},
+		{"название без подходов", "Жим лежа 4x10x80\nПрисед", 2, "нет подходов"},
+		{"неполный старый формат", "Жим лежа 4x10x80\nПрисед\n5\n5", 3, "три строки"},
+		{"подходы вперемешку с числами", "Жим\n80x10\n5", 3, "не смешивайте"},
+		{"слишком много подходов", "Тяга 3x12x60\nЖим 80x10x5", 2, "слишком много"},
+		{"ноль повторений", "Жим 4x0x80", 1, "повторений"},
+		{"RPE вне диапазона", "Жим 3x10x80\n\nПрисед\n100x5 @11", 4, "RPE"},
+		{"отметка без подходов", "Жим лежа разминка", 1, "отметки подхода"},
+		{"отдых без упражнения", "отдых 90", 1, "сначала укажите упражнение"},
+		{"слишком долгий отдых", "Жим 3x10x80\nПрисед 5x5x100 отдых 20 мин", 2, "отдых должен быть"},
+		{"отдых в строке подхода", "Жим\n80x10 отдых 60", 2, "отдых указывается"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.text, models.UnitKg)
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("Parse(%q) error = %v, want *ParseError", tt.text, err)
+			}
+			if perr.Line != tt.line {
+				t.Errorf("Parse(%q) line = %d, want %d", tt.text, perr.Line, tt.line)
+			}
+			if !strings.Contains(perr.Msg, tt.msg) {
+				t.Errorf("Parse(%q) msg = %q, want it to contain %q", tt.text, perr.Msg, tt.msg)
+			}
+		})
+	}
+}
+
+func TestParseErrorText(t *testing.T) {
+	_, err := Parse("Жим лежа 4x10x80\nПрисед", models.UnitKg)
+	if err == nil || !strings.HasPrefix(err.Error(), "строка 2 «Присед»: ") {
+		t.Errorf("error = %v, want it to name line 2 and its text", err)
+	}
+}
+
+func TestParseSets(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		unit       models.WeightUnit
+		want       []set
+		bodyweight bool
+	}{
+		{
+			name: "подходы с отметками и пустыми строками",
+			text: "80x10\n\n85x8 @8\n60x12 дроп",
+			unit: models.UnitKg,
+			want: []set{
+				{reps: 10, weight: 80, typ: models.SetWorking},
+				{reps: 8, weight: 85, typ: models.SetWorking, rpe: 8},
+				{reps: 12, weight: 60, typ: models.SetDrop},
+			},
+		},
+		{
+			name:       "со своим весом",
+			text:       "x12\n-20x8",
+			unit:       models.UnitKg,
+			want:       []set{{reps: 12, typ: models.SetWorking}, {reps: 8, weight: -20, typ: models.SetWorking}},
+			bodyweight: true,
+		},
+		{
+			name: "единицы в строке важнее единиц пользователя",
+			text: "100 lb x 5\n100x5",
+			unit: models.UnitKg,
+			want: []set{{reps: 5, weight: 45.36, typ: models.SetWorking}, {reps: 5, weight: 100, typ: models.SetWorking}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sets, bodyweight, err := ParseSets(tt.text, tt.unit)
+			if err != nil {
+				t.Fatalf("ParseSets(%q) error: %v", tt.text, err)
+			}
+			if got := simplifySets(sets); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSets(%q)\n got %+v\nwant %+v", tt.text, got, tt.want)
+			}
+			if bodyweight != tt.bodyweight {
+				t.Errorf("ParseSets(%q) bodyweight = %v, want %v", tt.text, bodyweight, tt.bodyweight)
+			}
+		})
+	}
+}
+
+func TestParseSetsErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		line int
+		msg  string
+	}{
+		{"", 1, "хотя бы один подход"},
+		{"80x10\nЖим лежа", 2, "ожидается «вес x повторения»"},
+		{"80x10\n\n85x8 @0", 3, "RPE"},
+	}
+	for _, tt := range tests {
+		_, _, err := ParseSets(tt.text, models.UnitKg)
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("ParseSets(%q) error = %v, want *ParseError", tt.text, err)
+			continue
+		}
+		if perr.Line != tt.line || !strings.Contains(perr.Msg, tt.msg) {
+			t.Errorf("ParseSets(%q) = line %d %q, want line %d containing %q", tt.text, perr.Line, perr.Msg, tt.line, tt.msg)
+		}
+	}
+}
+
+func TestParseRest(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"90", 90},
+		{"90с", 90},
+		{"45 sec", 45},
+		{"2 мин", 120},
+		{"2m", 120},
+		{"1:30", 90},
+		{"0", 0},
+		{"нет", 0},
+		{" Выкл ", 0},
+		{"off", 0},
+	}
+	for _, tt := range tests {
+		got, err := ParseRest(tt.text)
+		if err != nil {
+			t.Errorf("ParseRest(%q) error: %v", tt.text, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRest(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+
+	for _, text := range []string{"", "долго", "90 бег", "20 мин"} {
+		if got, err := ParseRest(text); err == nil {
+			t.Errorf("ParseRest(%q) = %d, want error", text, got)
+		}
+	}
+}
+
+func TestParseWeight(t *testing.T) {
+	tests := []struct {
+		text string
+		unit models.WeightUnit
+		want float64
+	}{
+		{"80", models.UnitKg, 80},
+		{"80.5 кг", models.UnitKg, 80.5},
+		{"72,5", models.UnitKg, 72.5},
+		{"176 lb", models.UnitKg, 79.83},
+		{"80", models.UnitLb, 36.29},
+		{"80 кг", models.UnitLb, 80},
+	}
+	for _, tt := range tests {
+		got, err := ParseWeight(tt.text, tt.unit)
+		if err != nil {
+			t.Errorf("ParseWeight(%q, %s) error: %v", tt.text, tt.unit, err)
+			continue
+		}
+		if round(got) != tt.want {
+			t.Errorf("ParseWeight(%q, %s) = %v, want %v", tt.text, tt.unit, got, tt.want)
+		}
+	}
+
+	for _, text := range []string{"", "много", "+10", "80x10"} {
+		if got, err := ParseWeight(text, models.UnitKg); err == nil {
+			t.Errorf("ParseWeight(%q) = %v, want error", text, got)
+		}
+	}
+}
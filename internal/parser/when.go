@@ -0,0 +1,170 @@
+package parser
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WorkoutTime - когда была тренировка. Start и End - nil, если время не указано.
+type WorkoutTime struct {
+	Date  time.Time
+	Start *time.Time
+	End   *time.Time
+}
+
+// Now - тренировка начинается сейчас
+func Now(now time.Time) WorkoutTime {
+	return WorkoutTime{Date: now, Start: &now}
+}
+
+// MaxBackdate - насколько давно может быть записанная задним числом тренировка
+const MaxBackdate = 366 * 24 * time.Hour
+
+var (
+	// «15.03», «15.03.2024», «15.03.24»
+	datePattern = regexp.MustCompile(`^(\d{1,2})\.(\d{1,2})(?:\.(\d{2}|\d{4}))?$`)
+	// «18:30», «18.30»
+	clockPattern = regexp.MustCompile(`^(\d{1,2})[:.](\d{2})$`)
+	// «18:30-19:45»
+	rangeSeparator = regexp.MustCompile(`\s*[-–—]\s*`)
+)
+
+// relativeDays - слова вместо даты
+var relativeDays = map[string]int{
+	"сегодня":   0,
+	"вчера":     1,
+	"позавчера": 2,
+	"today":     0,
+	"yesterday": 1,
+}
+
+// ParseWorkoutTime разбирает дату и время тренировки:
+//
+//	вчера                  - только дата
+//	15.03 18:30            - дата и начало
+//	вчера 18:30-19:45      - дата, начало и конец
+//	15.03.2024 9:00-10:15
+//
+// Год можно не указывать - берётся последняя такая дата, не позже now.
+func ParseWorkoutTime(text string, now time.Time) (WorkoutTime, error) {
+	fields := strings.Fields(rangeSeparator.ReplaceAllString(strings.ToLower(strings.TrimSpace(text)), "-"))
+	if len(fields) == 0 || len(fields) > 2 {
+		return WorkoutTime{}, errors.New("укажите дату, например «вчера 18:30» или «15.03 18:30-19:45»")
+	}
+
+	day, err := parseDay(fields[0], now)
+	if err != nil {
+		return WorkoutTime{}, err
+	}
+	result := WorkoutTime{Date: day}
+
+	if len(fields) == 2 {
+		clocks := strings.SplitN(fields[1], "-", 2)
+		start, err := parseClock(day, clocks[0])
+		if err != nil {
+			return WorkoutTime{}, err
+		}
+		result.Date = start
+		result.Start = &start
+		if len(clocks) == 2 {
+			end, err := parseClock(day, clocks[1])
+			if err != nil {
+				return WorkoutTime{}, err
+			}
+			// Тренировка через полночь
+			if !end.After(start) {
+				end = end.AddDate(0, 0, 1)
+			}
+			result.End = &end
+		}
+	}
+
+	latest := result.Date
+	if result.End != nil {
+		latest = *result.End
+	}
+	if latest.After(now) {
+		return WorkoutTime{}, errors.New("тренировка не может быть в будущем")
+	}
+	if now.Sub(result.Date) > MaxBackdate {
+		return WorkoutTime{}, errors.New("можно записать тренировку не старше года")
+	}
+	return result, nil
+}
+
+// parseDay разбирает дату без времени (полночь в часовом поясе now)
+func parseDay(s string, now time.Time) (time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if days, ok := relativeDays[s]; ok {
+		return today.AddDate(0, 0, -days), nil
+	}
+
+	m := datePattern.FindStringSubmatch(s)
+	if m == nil {
+		return time.Time{}, errors.New("не понял дату «" + s + "», напишите например 15.03 или вчера")
+	}
+	d, _ := strconv.Atoi(m[1])
+	mon, _ := strconv.Atoi(m[2])
+	year := now.Year()
+	explicitYear := m[3] != ""
+	if explicitYear {
+		year, _ = strconv.Atoi(m[3])
+		if year < 100 {
+			year += 2000
+		}
+	}
+
+	day := time.Date(year, time.Month(mon), d, 0, 0, 0, 0, now.Location())
+	// time.Date нормализует 31.02 в 03.03 - такую дату не принимаем
+	if day.Day() != d || int(day.Month()) != mon {
+		return time.Time{}, errors.New("такой даты нет: «" + s + "»")
+	}
+	if !explicitYear && day.After(today) {
+		day = day.AddDate(-1, 0, 0)
+	}
+	return day, nil
+}
+
+// parseClock разбирает «ЧЧ:ММ» и возвращает это время в день day
+func parseClock(day time.Time, s string) (time.Time, error) {
+	m := clockPattern.FindStringSubmatch(s)
+	if m == nil {
+		return time.Time{}, errors.New("не понял время «" + s + "», напишите например 18:30")
+	}
+	h, _ := strconv.Atoi(m[1])
+	min, _ := strconv.Atoi(m[2])
+	if h > 23 || min > 59 {
+		return time.Time{}, errors.New("такого времени нет: «" + s + "»")
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), h, min, 0, 0, day.Location()), nil
+}
+
+// ParseDateRange разбирает период по дням включительно:
+//
+//	01.03-31.03
+//	01.12.2023 - 15.01.2024
+//	15.03                   - один день
+//
+// Возвращает полночь первого и последнего дня.
+func ParseDateRange(text string, now time.Time) (from, to time.Time, err error) {
+	parts := rangeSeparator.Split(strings.ToLower(strings.TrimSpace(text)), -1)
+	if len(parts) == 0 || len(parts) > 2 || parts[0] == "" {
+		return time.Time{}, time.Time{}, errors.New("укажите период, например «01.03-31.03»")
+	}
+	if from, err = parseDay(parts[0], now); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	to = from
+	if len(parts) == 2 {
+		if to, err = parseDay(parts[1], now); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, errors.New("начало периода позже конца")
+	}
+	return from, to, nil
+}